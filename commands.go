@@ -6,16 +6,16 @@ import (
 	"fmt"
 	"github.com/minio/minio-go/v7/pkg/credentials"
 	"io"
+	"math"
 	"minx/wildcard"
 	"net/http"
 	"os"
 	"path/filepath"
-	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
-	"github.com/fatih/color"
 	"github.com/minio/minio-go/v7"
 	"github.com/urfave/cli/v2"
 )
@@ -199,172 +199,71 @@ func infoAction(c *cli.Context) error {
 	} else {
 		fmt.Printf("  状态:         Bucket 存在且可访问\n")
 
-		// 获取一些基本统计信息
-		// 注意：MinIO 不直接提供桶大小，我们可以列出一些对象获取基本统计信息
-		objChan := client.ListObjects(ctx, session.BucketName, minio.ListObjectsOptions{
-			Recursive: true,
-			MaxKeys:   1000, // 限制数量以避免性能问题
-		})
-
-		var totalSize int64
-		var objectCount int
-		for obj := range objChan {
-			if obj.Err != nil {
-				continue
-			}
-			totalSize += obj.Size
-			objectCount++
+		// MinIO 不直接提供桶大小，需要完整列出所有对象统计，大型存储桶可能需要一些时间
+		usage, _, err := usageScan{uploads: true}.run(ctx, client, session.BucketName)
+		if err != nil {
+			fmt.Printf("  无法统计空间: %v\n", err)
+			return nil
 		}
 
-		if objectCount >= 1000 {
-			fmt.Printf("  对象数量:     >1000 个对象\n")
-		} else {
-			fmt.Printf("  对象数量:     %d 个对象\n", objectCount)
+		fmt.Printf("  对象数量:     %d 个对象\n", usage.Objects)
+		fmt.Printf("  已用空间:     %s\n", formatSize(usage.Size))
+		if usage.Uploads > 0 {
+			// 未完成的上传不出现在对象列表中，但占用空间，可以使用 uploads rm 清理
+			fmt.Printf("  未完成上传:   %d 个，占用 %s\n", usage.Uploads, formatSize(usage.UploadSize))
 		}
-		fmt.Printf("  已用空间:     %s\n", formatSize(totalSize))
 	}
 
 	return nil
 }
 
-// 列出文件操作
-func lsAction(c *cli.Context) error {
-	manager, err := initSessionManager()
-	if err != nil {
-		return err
-	}
-
-	client, err := manager.GetClient()
-	if err != nil {
-		return err
-	}
-
-	session, err := manager.CurrentSession()
-	if err != nil {
-		return err
-	}
-
-	var remotePathArg string
-	if c.NArg() > 0 {
-		remotePathArg = c.Args().First()
+// 辅助函数：格式化文件大小
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < 0 {
+		return "未知"
 	}
-
-	remotePath, err := manager.FormatPath(remotePathArg)
-	if err != nil {
-		return err
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
 	}
-
-	// 确保路径以 / 结尾
-	prefix := strings.TrimPrefix(remotePath, "/")
-	if prefix != "" && !strings.HasSuffix(prefix, "/") {
-		prefix += "/"
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
 	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
 
-	// 列出对象
-	ctx := context.Background()
-	objectCh := client.ListObjects(ctx, session.BucketName, minio.ListObjectsOptions{
-		Prefix:    prefix,
-		Recursive: false,
-	})
-
-	// 收集目录和文件
-	var directories []string
-	var files []minio.ObjectInfo
-
-	for object := range objectCh {
-		if object.Err != nil {
-			return fmt.Errorf("列出对象时出错: %w", object.Err)
-		}
+// 辅助函数：解析文件大小，例如 512、100K、20MiB、1.5G (单位均按 1024 进制)
+func parseSize(s string) (int64, error) {
+	s = strings.TrimSpace(s)
 
-		name := strings.TrimPrefix(object.Key, prefix)
-		if strings.Contains(name, "/") {
-			// 这是一个目录
-			dirName := strings.Split(name, "/")[0] + "/"
-			if !contains(directories, dirName) {
-				directories = append(directories, dirName)
-			}
-		} else if name != "" {
-			// 这是一个文件
-			files = append(files, object)
-		}
+	i := 0
+	for i < len(s) && (s[i] >= '0' && s[i] <= '9' || s[i] == '.') {
+		i++
 	}
-
-	// 按修改时间排序（如果需要）
-	if c.Bool("r") {
-		sort.Slice(files, func(i, j int) bool {
-			return files[i].LastModified.After(files[j].LastModified)
-		})
-	} else {
-		sort.Slice(files, func(i, j int) bool {
-			return files[i].LastModified.Before(files[j].LastModified)
-		})
+	if i == 0 {
+		return 0, fmt.Errorf("无效的大小 '%s'", s)
 	}
 
-	// 限制输出数量（如果需要）
-	limit := c.Int("c")
-	count := 0
-
-	// 输出目录
-	if !c.Bool("d") || len(files) == 0 {
-		for _, dir := range directories {
-			if limit > 0 && count >= limit {
-				break
-			}
-
-			if c.Bool("color") {
-				color.New(color.FgBlue, color.Bold).Printf("%s\n", dir)
-			} else {
-				fmt.Printf("%s\n", dir)
-			}
-			count++
-		}
+	num, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil {
+		return 0, fmt.Errorf("无效的大小 '%s'", s)
 	}
 
-	// 输出文件
-	if !c.Bool("d") {
-		for _, file := range files {
-			if limit > 0 && count >= limit {
-				break
-			}
-
-			name := strings.TrimPrefix(file.Key, prefix)
-			sizeStr := formatSize(file.Size)
-			timeStr := file.LastModified.Format("2006-01-02 15:04:05")
-
-			if c.Bool("color") {
-				fmt.Printf("%s  %8s  %s\n", timeStr, sizeStr, name)
-			} else {
-				fmt.Printf("%s  %8s  %s\n", timeStr, sizeStr, name)
-			}
-			count++
-		}
+	unit := strings.ToUpper(strings.TrimSpace(s[i:]))
+	unit = strings.TrimSuffix(unit, "B")
+	unit = strings.TrimSuffix(unit, "I")
+	if unit == "" {
+		return int64(num), nil
 	}
 
-	return nil
-}
-
-// 辅助函数：检查字符串是否在切片中
-func contains(slice []string, item string) bool {
-	for _, s := range slice {
-		if s == item {
-			return true
-		}
+	exp := strings.Index("KMGTPE", unit)
+	if len(unit) != 1 || exp < 0 {
+		return 0, fmt.Errorf("无效的大小单位 '%s'", s[i:])
 	}
-	return false
-}
 
-// 辅助函数：格式化文件大小
-func formatSize(size int64) string {
-	const unit = 1024
-	if size < unit {
-		return fmt.Sprintf("%d B", size)
-	}
-	div, exp := int64(unit), 0
-	for n := size / unit; n >= unit; n /= unit {
-		div *= unit
-		exp++
-	}
-	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+	return int64(num * math.Pow(1024, float64(exp+1))), nil
 }
 
 // 改变目录操作
@@ -508,6 +407,7 @@ func mkdirAction(c *cli.Context) error {
 }
 
 // 显示目录结构操作
+// 逐层使用分隔符列出并立即输出，只读取 --depth 范围内的层级
 func treeAction(c *cli.Context) error {
 	manager, err := initSessionManager()
 	if err != nil {
@@ -540,106 +440,60 @@ func treeAction(c *cli.Context) error {
 		prefix += "/"
 	}
 
-	// 递归列出所有对象
-	ctx := context.Background()
-	objectCh := client.ListObjects(ctx, session.BucketName, minio.ListObjectsOptions{
-		Prefix:    prefix,
-		Recursive: true,
-	})
-
-	// 构建目录树
-	tree := make(map[string][]string)
-	var allPaths []string
-
-	for object := range objectCh {
-		if object.Err != nil {
-			return fmt.Errorf("列出对象时出错: %w", object.Err)
-		}
-
-		path := strings.TrimPrefix(object.Key, prefix)
-		if path == "" {
-			continue
-		}
-
-		allPaths = append(allPaths, path)
-
-		// 构建目录结构
-		parts := strings.Split(path, "/")
-		for i := 0; i < len(parts); i++ {
-			if i == len(parts)-1 && !strings.HasSuffix(path, "/") {
-				// 这是文件
-				parent := strings.Join(parts[:i], "/")
-				if parent == "" {
-					parent = "/"
-				}
-				tree[parent] = append(tree[parent], parts[i])
-			} else {
-				// 这是目录
-				dirPath := strings.Join(parts[:i+1], "/")
-				parentPath := "/"
-				if i > 0 {
-					parentPath = strings.Join(parts[:i], "/")
-				}
-
-				// 确保目录存在于树中
-				if _, exists := tree[dirPath]; !exists {
-					tree[dirPath] = []string{}
-				}
-
-				// 将目录添加到父目录中
-				dirName := parts[i] + "/"
-				if !containsStr(tree[parentPath], dirName) {
-					tree[parentPath] = append(tree[parentPath], dirName)
-				}
-			}
-		}
+	depth := c.Int("depth")
+	if depth < 0 {
+		return fmt.Errorf("--depth 不能为负数")
 	}
 
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
 	// 打印目录树
 	fmt.Printf("%s\n", remotePath)
-	printTree(tree, "/", 0)
-
-	return nil
+	return printTreeLevel(ctx, client, session.BucketName, prefix, "", 1, depth)
 }
 
-// 辅助函数：递归打印目录树
-func printTree(tree map[string][]string, currentPath string, depth int) {
-	items := tree[currentPath]
-	sort.Strings(items)
+// 输出 prefix 下的一层并递归输出子目录，level 从 1 开始，maxDepth 为 0 表示不限层数
+// 需要知道条目是否为最后一个才能选择连接线，因此只保留一个待输出的条目
+func printTreeLevel(ctx context.Context, client *minio.Client, bucket, prefix, indent string, level, maxDepth int) error {
+	emit := func(object minio.ObjectInfo, last bool) error {
+		name := strings.TrimPrefix(object.Key, prefix)
 
-	for i, item := range items {
-		isLast := i == len(items)-1
-		prefix := ""
-		for j := 0; j < depth; j++ {
-			prefix += "│   "
+		branch, childIndent := "├── ", indent+"│   "
+		if last {
+			branch, childIndent = "└── ", indent+"    "
 		}
+		fmt.Printf("%s%s%s\n", indent, branch, name)
 
-		if isLast {
-			fmt.Printf("%s└── %s\n", prefix, item)
-		} else {
-			fmt.Printf("%s├── %s\n", prefix, item)
+		if !strings.HasSuffix(name, "/") || (maxDepth > 0 && level >= maxDepth) {
+			return nil
 		}
+		return printTreeLevel(ctx, client, bucket, object.Key, childIndent, level+1, maxDepth)
+	}
 
-		if strings.HasSuffix(item, "/") {
-			// 这是目录，递归打印
-			nextPath := currentPath
-			if nextPath != "/" {
-				nextPath += "/"
+	var pending *minio.ObjectInfo
+	for object := range client.ListObjects(ctx, bucket, minio.ListObjectsOptions{Prefix: prefix}) {
+		if object.Err != nil {
+			return fmt.Errorf("列出对象时出错: %w", object.Err)
+		}
+		if object.Key == prefix {
+			// 目录自身的标记对象
+			continue
+		}
+
+		if pending != nil {
+			if err := emit(*pending, false); err != nil {
+				return err
 			}
-			nextPath += strings.TrimSuffix(item, "/")
-			printTree(tree, nextPath, depth+1)
 		}
+		object := object
+		pending = &object
 	}
-}
 
-// 辅助函数：检查字符串是否在切片中
-func containsStr(slice []string, item string) bool {
-	for _, s := range slice {
-		if s == item {
-			return true
-		}
+	if pending != nil {
+		return emit(*pending, true)
 	}
-	return false
+	return nil
 }
 
 // 下载文件或目录操作
@@ -671,6 +525,17 @@ func getAction(c *cli.Context) error {
 
 	objectName := strings.TrimPrefix(formattedPath, "/")
 
+	// 输出到标准输出时不打印任何提示信息
+	if c.Args().Get(1) == "-" {
+		if strings.HasSuffix(objectName, "/") {
+			return fmt.Errorf("无法将目录输出到标准输出")
+		}
+		return catObjects(c, []string{remotePath})
+	}
+	if c.IsSet("range") || c.IsSet("offset") || c.IsSet("length") {
+		return fmt.Errorf("--range/--offset/--length 仅在输出到标准输出 (-) 时可用")
+	}
+
 	// 确定本地保存路径
 	var localPath string
 	if c.NArg() > 1 {
@@ -686,10 +551,17 @@ func getAction(c *cli.Context) error {
 
 	// 判断是文件还是目录
 	ctx := context.Background()
+	dec := newDecoder(c)
 	isDir := strings.HasSuffix(objectName, "/")
 	if !isDir {
+		// SSE-C 加密的对象读取时需要提供密钥
+		sse, err := readEncryption(c.String("sse"), objectName)
+		if err != nil {
+			return err
+		}
+
 		// 检查是否存在该文件
-		objInfo, err := client.StatObject(ctx, session.BucketName, objectName, minio.StatObjectOptions{})
+		objInfo, err := client.StatObject(ctx, session.BucketName, objectName, minio.StatObjectOptions{ServerSideEncryption: sse})
 		if err != nil {
 			// 检查是否是目录
 			objects := client.ListObjects(ctx, session.BucketName, minio.ListObjectsOptions{
@@ -711,8 +583,9 @@ func getAction(c *cli.Context) error {
 				return fmt.Errorf("文件或目录 '%s' 不存在", formattedPath)
 			}
 		} else {
-			// 是文件，准备下载
-			fmt.Printf("下载文件: %s (%s)\n", formattedPath, formatSize(objInfo.Size))
+			// 是文件，准备下载，客户端加密的文件按解密后的大小计算
+			size := dec.PlainSize(objInfo)
+			fmt.Printf("下载文件: %s (%s)\n", formattedPath, formatSize(size))
 
 			// 创建目录
 			localDir := filepath.Dir(localPath)
@@ -730,16 +603,13 @@ func getAction(c *cli.Context) error {
 					return fmt.Errorf("获取本地文件信息失败: %w", err)
 				}
 
-				if fileInfo.Size() >= objInfo.Size {
+				if size >= 0 && fileInfo.Size() >= size {
 					fmt.Printf("文件已完成下载: %s\n", localPath)
 					return nil
 				}
 
 				fmt.Printf("继续下载文件: %s (从 %s/%s)\n",
-					localPath, formatSize(fileInfo.Size()), formatSize(objInfo.Size))
-
-				opts := minio.GetObjectOptions{}
-				opts.SetRange(fileInfo.Size(), objInfo.Size-1)
+					localPath, formatSize(fileInfo.Size()), formatSize(size))
 
 				// 打开本地文件进行追加
 				file, err := os.OpenFile(localPath, os.O_APPEND|os.O_WRONLY, 0644)
@@ -749,31 +619,29 @@ func getAction(c *cli.Context) error {
 				defer file.Close()
 
 				// 下载剩余部分
-				obj, err := client.GetObject(ctx, session.BucketName, objectName, opts)
+				obj, err := dec.Open(ctx, client, session.BucketName, objInfo, sse, fileInfo.Size(), -1)
 				if err != nil {
 					return fmt.Errorf("获取对象失败: %w", err)
 				}
 				defer obj.Close()
 
 				// 创建进度条
-				progress := &ProgressBar{
-					Total:     objInfo.Size,
-					Current:   fileInfo.Size(),
-					Width:     50,
-					FileName:  localPath,
-					StartTime: time.Now(),
-				}
+				progress := newProgress(c)
+				bar := progress.Start(localPath, size)
+				bar.SetOffset(fileInfo.Size())
 
 				// 创建缓冲读取器
-				reader := NewProgressReader(obj, progress)
+				reader := NewProgressReader(newTransferReader(obj), bar)
 
 				// 复制数据到文件
 				written, err := io.Copy(file, reader)
+				bar.Done(err)
+				progress.Finish()
 				if err != nil {
 					return fmt.Errorf("下载文件失败: %w", err)
 				}
 
-				fmt.Printf("\n已下载 %s 字节到 %s\n", formatSize(fileInfo.Size()+written), localPath)
+				fmt.Printf("已下载 %s 字节到 %s\n", formatSize(fileInfo.Size()+written), localPath)
 
 			} else {
 				// 常规下载
@@ -783,31 +651,29 @@ func getAction(c *cli.Context) error {
 				}
 				defer file.Close()
 
-				// 创建进度条
-				progress := &ProgressBar{
-					Total:     objInfo.Size,
-					Width:     50,
-					FileName:  localPath,
-					StartTime: time.Now(),
-				}
-
 				// 获取对象
-				obj, err := client.GetObject(ctx, session.BucketName, objectName, minio.GetObjectOptions{})
+				obj, err := dec.Open(ctx, client, session.BucketName, objInfo, sse, 0, -1)
 				if err != nil {
 					return fmt.Errorf("获取对象失败: %w", err)
 				}
 				defer obj.Close()
 
+				// 创建进度条
+				progress := newProgress(c)
+				bar := progress.Start(localPath, size)
+
 				// 创建缓冲读取器
-				reader := NewProgressReader(obj, progress)
+				reader := NewProgressReader(newTransferReader(obj), bar)
 
 				// 复制数据到文件
 				written, err := io.Copy(file, reader)
+				bar.Done(err)
+				progress.Finish()
 				if err != nil {
 					return fmt.Errorf("下载文件失败: %w", err)
 				}
 
-				fmt.Printf("\n已下载 %s 字节到 %s\n", formatSize(written), localPath)
+				fmt.Printf("已下载 %s 字节到 %s\n", formatSize(written), localPath)
 			}
 
 			return nil
@@ -823,27 +689,30 @@ func getAction(c *cli.Context) error {
 			return fmt.Errorf("创建本地目录失败: %w", err)
 		}
 
-		// 列出目录内所有对象
+		// 列出目录内所有对象，同时获取元数据以识别客户端加密的文件
 		prefix := objectName
 		objects := client.ListObjects(ctx, session.BucketName, minio.ListObjectsOptions{
-			Prefix:    prefix,
-			Recursive: true,
+			Prefix:       prefix,
+			Recursive:    true,
+			WithMetadata: true,
 		})
 
-		// 并发下载限制
-		workers := c.Int("w")
-		if workers < 1 {
-			workers = 1
-		} else if workers > 10 {
-			workers = 10
+		// 并发控制
+		concurrency, err := newConcurrency(c)
+		if err != nil {
+			return err
 		}
+		defer concurrency.Stop()
 
 		// 创建工作池
 		var wg sync.WaitGroup
 		jobCh := make(chan minio.ObjectInfo)
+		policy := retryPolicyFromContext(c)
+		failures := newFailureReport("get", session)
+		progress := newProgress(c)
 
 		// 启动工作线程
-		for i := 0; i < workers; i++ {
+		for i := 0; i < concurrency.Workers(); i++ {
 			wg.Add(1)
 			go func() {
 				defer wg.Done()
@@ -859,12 +728,6 @@ func getAction(c *cli.Context) error {
 					// 确定本地文件路径
 					filePath := filepath.Join(localPath, relPath)
 
-					// 创建目录结构
-					if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
-						fmt.Fprintf(os.Stderr, "无法创建目录 '%s': %v\n", filepath.Dir(filePath), err)
-						continue
-					}
-
 					// 检查是否需要跳过
 					if c.String("start") != "" && relPath < c.String("start") {
 						continue
@@ -872,83 +735,121 @@ func getAction(c *cli.Context) error {
 					if c.String("end") != "" && relPath >= c.String("end") {
 						continue
 					}
+					// 后台任务恢复时跳过已下载的文件
+					if checkpointDone(obj.Key) {
+						continue
+					}
 
-					// 下载文件
-					if c.Bool("c") && fileExists(filePath) {
-						// 断点续传
-						fileInfo, err := os.Stat(filePath)
-						if err != nil {
-							fmt.Fprintf(os.Stderr, "获取文件信息失败 '%s': %v\n", filePath, err)
-							continue
-						}
+					action := PlanAction{Op: opDownload, Source: obj.Key, Target: planLocalPath(filePath), Size: obj.Size}
+					if c.String("sse") != "" || dec.options() != nil {
+						action.Options = &TransferOptions{SSE: c.String("sse"), Encrypt: dec.options()}
+					}
 
-						if fileInfo.Size() >= obj.Size {
-							fmt.Printf("文件已完成下载: %s\n", filePath)
-							continue
-						}
+					sse, err := readEncryption(c.String("sse"), obj.Key)
+					if err != nil {
+						progress.Errorf("下载失败 '%s': %v", filePath, err)
+						failures.Add(action, 1, err)
+						continue
+					}
 
-						fmt.Printf("继续下载: %s (%s/%s)\n",
-							filePath, formatSize(fileInfo.Size()), formatSize(obj.Size))
+					// 客户端加密的文件按解密后的大小计算
+					obj, err := dec.Resolve(ctx, client, session.BucketName, obj, sse)
+					if err != nil {
+						progress.Errorf("下载失败 '%s': %v", filePath, err)
+						failures.Add(action, 1, err)
+						continue
+					}
+					size := dec.PlainSize(obj)
+					if size > 0 {
+						progress.AddTotal(size)
+					}
 
-						opts := minio.GetObjectOptions{}
-						opts.SetRange(fileInfo.Size(), obj.Size-1)
+					// 创建目录结构
+					if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+						progress.Errorf("无法创建目录 '%s': %v", filepath.Dir(filePath), err)
+						failures.Add(action, 1, err)
+						continue
+					}
 
-						// 打开本地文件进行追加
-						file, err := os.OpenFile(filePath, os.O_APPEND|os.O_WRONLY, 0644)
-						if err != nil {
-							fmt.Fprintf(os.Stderr, "打开文件失败 '%s': %v\n", filePath, err)
-							continue
-						}
+					// 下载文件，断点续传模式下重试会从已下载的位置继续
+					concurrency.Acquire()
+					bar := progress.Start(relPath, size)
+					attempts, err := policy.Do(ctx, func() error {
+						bar.Reset()
 
-						// 下载剩余部分
-						objReader, err := client.GetObject(ctx, session.BucketName, obj.Key, opts)
-						if err != nil {
-							fmt.Fprintf(os.Stderr, "获取对象失败 '%s': %v\n", obj.Key, err)
-							file.Close()
-							continue
-						}
+						if c.Bool("c") && fileExists(filePath) {
+							// 断点续传
+							fileInfo, err := os.Stat(filePath)
+							if err != nil {
+								return fmt.Errorf("获取文件信息失败: %w", err)
+							}
 
-						// 复制数据到文件
-						written, err := io.Copy(file, objReader)
-						objReader.Close()
-						file.Close()
+							if size >= 0 && fileInfo.Size() >= size {
+								progress.Logf("文件已完成下载: %s", filePath)
+								return nil
+							}
 
-						if err != nil {
-							fmt.Fprintf(os.Stderr, "下载文件失败 '%s': %v\n", filePath, err)
-							continue
-						}
+							progress.Logf("继续下载: %s (%s/%s)",
+								filePath, formatSize(fileInfo.Size()), formatSize(size))
+							bar.SetOffset(fileInfo.Size())
 
-						fmt.Printf("已下载: %s (%s 字节)\n", filePath, formatSize(fileInfo.Size()+written))
+							// 打开本地文件进行追加
+							file, err := os.OpenFile(filePath, os.O_APPEND|os.O_WRONLY, 0644)
+							if err != nil {
+								return fmt.Errorf("打开文件失败: %w", err)
+							}
+							defer file.Close()
+
+							// 下载剩余部分
+							objReader, err := dec.Open(ctx, client, session.BucketName, obj, sse, fileInfo.Size(), -1)
+							if err != nil {
+								return fmt.Errorf("获取对象失败: %w", err)
+							}
+							defer objReader.Close()
+
+							// 复制数据到文件
+							written, err := io.Copy(file, NewProgressReader(newTransferReader(objReader), bar))
+							if err != nil {
+								return err
+							}
+
+							progress.Logf("已下载: %s (%s 字节)", filePath, formatSize(fileInfo.Size()+written))
+							return nil
+						}
 
-					} else {
 						// 常规下载
-						fmt.Printf("下载: %s (%s)\n", filePath, formatSize(obj.Size))
+						progress.Logf("下载: %s (%s)", filePath, formatSize(size))
 
 						file, err := os.Create(filePath)
 						if err != nil {
-							fmt.Fprintf(os.Stderr, "创建文件失败 '%s': %v\n", filePath, err)
-							continue
+							return fmt.Errorf("创建文件失败: %w", err)
 						}
+						defer file.Close()
 
 						// 获取对象
-						objReader, err := client.GetObject(ctx, session.BucketName, obj.Key, minio.GetObjectOptions{})
+						objReader, err := dec.Open(ctx, client, session.BucketName, obj, sse, 0, -1)
 						if err != nil {
-							fmt.Fprintf(os.Stderr, "获取对象失败 '%s': %v\n", obj.Key, err)
-							file.Close()
-							continue
+							return fmt.Errorf("获取对象失败: %w", err)
 						}
+						defer objReader.Close()
 
 						// 复制数据到文件
-						written, err := io.Copy(file, objReader)
-						objReader.Close()
-						file.Close()
-
+						written, err := io.Copy(file, NewProgressReader(newTransferReader(objReader), bar))
 						if err != nil {
-							fmt.Fprintf(os.Stderr, "下载文件失败 '%s': %v\n", filePath, err)
-							continue
+							return err
 						}
 
-						fmt.Printf("已下载: %s (%s 字节)\n", filePath, formatSize(written))
+						progress.Logf("已下载: %s (%s 字节)", filePath, formatSize(written))
+						return nil
+					})
+					bar.Done(err)
+					concurrency.Release(err)
+
+					if err != nil {
+						progress.Errorf("下载文件失败 '%s': %v", filePath, err)
+						failures.Add(action, attempts, err)
+					} else {
+						checkpointMark(obj.Key)
 					}
 				}
 			}()
@@ -957,7 +858,8 @@ func getAction(c *cli.Context) error {
 		// 发送下载任务
 		for obj := range objects {
 			if obj.Err != nil {
-				fmt.Fprintf(os.Stderr, "列出对象时出错: %v\n", obj.Err)
+				progress.Errorf("列出对象时出错: %v", obj.Err)
+				failures.AddError(fmt.Errorf("列出对象时出错: %w", obj.Err))
 				continue
 			}
 			jobCh <- obj
@@ -966,8 +868,10 @@ func getAction(c *cli.Context) error {
 		// 关闭任务通道并等待所有工作线程完成
 		close(jobCh)
 		wg.Wait()
+		progress.Finish()
 
 		fmt.Printf("目录下载完成: %s\n", localPath)
+		return failures.Finish(c)
 	}
 
 	return nil
@@ -1001,6 +905,9 @@ func putAction(c *cli.Context) error {
 	}
 
 	localPath := c.Args().First()
+	if localPath == "-" && c.NArg() < 2 {
+		return fmt.Errorf("从标准输入上传需要指定远程路径")
+	}
 
 	// 检查本地路径是否是 URL
 	isURL := strings.HasPrefix(localPath, "http://") || strings.HasPrefix(localPath, "https://")
@@ -1027,8 +934,23 @@ func putAction(c *cli.Context) error {
 
 	// 上传文件或目录
 	ctx := context.Background()
+	plan := newPlan(c, "put", session)
+	opts, err := transferOptionsFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	if localPath == "-" {
+		return putStdin(c, client, session, objectName, formattedPath, plan, opts)
+	}
 
 	if isURL {
+		if plan != nil {
+			op, reason := planUploadOp(ctx, client, session.BucketName, objectName)
+			plan.Add(PlanAction{Op: op, Source: localPath, Target: objectName, Size: headContentLength(localPath), Reason: reason, Options: opts})
+			return plan.Finish(c)
+		}
+
 		// 从 URL 上传
 		fmt.Printf("从 URL 上传: %s 到 %s\n", localPath, formattedPath)
 
@@ -1046,27 +968,33 @@ func putAction(c *cli.Context) error {
 		// 计算内容大小
 		contentLength := resp.ContentLength
 
-		// 创建进度条
-		progress := &ProgressBar{
-			Total:     contentLength,
-			Width:     50,
-			FileName:  objectName,
-			StartTime: time.Now(),
+		// 启用回收站时，被覆盖的对象先复制到回收站
+		if _, err := trashOverwritten(ctx, client, session.BucketName, objectName, opts.sseSpec()); err != nil {
+			return err
 		}
 
-		// 创建带进度的读取器
-		reader := NewProgressReader(resp.Body, progress)
+		// 创建进度条
+		// 服务器未返回 Content-Length 时大小为 -1，进度条按未知大小显示
+		progress := newProgress(c)
+		bar := progress.Start(objectName, contentLength)
+
+		// 创建带进度的读取器，加密时进度按明文统计
+		reader, uploadSize, putOpts, err := opts.prepareUpload(objectName, resp.Header.Get("Content-Type"), NewProgressReader(newTransferReader(resp.Body), bar), contentLength)
+		if err != nil {
+			bar.Done(err)
+			progress.Finish()
+			return err
+		}
 
 		// 执行上传
-		contentType := resp.Header.Get("Content-Type")
-		_, err = client.PutObject(ctx, session.BucketName, objectName, reader, contentLength, minio.PutObjectOptions{
-			ContentType: contentType,
-		})
+		info, err := client.PutObject(ctx, session.BucketName, objectName, reader, uploadSize, putOpts)
+		bar.Done(err)
+		progress.Finish()
 		if err != nil {
 			return fmt.Errorf("上传文件失败: %w", err)
 		}
 
-		fmt.Printf("\n已上传 %s 字节到 %s\n", formatSize(contentLength), formattedPath)
+		fmt.Printf("已上传 %s 字节到 %s\n", formatSize(info.Size), formattedPath)
 
 	} else {
 		// 从本地文件上传
@@ -1085,12 +1013,15 @@ func putAction(c *cli.Context) error {
 			}
 
 			// 创建远程目录
-			_, err = client.PutObject(ctx, session.BucketName, objectName, strings.NewReader(""), 0, minio.PutObjectOptions{})
-			if err != nil {
-				return fmt.Errorf("创建远程目录失败: %w", err)
+			if plan == nil {
+				_, err = client.PutObject(ctx, session.BucketName, objectName, strings.NewReader(""), 0, minio.PutObjectOptions{})
+				if err != nil {
+					return fmt.Errorf("创建远程目录失败: %w", err)
+				}
 			}
 
-			// 递归上传目录内容
+			// 递归上传目录内容，失败的文件记入失败记录，可以用 minx retry 重试
+			failures := newFailureReport("put", session)
 			err = filepath.Walk(localPath, func(path string, info os.FileInfo, err error) error {
 				if err != nil {
 					return err
@@ -1120,32 +1051,59 @@ func putAction(c *cli.Context) error {
 						dirObjectName += relPath + "/"
 					}
 
+					if plan != nil {
+						plan.Add(PlanAction{Op: opMkdir, Target: dirObjectName})
+						return nil
+					}
+
 					_, err = client.PutObject(ctx, session.BucketName, dirObjectName, strings.NewReader(""), 0, minio.PutObjectOptions{})
 					if err != nil {
 						fmt.Fprintf(os.Stderr, "创建目录失败 '%s': %v\n", dirObjectName, err)
+						failures.Add(PlanAction{Op: opMkdir, Target: dirObjectName}, 1, err)
 					}
 				} else {
 					// 上传文件
 					fileObjectName := objectName + relPath
 
+					if plan != nil {
+						op, reason := planUploadOp(ctx, client, session.BucketName, fileObjectName)
+						plan.Add(PlanAction{Op: op, Source: planLocalPath(path), Target: fileObjectName, Size: info.Size(), Reason: reason, Options: opts})
+						return nil
+					}
+
 					fmt.Printf("上传: %s (%s)\n", path, formatSize(info.Size()))
+					action := PlanAction{Op: opUpload, Source: planLocalPath(path), Target: fileObjectName, Size: info.Size(), Options: opts}
+
+					// 启用回收站时，被覆盖的对象先复制到回收站，上传失败时原对象保持不变
+					if _, err := trashOverwritten(ctx, client, session.BucketName, fileObjectName, opts.sseSpec()); err != nil {
+						fmt.Fprintf(os.Stderr, "上传文件失败 '%s': %v\n", path, err)
+						failures.Add(action, 1, err)
+						return nil
+					}
 
 					file, err := os.Open(path)
 					if err != nil {
 						fmt.Fprintf(os.Stderr, "无法打开文件 '%s': %v\n", path, err)
+						failures.Add(action, 1, err)
 						return nil
 					}
 					defer file.Close()
 
 					// 获取文件 MIME 类型
-					contentType := getMimeType(path)
+					contentType := detectContentType(path)
+
+					reader, uploadSize, putOpts, err := opts.prepareUpload(fileObjectName, contentType, newTransferReader(file), info.Size())
+					if err != nil {
+						fmt.Fprintf(os.Stderr, "上传文件失败 '%s': %v\n", path, err)
+						failures.Add(action, 1, err)
+						return nil
+					}
 
 					// 执行上传
-					_, err = client.PutObject(ctx, session.BucketName, fileObjectName, file, info.Size(), minio.PutObjectOptions{
-						ContentType: contentType,
-					})
+					_, err = client.PutObject(ctx, session.BucketName, fileObjectName, reader, uploadSize, putOpts)
 					if err != nil {
 						fmt.Fprintf(os.Stderr, "上传文件失败 '%s': %v\n", path, err)
+						failures.Add(action, 1, err)
 					}
 				}
 
@@ -1156,9 +1114,19 @@ func putAction(c *cli.Context) error {
 				return fmt.Errorf("遍历目录失败: %w", err)
 			}
 
-			fmt.Printf("目录上传完成: %s\n", formattedPath)
+			if plan != nil {
+				return plan.Finish(c)
+			}
 
+			fmt.Printf("目录上传完成: %s\n", formattedPath)
+			return failures.Finish(c)
 		} else {
+			if plan != nil {
+				op, reason := planUploadOp(ctx, client, session.BucketName, objectName)
+				plan.Add(PlanAction{Op: op, Source: planLocalPath(localPath), Target: objectName, Size: fileInfo.Size(), Reason: reason, Options: opts})
+				return plan.Finish(c)
+			}
+
 			// 文件上传
 			fmt.Printf("上传文件: %s (%s) 到 %s\n", localPath, formatSize(fileInfo.Size()), formattedPath)
 
@@ -1168,62 +1136,38 @@ func putAction(c *cli.Context) error {
 			}
 			defer file.Close()
 
-			// 创建进度条
-			progress := &ProgressBar{
-				Total:     fileInfo.Size(),
-				Width:     50,
-				FileName:  localPath,
-				StartTime: time.Now(),
+			// 启用回收站时，被覆盖的对象先复制到回收站
+			if _, err := trashOverwritten(ctx, client, session.BucketName, objectName, opts.sseSpec()); err != nil {
+				return err
 			}
 
-			// 创建带进度的读取器
-			reader := NewProgressReader(file, progress)
+			// 创建进度条
+			progress := newProgress(c)
+			bar := progress.Start(localPath, fileInfo.Size())
 
-			// 获取文件 MIME 类型
-			contentType := getMimeType(localPath)
+			// 创建带进度的读取器，加密时进度按明文统计
+			reader, uploadSize, putOpts, err := opts.prepareUpload(objectName, detectContentType(localPath), NewProgressReader(newTransferReader(file), bar), fileInfo.Size())
+			if err != nil {
+				bar.Done(err)
+				progress.Finish()
+				return err
+			}
 
 			// 执行上传
-			_, err = client.PutObject(ctx, session.BucketName, objectName, reader, fileInfo.Size(), minio.PutObjectOptions{
-				ContentType: contentType,
-			})
+			_, err = client.PutObject(ctx, session.BucketName, objectName, reader, uploadSize, putOpts)
+			bar.Done(err)
+			progress.Finish()
 			if err != nil {
 				return fmt.Errorf("上传文件失败: %w", err)
 			}
 
-			fmt.Printf("\n已上传 %s 字节到 %s\n", formatSize(fileInfo.Size()), formattedPath)
+			fmt.Printf("已上传 %s 字节到 %s\n", formatSize(fileInfo.Size()), formattedPath)
 		}
 	}
 
 	return nil
 }
 
-// 辅助函数：获取文件 MIME 类型
-func getMimeType(path string) string {
-	ext := filepath.Ext(path)
-	switch strings.ToLower(ext) {
-	case ".jpg", ".jpeg":
-		return "image/jpeg"
-	case ".png":
-		return "image/png"
-	case ".gif":
-		return "image/gif"
-	case ".txt":
-		return "text/plain"
-	case ".html", ".htm":
-		return "text/html"
-	case ".pdf":
-		return "application/pdf"
-	case ".zip":
-		return "application/zip"
-	case ".mp3":
-		return "audio/mpeg"
-	case ".mp4":
-		return "video/mp4"
-	default:
-		return "application/octet-stream"
-	}
-}
-
 // 上传多个文件操作
 func uploadAction(c *cli.Context) error {
 	if c.NArg() < 1 {
@@ -1307,20 +1251,28 @@ func uploadAction(c *cli.Context) error {
 		defer errLog.Close()
 	}
 
-	// 并发上传限制
-	workers := c.Int("w")
-	if workers < 1 {
-		workers = 1
-	} else if workers > 10 {
-		workers = 10
+	// 并发控制
+	concurrency, err := newConcurrency(c)
+	if err != nil {
+		return err
+	}
+	defer concurrency.Stop()
+
+	plan := newPlan(c, "upload", session)
+	opts, err := transferOptionsFromContext(c)
+	if err != nil {
+		return err
 	}
+	policy := retryPolicyFromContext(c)
+	failures := newFailureReport("upload", session)
+	progress := newProgress(c)
 
 	// 创建工作池
 	var wg sync.WaitGroup
 	jobCh := make(chan string)
 
 	// 启动工作线程
-	for i := 0; i < workers; i++ {
+	for i := 0; i < concurrency.Workers(); i++ {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
@@ -1331,7 +1283,8 @@ func uploadAction(c *cli.Context) error {
 				if !filepath.IsAbs(localPath) {
 					currentDir, err := os.Getwd()
 					if err != nil {
-						logError(errLog, "获取当前工作目录失败: %v", err)
+						logError(progress, errLog, "获取当前工作目录失败: %v", err)
+						failures.AddError(err)
 						continue
 					}
 					localPath = filepath.Join(currentDir, localPath)
@@ -1339,23 +1292,32 @@ func uploadAction(c *cli.Context) error {
 
 				fileInfo, err := os.Stat(localPath)
 				if err != nil {
-					logError(errLog, "获取文件信息失败 '%s': %v", localPath, err)
+					logError(progress, errLog, "获取文件信息失败 '%s': %v", localPath, err)
+					failures.AddError(err)
 					continue
 				}
 
 				if fileInfo.IsDir() {
 					// 目录上传
-					fmt.Printf("上传目录: %s -> %s\n", localPath, formattedPath+filepath.Base(localPath)+"/")
+					progress.Logf("上传目录: %s -> %s", localPath, formattedPath+filepath.Base(localPath)+"/")
 
 					// 计算目录名
 					dirName := filepath.Base(localPath)
 					dirObjectPrefix := objectPrefix + dirName + "/"
 
 					// 创建远程目录
-					_, err = client.PutObject(ctx, session.BucketName, dirObjectPrefix, strings.NewReader(""), 0, minio.PutObjectOptions{})
-					if err != nil {
-						logError(errLog, "创建远程目录失败 '%s': %v", dirObjectPrefix, err)
-						continue
+					if plan != nil {
+						plan.Add(PlanAction{Op: opMkdir, Target: dirObjectPrefix})
+					} else {
+						attempts, err := policy.Do(ctx, func() error {
+							_, err := client.PutObject(ctx, session.BucketName, dirObjectPrefix, strings.NewReader(""), 0, minio.PutObjectOptions{})
+							return err
+						})
+						if err != nil {
+							logError(progress, errLog, "创建远程目录失败 '%s': %v", dirObjectPrefix, err)
+							failures.Add(PlanAction{Op: opMkdir, Target: dirObjectPrefix}, attempts, err)
+							continue
+						}
 					}
 
 					// 递归上传目录内容
@@ -1385,48 +1347,77 @@ func uploadAction(c *cli.Context) error {
 							// 创建目录
 							if relPath != "." {
 								dirObjName := dirObjectPrefix + relPath + "/"
-								_, err = client.PutObject(ctx, session.BucketName, dirObjName, strings.NewReader(""), 0, minio.PutObjectOptions{})
+								if plan != nil {
+									plan.Add(PlanAction{Op: opMkdir, Target: dirObjName})
+									return nil
+								}
+
+								attempts, err := policy.Do(ctx, func() error {
+									_, err := client.PutObject(ctx, session.BucketName, dirObjName, strings.NewReader(""), 0, minio.PutObjectOptions{})
+									return err
+								})
 								if err != nil {
-									logError(errLog, "创建目录失败 '%s': %v", dirObjName, err)
+									logError(progress, errLog, "创建目录失败 '%s': %v", dirObjName, err)
+									failures.Add(PlanAction{Op: opMkdir, Target: dirObjName}, attempts, err)
 								}
 							}
 						} else {
 							// 上传文件
 							fileObjectName := dirObjectPrefix + relPath
 
-							fmt.Printf("上传: %s (%s)\n", path, formatSize(info.Size()))
+							if plan != nil {
+								op, reason := planUploadOp(ctx, client, session.BucketName, fileObjectName)
+								plan.Add(PlanAction{Op: op, Source: planLocalPath(path), Target: fileObjectName, Size: info.Size(), Reason: reason, Options: opts})
+								return nil
+							}
 
-							file, err := os.Open(path)
-							if err != nil {
-								logError(errLog, "无法打开文件 '%s': %v", path, err)
+							// 后台任务恢复时跳过已上传的文件
+							if checkpointDone(fileObjectName) {
 								return nil
 							}
 
-							// 创建进度条
-							progress := &ProgressBar{
-								Total:     info.Size(),
-								Width:     50,
-								FileName:  filepath.Base(path),
-								StartTime: time.Now(),
+							progress.Logf("上传: %s (%s)", path, formatSize(info.Size()))
+							progress.AddTotal(info.Size())
+
+							// 启用回收站时，被覆盖的对象先复制到回收站，上传失败时原对象保持不变
+							if _, err := trashOverwritten(ctx, client, session.BucketName, fileObjectName, opts.sseSpec()); err != nil {
+								logError(progress, errLog, "上传文件失败 '%s': %v", path, err)
+								failures.Add(PlanAction{Op: opUpload, Source: planLocalPath(path), Target: fileObjectName, Size: info.Size(), Options: opts}, 1, err)
+								return nil
 							}
 
-							// 创建带进度的读取器
-							reader := NewProgressReader(file, progress)
+							concurrency.Acquire()
+							bar := progress.Start(filepath.Base(path), info.Size())
+							attempts, err := policy.Do(ctx, func() error {
+								bar.Reset()
 
-							// 获取文件 MIME 类型
-							contentType := getMimeType(path)
+								file, err := os.Open(path)
+								if err != nil {
+									return fmt.Errorf("无法打开文件: %w", err)
+								}
+								defer file.Close()
 
-							// 执行上传
-							_, err = client.PutObject(ctx, session.BucketName, fileObjectName, reader, info.Size(), minio.PutObjectOptions{
-								ContentType: contentType,
-							})
+								// 获取文件 MIME 类型
+								contentType := detectContentType(path)
 
-							file.Close()
+								// 创建带进度的读取器，加密时进度按明文统计
+								reader, uploadSize, putOpts, err := opts.prepareUpload(fileObjectName, contentType, NewProgressReader(newTransferReader(file), bar), info.Size())
+								if err != nil {
+									return err
+								}
 
-							fmt.Println() // 进度条完成后换行
+								// 执行上传
+								_, err = client.PutObject(ctx, session.BucketName, fileObjectName, reader, uploadSize, putOpts)
+								return err
+							})
+							bar.Done(err)
+							concurrency.Release(err)
 
 							if err != nil {
-								logError(errLog, "上传文件失败 '%s': %v", path, err)
+								logError(progress, errLog, "上传文件失败 '%s': %v", path, err)
+								failures.Add(PlanAction{Op: opUpload, Source: planLocalPath(path), Target: fileObjectName, Size: info.Size(), Options: opts}, attempts, err)
+							} else {
+								checkpointMark(fileObjectName)
 							}
 						}
 
@@ -1434,47 +1425,69 @@ func uploadAction(c *cli.Context) error {
 					})
 
 					if err != nil {
-						logError(errLog, "遍历目录失败 '%s': %v", localPath, err)
+						logError(progress, errLog, "遍历目录失败 '%s': %v", localPath, err)
+						failures.AddError(fmt.Errorf("遍历目录失败 '%s': %w", localPath, err))
 					}
 
 				} else {
-					// 文件上传
-					fmt.Printf("上传文件: %s (%s) -> %s\n", localPath, formatSize(fileInfo.Size()), formattedPath)
+					// 计算文件名
+					fileName := filepath.Base(localPath)
+					fileObjectName := objectPrefix + fileName
 
-					file, err := os.Open(localPath)
-					if err != nil {
-						logError(errLog, "无法打开文件 '%s': %v", localPath, err)
+					if plan != nil {
+						op, reason := planUploadOp(ctx, client, session.BucketName, fileObjectName)
+						plan.Add(PlanAction{Op: op, Source: planLocalPath(localPath), Target: fileObjectName, Size: fileInfo.Size(), Reason: reason, Options: opts})
 						continue
 					}
 
-					// 计算文件名
-					fileName := filepath.Base(localPath)
-					fileObjectName := objectPrefix + fileName
+					// 后台任务恢复时跳过已上传的文件
+					if checkpointDone(fileObjectName) {
+						continue
+					}
 
-					// 创建进度条
-					progress := &ProgressBar{
-						Total:     fileInfo.Size(),
-						Width:     50,
-						FileName:  fileName,
-						StartTime: time.Now(),
+					// 文件上传
+					progress.Logf("上传文件: %s (%s) -> %s", localPath, formatSize(fileInfo.Size()), formattedPath)
+					progress.AddTotal(fileInfo.Size())
+
+					// 启用回收站时，被覆盖的对象先复制到回收站，上传失败时原对象保持不变
+					if _, err := trashOverwritten(ctx, client, session.BucketName, fileObjectName, opts.sseSpec()); err != nil {
+						logError(progress, errLog, "上传文件失败 '%s': %v", localPath, err)
+						failures.Add(PlanAction{Op: opUpload, Source: planLocalPath(localPath), Target: fileObjectName, Size: fileInfo.Size(), Options: opts}, 1, err)
+						continue
 					}
 
-					// 创建带进度的读取器
-					reader := NewProgressReader(file, progress)
+					concurrency.Acquire()
+					bar := progress.Start(fileName, fileInfo.Size())
+					attempts, err := policy.Do(ctx, func() error {
+						bar.Reset()
 
-					// 获取文件 MIME 类型
-					contentType := getMimeType(localPath)
+						file, err := os.Open(localPath)
+						if err != nil {
+							return fmt.Errorf("无法打开文件: %w", err)
+						}
+						defer file.Close()
 
-					// 执行上传
-					_, err = client.PutObject(ctx, session.BucketName, fileObjectName, reader, fileInfo.Size(), minio.PutObjectOptions{
-						ContentType: contentType,
-					})
-					file.Close()
+						// 获取文件 MIME 类型
+						contentType := detectContentType(localPath)
 
-					fmt.Println() // 进度条完成后换行
+						// 创建带进度的读取器，加密时进度按明文统计
+						reader, uploadSize, putOpts, err := opts.prepareUpload(fileObjectName, contentType, NewProgressReader(newTransferReader(file), bar), fileInfo.Size())
+						if err != nil {
+							return err
+						}
+
+						// 执行上传
+						_, err = client.PutObject(ctx, session.BucketName, fileObjectName, reader, uploadSize, putOpts)
+						return err
+					})
+					bar.Done(err)
+					concurrency.Release(err)
 
 					if err != nil {
-						logError(errLog, "上传文件失败 '%s': %v", localPath, err)
+						logError(progress, errLog, "上传文件失败 '%s': %v", localPath, err)
+						failures.Add(PlanAction{Op: opUpload, Source: planLocalPath(localPath), Target: fileObjectName, Size: fileInfo.Size(), Options: opts}, attempts, err)
+					} else {
+						checkpointMark(fileObjectName)
 					}
 				}
 			}
@@ -1490,14 +1503,20 @@ func uploadAction(c *cli.Context) error {
 	close(jobCh)
 	wg.Wait()
 
+	progress.Finish()
+
+	if plan != nil {
+		return plan.Finish(c)
+	}
+
 	fmt.Printf("上传完成，共 %d 个文件或目录\n", len(filesToUpload))
-	return nil
+	return failures.Finish(c)
 }
 
 // 辅助函数：记录错误
-func logError(file *os.File, format string, args ...interface{}) {
+func logError(progress *Progress, file *os.File, format string, args ...interface{}) {
 	message := fmt.Sprintf(format, args...)
-	fmt.Fprintf(os.Stderr, "错误: %s\n", message)
+	progress.Errorf("错误: %s", message)
 
 	if file != nil {
 		fmt.Fprintf(file, "%s: %s\n", time.Now().Format(time.RFC3339), message)
@@ -1531,7 +1550,21 @@ func rmAction(c *cli.Context) error {
 		return err
 	}
 
+	// 异步删除作为后台任务运行，关闭终端后继续执行
+	if c.Bool("async") && runningJob == nil && !isDryRun(c) {
+		return rmAsync(c, formattedPath)
+	}
+
 	objectName := strings.TrimPrefix(formattedPath, "/")
+	plan := newPlan(c, "rm", session)
+
+	// 绕过治理模式的保留期，合规模式的对象仍然无法删除
+	var lock *ObjectLock
+	if c.Bool("bypass-governance") {
+		lock = &ObjectLock{BypassGovernance: true}
+	}
+	// 启用回收站时移到回收站，而不是直接删除
+	rm := newRemover(client, session, lock)
 
 	// 检查通配符
 	if strings.Contains(objectName, "*") {
@@ -1546,7 +1579,7 @@ func rmAction(c *cli.Context) error {
 			Recursive: true,
 		})
 
-		var objectsToDelete []string
+		var objectsToDelete []minio.ObjectInfo
 		for object := range objectCh {
 			if object.Err != nil {
 				return fmt.Errorf("列出对象时出错: %w", object.Err)
@@ -1563,7 +1596,10 @@ func rmAction(c *cli.Context) error {
 					continue
 				}
 
-				objectsToDelete = append(objectsToDelete, object.Key)
+				objectsToDelete = append(objectsToDelete, object)
+				if plan != nil {
+					plan.Add(rm.action(object.Key, object.Size, "匹配 "+pattern))
+				}
 			}
 		}
 
@@ -1571,41 +1607,26 @@ func rmAction(c *cli.Context) error {
 			return fmt.Errorf("没有匹配的文件或目录: %s", formattedPath)
 		}
 
-		// 执行删除
-		if c.Bool("async") {
-			// 异步删除
-			go func() {
-				for _, obj := range objectsToDelete {
-					fmt.Printf("异步删除: %s\n", obj)
-					err := client.RemoveObject(context.Background(), session.BucketName, obj, minio.RemoveObjectOptions{})
-					if err != nil {
-						fmt.Fprintf(os.Stderr, "删除失败 '%s': %v\n", obj, err)
-					}
-				}
-			}()
-			fmt.Printf("已启动异步删除 %d 个对象\n", len(objectsToDelete))
-
-		} else {
-			// 同步删除
-			for _, obj := range objectsToDelete {
-				fmt.Printf("删除: %s\n", obj)
-				err := client.RemoveObject(ctx, session.BucketName, obj, minio.RemoveObjectOptions{})
-				if err != nil {
-					fmt.Fprintf(os.Stderr, "删除失败 '%s': %v\n", obj, err)
-				}
-			}
-			fmt.Printf("已删除 %d 个对象\n", len(objectsToDelete))
+		if plan != nil {
+			return plan.Finish(c)
 		}
 
+		// 执行删除，失败的对象记入失败记录，不计入已删除数量
+		removed, failures := removeObjects(ctx, c, session, rm, objectsToDelete, "匹配 "+pattern)
+		fmt.Printf("已删除 %d 个对象\n", removed)
+		rm.finish("rm", formattedPath)
+		return failures.Finish(c)
+
 	} else {
 		// 没有通配符，直接删除
 		ctx := context.Background()
 
 		// 判断是文件还是目录
 		isDir := strings.HasSuffix(objectName, "/")
+		var fileSize int64
 		if !isDir {
 			// 检查是否存在
-			_, err := client.StatObject(ctx, session.BucketName, objectName, minio.StatObjectOptions{})
+			objInfo, err := client.StatObject(ctx, session.BucketName, objectName, minio.StatObjectOptions{})
 			if err != nil {
 				// 检查是否是目录
 				objectCh := client.ListObjects(ctx, session.BucketName, minio.ListObjectsOptions{
@@ -1628,6 +1649,8 @@ func rmAction(c *cli.Context) error {
 				}
 			} else if c.Bool("d") {
 				return fmt.Errorf("'%s' 不是目录", formattedPath)
+			} else {
+				fileSize = objInfo.Size
 			}
 		} else if !c.Bool("a") && !c.Bool("d") {
 			return fmt.Errorf("无法删除目录 '%s'，请使用 -d 或 -a 选项", formattedPath)
@@ -1642,12 +1665,15 @@ func rmAction(c *cli.Context) error {
 					Recursive: true,
 				})
 
-				var objectsToDelete []string
+				var objectsToDelete []minio.ObjectInfo
 				for object := range objectCh {
 					if object.Err != nil {
 						return fmt.Errorf("列出对象时出错: %w", object.Err)
 					}
-					objectsToDelete = append(objectsToDelete, object.Key)
+					objectsToDelete = append(objectsToDelete, object)
+					if plan != nil {
+						plan.Add(rm.action(object.Key, object.Size, "位于目录 "+formattedPath))
+					}
 				}
 
 				if len(objectsToDelete) == 0 {
@@ -1655,45 +1681,73 @@ func rmAction(c *cli.Context) error {
 					return nil
 				}
 
-				// 执行删除
-				if c.Bool("async") {
-					// 异步删除
-					go func() {
-						for _, obj := range objectsToDelete {
-							fmt.Printf("异步删除: %s\n", obj)
-							err := client.RemoveObject(context.Background(), session.BucketName, obj, minio.RemoveObjectOptions{})
-							if err != nil {
-								fmt.Fprintf(os.Stderr, "删除失败 '%s': %v\n", obj, err)
-							}
-						}
-					}()
-					fmt.Printf("已启动异步删除目录 '%s'，共 %d 个对象\n", formattedPath, len(objectsToDelete))
-
-				} else {
-					// 同步删除
-					for _, obj := range objectsToDelete {
-						fmt.Printf("删除: %s\n", obj)
-						err := client.RemoveObject(ctx, session.BucketName, obj, minio.RemoveObjectOptions{})
-						if err != nil {
-							fmt.Fprintf(os.Stderr, "删除失败 '%s': %v\n", obj, err)
-						}
-					}
-					fmt.Printf("已删除目录 '%s'，共 %d 个对象\n", formattedPath, len(objectsToDelete))
+				if plan != nil {
+					return plan.Finish(c)
 				}
+
+				// 执行删除，失败的对象记入失败记录，不计入已删除数量
+				removed, failures := removeObjects(ctx, c, session, rm, objectsToDelete, "位于目录 "+formattedPath)
+				fmt.Printf("已删除目录 '%s'，共 %d 个对象\n", formattedPath, removed)
+				rm.finish("rm", formattedPath)
+				return failures.Finish(c)
 			} else {
 				return fmt.Errorf("无法删除目录 '%s'，请使用 -d 或 -a 选项", formattedPath)
 			}
 		} else {
+			if plan != nil {
+				plan.Add(rm.action(objectName, fileSize, "指定文件"))
+				return plan.Finish(c)
+			}
+
 			// 删除文件
 			fmt.Printf("删除文件: %s\n", formattedPath)
-			err := client.RemoveObject(ctx, session.BucketName, objectName, minio.RemoveObjectOptions{})
+			err := rm.remove(ctx, objectName)
 			if err != nil {
 				return fmt.Errorf("删除文件失败: %w", err)
 			}
 			fmt.Printf("已删除文件 '%s'\n", formattedPath)
+			rm.finish("rm", formattedPath)
+		}
+	}
+
+	return nil
+}
+
+// 逐个删除对象 (启用回收站时移到回收站)，与 sync --delete 相同，失败的删除按重试策略重试后记入失败记录
+// 返回成功删除的数量和失败记录
+func removeObjects(ctx context.Context, c *cli.Context, session *Session, rm *remover, objects []minio.ObjectInfo, reason string) (int, *FailureReport) {
+	policy := retryPolicyFromContext(c)
+	failures := newFailureReport("rm", session)
+
+	removed := 0
+	for _, object := range objects {
+		action := rm.action(object.Key, object.Size, reason)
+		fmt.Printf("删除: %s\n", object.Key)
+		attempts, err := runPlanAction(ctx, policy, rm.client, rm.bucket, action)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "删除失败 '%s': %v\n", object.Key, err)
+			failures.Add(action, attempts, err)
+			continue
 		}
+		rm.record(action)
+		removed++
 	}
 
+	return removed, failures
+}
+
+// 启动执行删除的后台任务，远程路径已转换为绝对路径，不受之后 cd 的影响
+func rmAsync(c *cli.Context, formattedPath string) error {
+	args := append([]string{"rm"}, flagArgs(c, c.Command.Flags, "async")...)
+	args = append(args, formattedPath)
+
+	job, err := startJob(c, globalFlagArgs(c), args)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("已启动异步删除任务 %s: %s\n", job.ID, formattedPath)
+	fmt.Printf("使用 minx job status %s 查看状态，minx job logs -f %s 查看输出\n", job.ID, job.ID)
 	return nil
 }
 
@@ -1736,15 +1790,24 @@ func mvAction(c *cli.Context) error {
 
 	ctx := context.Background()
 
+	srcSSE, dstSSE, err := copyEncryption(c.String("sse-src"), c.String("sse"), sourceObject, destObject)
+	if err != nil {
+		return err
+	}
+
 	// 检查源对象是否存在
-	_, err = client.StatObject(ctx, session.BucketName, sourceObject, minio.StatObjectOptions{})
+	srcInfo, err := client.StatObject(ctx, session.BucketName, sourceObject, minio.StatObjectOptions{ServerSideEncryption: srcSSE})
 	if err != nil {
 		return fmt.Errorf("源文件 '%s' 不存在或无法访问", sourceFormatted)
 	}
 
-	// 检查目标对象是否存在
+	// 检查目标对象是否存在，目标为 SSE-C 加密时同样需要密钥
+	dstReadSSE, err := readEncryption(c.String("sse"), destObject)
+	if err != nil {
+		return err
+	}
 	destExists := false
-	_, err = client.StatObject(ctx, session.BucketName, destObject, minio.StatObjectOptions{})
+	_, err = client.StatObject(ctx, session.BucketName, destObject, minio.StatObjectOptions{ServerSideEncryption: dstReadSSE})
 	if err == nil {
 		destExists = true
 		if !c.Bool("f") {
@@ -1752,14 +1815,37 @@ func mvAction(c *cli.Context) error {
 		}
 	}
 
+	if plan := newPlan(c, "mv", session); plan != nil {
+		reason := "目标不存在"
+		if destExists {
+			reason = "覆盖已存在的目标"
+		}
+		action := PlanAction{Op: opMove, Source: sourceObject, Target: destObject, Size: srcInfo.Size, Reason: reason}
+		if c.String("sse") != "" || c.String("sse-src") != "" {
+			action.Options = &TransferOptions{SSE: c.String("sse"), SSESource: c.String("sse-src")}
+		}
+		plan.Add(action)
+		return plan.Finish(c)
+	}
+
+	// 启用回收站时，被覆盖的目标先复制到回收站
+	var trashed string
+	if destExists {
+		if trashed, err = trashOverwritten(ctx, client, session.BucketName, destObject, c.String("sse")); err != nil {
+			return err
+		}
+	}
+
 	// 执行复制操作
 	fmt.Printf("移动: %s -> %s\n", sourceFormatted, destFormatted)
 	_, err = client.CopyObject(ctx, minio.CopyDestOptions{
-		Bucket: session.BucketName,
-		Object: destObject,
+		Bucket:     session.BucketName,
+		Object:     destObject,
+		Encryption: dstSSE,
 	}, minio.CopySrcOptions{
-		Bucket: session.BucketName,
-		Object: sourceObject,
+		Bucket:     session.BucketName,
+		Object:     sourceObject,
+		Encryption: srcSSE,
 	})
 	if err != nil {
 		return fmt.Errorf("复制文件失败: %w", err)
@@ -1777,6 +1863,22 @@ func mvAction(c *cli.Context) error {
 		fmt.Printf("已移动文件 '%s' 到 '%s'\n", sourceFormatted, destFormatted)
 	}
 
+	// 记录撤销操作: 移回原位置，再还原被覆盖的目标
+	undo := []PlanAction{{Op: opMove, Source: destObject, Target: sourceObject, Size: srcInfo.Size, Reason: "撤销移动"}}
+	if c.String("sse") != "" || c.String("sse-src") != "" {
+		undo[0].Options = &TransferOptions{SSE: c.String("sse-src"), SSESource: c.String("sse")}
+	}
+	if trashed != "" {
+		restore := PlanAction{Op: opRestore, Source: trashed, Target: destObject, Reason: "撤销覆盖"}
+		if c.String("sse") != "" {
+			restore.Options = &TransferOptions{SSE: c.String("sse")}
+		}
+		undo = append(undo, restore)
+	}
+	if err := recordJournal("mv", session.BucketName, sourceFormatted+" -> "+destFormatted, undo); err != nil {
+		fmt.Fprintf(os.Stderr, "无法记录操作日志: %v\n", err)
+	}
+
 	return nil
 }
 
@@ -1819,15 +1921,24 @@ func cpAction(c *cli.Context) error {
 
 	ctx := context.Background()
 
+	srcSSE, dstSSE, err := copyEncryption(c.String("sse-src"), c.String("sse"), sourceObject, destObject)
+	if err != nil {
+		return err
+	}
+
 	// 检查源对象是否存在
-	_, err = client.StatObject(ctx, session.BucketName, sourceObject, minio.StatObjectOptions{})
+	srcInfo, err := client.StatObject(ctx, session.BucketName, sourceObject, minio.StatObjectOptions{ServerSideEncryption: srcSSE})
 	if err != nil {
 		return fmt.Errorf("源文件 '%s' 不存在或无法访问", sourceFormatted)
 	}
 
-	// 检查目标对象是否存在
+	// 检查目标对象是否存在，目标为 SSE-C 加密时同样需要密钥
+	dstReadSSE, err := readEncryption(c.String("sse"), destObject)
+	if err != nil {
+		return err
+	}
 	destExists := false
-	_, err = client.StatObject(ctx, session.BucketName, destObject, minio.StatObjectOptions{})
+	_, err = client.StatObject(ctx, session.BucketName, destObject, minio.StatObjectOptions{ServerSideEncryption: dstReadSSE})
 	if err == nil {
 		destExists = true
 		if !c.Bool("f") {
@@ -1835,14 +1946,36 @@ func cpAction(c *cli.Context) error {
 		}
 	}
 
+	if plan := newPlan(c, "cp", session); plan != nil {
+		reason := "目标不存在"
+		if destExists {
+			reason = "覆盖已存在的目标"
+		}
+		action := PlanAction{Op: opCopy, Source: sourceObject, Target: destObject, Size: srcInfo.Size, Reason: reason}
+		if c.String("sse") != "" || c.String("sse-src") != "" {
+			action.Options = &TransferOptions{SSE: c.String("sse"), SSESource: c.String("sse-src")}
+		}
+		plan.Add(action)
+		return plan.Finish(c)
+	}
+
+	// 启用回收站时，被覆盖的目标先复制到回收站
+	if destExists {
+		if _, err := trashOverwritten(ctx, client, session.BucketName, destObject, c.String("sse")); err != nil {
+			return err
+		}
+	}
+
 	// 执行复制操作
 	fmt.Printf("复制: %s -> %s\n", sourceFormatted, destFormatted)
 	_, err = client.CopyObject(ctx, minio.CopyDestOptions{
-		Bucket: session.BucketName,
-		Object: destObject,
+		Bucket:     session.BucketName,
+		Object:     destObject,
+		Encryption: dstSSE,
 	}, minio.CopySrcOptions{
-		Bucket: session.BucketName,
-		Object: sourceObject,
+		Bucket:     session.BucketName,
+		Object:     sourceObject,
+		Encryption: srcSSE,
 	})
 	if err != nil {
 		return fmt.Errorf("复制文件失败: %w", err)
@@ -1912,39 +2045,33 @@ func syncAction(c *cli.Context) error {
 		objectPrefix += "/"
 	}
 
-	fmt.Printf("同步目录: %s -> %s\n", localPath, formattedPath)
-
-	ctx := context.Background()
-
-	// 列出远程文件
-	remoteFiles := make(map[string]minio.ObjectInfo)
-
-	objectCh := client.ListObjects(ctx, session.BucketName, minio.ListObjectsOptions{
-		Prefix:    objectPrefix,
-		Recursive: true,
-	})
-
-	for object := range objectCh {
-		if object.Err != nil {
-			return fmt.Errorf("列出远程对象时出错: %w", object.Err)
+	if c.Bool("watch") {
+		if isDryRun(c) {
+			return fmt.Errorf("--watch 不能与 --dry-run 或 --plan-out 一起使用")
 		}
+		return watchSync(c, client, session, localPath, objectPrefix)
+	}
 
-		// 忽略目录对象
-		if strings.HasSuffix(object.Key, "/") {
-			continue
-		}
+	return syncOnce(context.Background(), c, client, session, localPath, objectPrefix)
+}
 
-		relPath := strings.TrimPrefix(object.Key, objectPrefix)
-		remoteFiles[relPath] = object
+// 执行一次完整的同步: 遍历本地目录，上传远程不存在或已变化的文件，指定 --delete 时删除本地不存在的远程文件
+// ctx 取消时停止遍历并返回，不会执行删除
+func syncOnce(ctx context.Context, c *cli.Context, client *minio.Client, session *Session, localPath, objectPrefix string) error {
+	fmt.Printf("同步目录: %s -> /%s\n", localPath, objectPrefix)
+
+	// 列出远程文件
+	remoteFiles, err := listRemoteFiles(ctx, client, session.BucketName, objectPrefix, session.Trash)
+	if err != nil {
+		return err
 	}
 
-	// 并发上传限制
-	workers := c.Int("w")
-	if workers < 1 {
-		workers = 1
-	} else if workers > 10 {
-		workers = 10
+	// 并发控制
+	concurrency, err := newConcurrency(c)
+	if err != nil {
+		return err
 	}
+	defer concurrency.Stop()
 
 	// 创建工作池
 	var wg sync.WaitGroup
@@ -1954,17 +2081,32 @@ func syncAction(c *cli.Context) error {
 	processedFiles := make(map[string]bool)
 	var processedMutex sync.Mutex
 
+	plan := newPlan(c, "sync", session)
+	opts, err := transferOptionsFromContext(c)
+	if err != nil {
+		return err
+	}
+	policy := retryPolicyFromContext(c)
+	failures := newFailureReport("sync", session)
+	progress := newProgress(c)
+
 	// 启动工作线程
-	for i := 0; i < workers; i++ {
+	for i := 0; i < concurrency.Workers(); i++ {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
 
 			for relPath := range jobCh {
+				// 使用互斥锁保护 map 写入，读取失败的文件也视为本地存在，避免被 --delete 删除
+				processedMutex.Lock()
+				processedFiles[relPath] = true
+				processedMutex.Unlock()
+
 				fullLocalPath := filepath.Join(localPath, relPath)
 				localFileInfo, err := os.Stat(fullLocalPath)
 				if err != nil {
-					fmt.Fprintf(os.Stderr, "获取本地文件信息失败 '%s': %v\n", fullLocalPath, err)
+					progress.Errorf("获取本地文件信息失败 '%s': %v", fullLocalPath, err)
+					failures.AddError(fmt.Errorf("获取本地文件信息失败 '%s': %w", fullLocalPath, err))
 					continue
 				}
 
@@ -1973,10 +2115,11 @@ func syncAction(c *cli.Context) error {
 					continue
 				}
 
-				// 使用互斥锁保护 map 写入
-				processedMutex.Lock()
-				processedFiles[relPath] = true
-				processedMutex.Unlock()
+				// 后台任务恢复时跳过已上传且之后没有修改的文件
+				checkpoint := syncCheckpointKey(objectPrefix+relPath, localFileInfo)
+				if checkpointDone(checkpoint) {
+					continue
+				}
 
 				remoteObj, exists := remoteFiles[relPath]
 
@@ -1985,37 +2128,76 @@ func syncAction(c *cli.Context) error {
 				// 2. 文件大小不一致
 				// 3. 本地文件的修改时间晚于远程文件
 				needUpload := false
+				reason := "未变化"
 				if !exists {
 					needUpload = true
-				} else if localFileInfo.Size() != remoteObj.Size {
-					needUpload = true
-				} else if localFileInfo.ModTime().After(remoteObj.LastModified) {
+					reason = "远程不存在"
+				} else if kind, why := compareLocalRemote(localFileInfo, syncRemoteSize(ctx, client, session.BucketName, remoteObj, opts), remoteObj.LastModified); kind != "" {
 					needUpload = true
+					reason = why
+				}
+
+				if plan != nil {
+					op := opSkip
+					if needUpload && exists {
+						op = opOverwrite
+					} else if needUpload {
+						op = opUpload
+					}
+					plan.Add(PlanAction{Op: op, Source: fullLocalPath, Target: objectPrefix + relPath, Size: localFileInfo.Size(), Reason: reason, Options: opts})
+					continue
 				}
 
 				if needUpload {
-					fmt.Printf("同步: %s\n", relPath)
+					progress.Logf("同步: %s", relPath)
+					progress.AddTotal(localFileInfo.Size())
 
-					file, err := os.Open(fullLocalPath)
-					if err != nil {
-						fmt.Fprintf(os.Stderr, "无法打开文件 '%s': %v\n", fullLocalPath, err)
+					// 计算对象名
+					objectName := objectPrefix + relPath
+
+					// 启用回收站时，被覆盖的对象先复制到回收站，上传失败时原对象保持不变
+					if _, err := trashOverwritten(ctx, client, session.BucketName, objectName, opts.sseSpec()); err != nil {
+						progress.Errorf("上传文件失败 '%s': %v", fullLocalPath, err)
+						failures.Add(PlanAction{Op: opOverwrite, Source: fullLocalPath, Target: objectName, Size: localFileInfo.Size(), Reason: reason, Options: opts}, 1, err)
 						continue
 					}
 
-					// 计算对象名
-					objectName := objectPrefix + relPath
+					concurrency.Acquire()
+					bar := progress.Start(relPath, localFileInfo.Size())
+					attempts, err := policy.Do(ctx, func() error {
+						bar.Reset()
 
-					// 获取文件 MIME 类型
-					contentType := getMimeType(fullLocalPath)
+						file, err := os.Open(fullLocalPath)
+						if err != nil {
+							return fmt.Errorf("无法打开文件: %w", err)
+						}
+						defer file.Close()
 
-					// 执行上传
-					_, err = client.PutObject(ctx, session.BucketName, objectName, file, localFileInfo.Size(), minio.PutObjectOptions{
-						ContentType: contentType,
+						// 获取文件 MIME 类型
+						contentType := detectContentType(fullLocalPath)
+
+						// 加密时进度按明文统计
+						reader, uploadSize, putOpts, err := opts.prepareUpload(objectName, contentType, NewProgressReader(newTransferReader(file), bar), localFileInfo.Size())
+						if err != nil {
+							return err
+						}
+
+						// 执行上传
+						_, err = client.PutObject(ctx, session.BucketName, objectName, reader, uploadSize, putOpts)
+						return err
 					})
-					file.Close()
+					bar.Done(err)
+					concurrency.Release(err)
 
 					if err != nil {
-						fmt.Fprintf(os.Stderr, "上传文件失败 '%s': %v\n", fullLocalPath, err)
+						progress.Errorf("上传文件失败 '%s': %v", fullLocalPath, err)
+						op := opUpload
+						if exists {
+							op = opOverwrite
+						}
+						failures.Add(PlanAction{Op: op, Source: fullLocalPath, Target: objectName, Size: localFileInfo.Size(), Reason: reason, Options: opts}, attempts, err)
+					} else {
+						checkpointMark(checkpoint)
 					}
 				}
 			}
@@ -2023,64 +2205,67 @@ func syncAction(c *cli.Context) error {
 	}
 
 	// 遍历本地文件
-	err = filepath.Walk(localPath, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
+	err = walkLocalFiles(localPath, func(relPath string, info os.FileInfo) error {
+		if err := ctx.Err(); err != nil {
 			return err
 		}
-
-		// 跳过隐藏文件和目录
-		if strings.HasPrefix(filepath.Base(path), ".") {
-			if info.IsDir() {
-				return filepath.SkipDir
-			}
-			return nil
-		}
-
-		if !info.IsDir() {
-			// 计算相对路径
-			relPath, err := filepath.Rel(localPath, path)
-			if err != nil {
-				return err
-			}
-
-			// 转换 Windows 路径分隔符
-			relPath = strings.ReplaceAll(relPath, "\\", "/")
-
-			// 发送任务
-			jobCh <- relPath
-		}
-
+		// 发送任务
+		jobCh <- relPath
 		return nil
 	})
 
-	if err != nil {
-		return fmt.Errorf("遍历本地目录失败: %w", err)
-	}
-
 	// 关闭任务通道并等待所有工作线程完成
 	close(jobCh)
 	wg.Wait()
+	progress.Finish()
+
+	// 未遍历完整个目录时不能删除远程文件，否则会误删尚未遍历到的文件
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("遍历本地目录失败: %w", err)
+	}
 
-	// 如果需要，删除远程不存在的文件
+	// 如果需要，删除远程不存在的文件，启用回收站时移到回收站
 	if c.Bool("delete") {
+		rm := newRemover(client, session, nil)
 		for remotePath, info := range remoteFiles {
 			// 使用互斥锁保护 map 读取
 			processedMutex.Lock()
 			processed := processedFiles[remotePath]
 			processedMutex.Unlock()
 
-			if !processed {
-				fmt.Printf("删除: %s\n", remotePath)
-				err := client.RemoveObject(ctx, session.BucketName, info.Key, minio.RemoveObjectOptions{})
-				if err != nil {
-					fmt.Fprintf(os.Stderr, "删除远程文件失败 '%s': %v\n", remotePath, err)
-				}
+			if processed {
+				continue
 			}
+
+			action := rm.action(info.Key, info.Size, "本地不存在")
+			if plan != nil {
+				plan.Add(action)
+				continue
+			}
+
+			fmt.Printf("删除: %s\n", remotePath)
+			attempts, err := runPlanAction(ctx, policy, client, session.BucketName, action)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "删除远程文件失败 '%s': %v\n", remotePath, err)
+				failures.Add(action, attempts, err)
+				continue
+			}
+			rm.record(action)
+		}
+		if plan == nil {
+			rm.finish("sync", "删除 /"+objectPrefix+" 下本地不存在的文件")
 		}
 	}
 
-	fmt.Printf("同步完成: %s -> %s\n", localPath, formattedPath)
-	return nil
+	if plan != nil {
+		return plan.Finish(c)
+	}
+
+	fmt.Printf("同步完成: %s -> /%s\n", localPath, objectPrefix)
+	return failures.Finish(c)
 }
 
 // 生成认证字符串操作
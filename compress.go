@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/encrypt"
+	"github.com/urfave/cli/v2"
+)
+
+// 压缩上传时写入的元数据，下载时据此自动解压
+const (
+	compressMetaAlgorithm    = "Minx-Compression"
+	compressMetaOriginalSize = "Minx-Original-Size"
+)
+
+// 每次从源读取的数据量
+const compressChunkSize = 256 * 1024
+
+// 压缩相关参数
+func compressFlag() cli.Flag {
+	return &cli.StringFlag{
+		Name:  "compress",
+		Usage: "上传时压缩: zstd 或 gzip，下载时自动解压",
+	}
+}
+
+// 检查压缩算法
+func parseCompression(algo string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(algo)) {
+	case "", "off", "none":
+		return "", nil
+	case "zstd":
+		return "zstd", nil
+	case "gzip", "gz":
+		return "gzip", nil
+	default:
+		return "", fmt.Errorf("不支持的压缩算法 '%s'，应为 zstd 或 gzip", algo)
+	}
+}
+
+// compressReader 在读取时压缩数据，按需从源读取，不使用额外的 goroutine
+// 上传中途失败时 minio-go 不会关闭读取器，因此不能依赖管道和后台写入
+type compressReader struct {
+	src   io.Reader
+	w     io.WriteCloser
+	buf   bytes.Buffer
+	chunk []byte
+	done  bool
+}
+
+func newCompressReader(algo string, src io.Reader) (*compressReader, error) {
+	r := &compressReader{src: src, chunk: make([]byte, compressChunkSize)}
+
+	switch algo {
+	case "gzip":
+		r.w = gzip.NewWriter(&r.buf)
+	case "zstd":
+		// 并发度为 1 时压缩同步进行
+		w, err := zstd.NewWriter(&r.buf, zstd.WithEncoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		r.w = w
+	default:
+		return nil, fmt.Errorf("不支持的压缩算法 '%s'", algo)
+	}
+
+	return r, nil
+}
+
+// Read 实现 io.Reader 接口
+func (r *compressReader) Read(p []byte) (int, error) {
+	for r.buf.Len() == 0 && !r.done {
+		n, err := r.src.Read(r.chunk)
+		if n > 0 {
+			if _, werr := r.w.Write(r.chunk[:n]); werr != nil {
+				return 0, werr
+			}
+		}
+
+		if err == io.EOF {
+			if cerr := r.w.Close(); cerr != nil {
+				return 0, cerr
+			}
+			r.done = true
+		} else if err != nil {
+			return 0, err
+		}
+	}
+
+	if r.buf.Len() == 0 {
+		return 0, io.EOF
+	}
+	return r.buf.Read(p)
+}
+
+// decompressReader 解压数据流，关闭时同时关闭底层数据流
+type decompressReader struct {
+	io.Reader
+	close func()
+	src   io.ReadCloser
+}
+
+func newDecompressReader(algo string, src io.ReadCloser) (*decompressReader, error) {
+	switch algo {
+	case "gzip":
+		r, err := gzip.NewReader(src)
+		if err != nil {
+			return nil, err
+		}
+		return &decompressReader{Reader: r, close: func() { r.Close() }, src: src}, nil
+
+	case "zstd":
+		r, err := zstd.NewReader(src, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		return &decompressReader{Reader: r, close: r.Close, src: src}, nil
+
+	default:
+		return nil, fmt.Errorf("不支持的压缩算法 '%s'", algo)
+	}
+}
+
+// Close 关闭解压器和底层数据流
+func (r *decompressReader) Close() error {
+	r.close()
+	return r.src.Close()
+}
+
+// 对象上传时使用的压缩算法，未压缩时为空字符串
+func compressionOf(info minio.ObjectInfo) string {
+	algo, err := parseCompression(userMeta(info, compressMetaAlgorithm))
+	if err != nil {
+		return ""
+	}
+	return algo
+}
+
+// 压缩前的大小，未知时返回 -1
+func originalSize(info minio.ObjectInfo) int64 {
+	size, err := strconv.ParseInt(userMeta(info, compressMetaOriginalSize), 10, 64)
+	if err != nil || size < 0 {
+		return -1
+	}
+	return size
+}
+
+// 压缩上传: 返回压缩后的数据流和要写入的元数据，size 为原始大小，未知时为 -1
+func compressUpload(algo string, r io.Reader, size int64) (io.Reader, map[string]string, error) {
+	reader, err := newCompressReader(algo, r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	meta := map[string]string{compressMetaAlgorithm: algo}
+	if size >= 0 {
+		meta[compressMetaOriginalSize] = strconv.FormatInt(size, 10)
+	}
+	return reader, meta, nil
+}
+
+// Open 打开对象，返回解密和解压后从偏移 offset 开始、长度为 length (小于 0 表示到末尾) 的数据
+// 压缩的数据流无法定位，断点续传和范围读取时从头解压并跳过 offset 之前的内容
+func (d *Decoder) Open(ctx context.Context, client *minio.Client, bucket string, info minio.ObjectInfo, sse encrypt.ServerSide, offset, length int64) (io.ReadCloser, error) {
+	algo := compressionOf(info)
+	if d.raw || algo == "" {
+		return d.openDecrypted(ctx, client, bucket, info, sse, offset, length)
+	}
+
+	src, err := d.openDecrypted(ctx, client, bucket, info, sse, 0, -1)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := newDecompressReader(algo, src)
+	if err != nil {
+		src.Close()
+		return nil, fmt.Errorf("解压失败: %w", err)
+	}
+
+	if offset > 0 {
+		if _, err := io.CopyN(io.Discard, r, offset); err != nil && err != io.EOF {
+			r.Close()
+			return nil, err
+		}
+	}
+
+	if length < 0 {
+		return r, nil
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(r, length), r}, nil
+}
+
+// 对象内容的实际大小 (解密和解压后)，列表结果不含元数据时单独获取，未知时返回 -1
+func contentSize(ctx context.Context, client *minio.Client, bucket string, info minio.ObjectInfo) int64 {
+	if info.UserMetadata == nil {
+		if stat, err := client.StatObject(ctx, bucket, info.Key, minio.StatObjectOptions{}); err == nil {
+			info = stat
+		}
+	}
+	return newDecoder(nil).PlainSize(info)
+}
+
+// sync 比较用的远程大小: 列表带有元数据，或本次上传会压缩、加密时，按还原后的大小比较
+// 列表不含元数据且上传原样进行时直接使用存储大小，避免逐个获取对象信息
+func syncRemoteSize(ctx context.Context, client *minio.Client, bucket string, info minio.ObjectInfo, opts *TransferOptions) int64 {
+	if info.UserMetadata == nil && (opts == nil || (opts.Encrypt == nil && opts.Compress == "")) {
+		return info.Size
+	}
+	return contentSize(ctx, client, bucket, info)
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// fakeS3 是只支持 GET 和 HEAD (含 Range) 的内存对象存储，用于测试下载和解码
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte // 键为 bucket/key
+}
+
+func (s *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	data, ok := s.objects[strings.TrimPrefix(r.URL.Path, "/")]
+	s.mu.Unlock()
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	size := int64(len(data))
+	start, end := int64(0), size-1
+	status := http.StatusOK
+	if rng := r.Header.Get("Range"); rng != "" {
+		from, to, _ := strings.Cut(strings.TrimPrefix(rng, "bytes="), "-")
+		start, _ = strconv.ParseInt(from, 10, 64)
+		if to != "" {
+			end, _ = strconv.ParseInt(to, 10, 64)
+		}
+		if start >= size {
+			w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+			return
+		}
+		if end >= size {
+			end = size - 1
+		}
+		status = http.StatusPartialContent
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, size))
+	}
+
+	w.Header().Set("ETag", `"d41d8cd98f00b204e9800998ecf8427e"`)
+	w.Header().Set("Last-Modified", time.Unix(0, 0).UTC().Format(http.TimeFormat))
+	w.Header().Set("Content-Length", strconv.FormatInt(end-start+1, 10))
+	w.WriteHeader(status)
+	if r.Method != http.MethodHead {
+		w.Write(data[start : end+1])
+	}
+}
+
+func newTestClient(t *testing.T) (*minio.Client, *fakeS3) {
+	t.Helper()
+
+	fake := &fakeS3{objects: make(map[string][]byte)}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := minio.New(u.Host, &minio.Options{
+		Creds:        credentials.NewStaticV4("access", "secret", ""),
+		Region:       "us-east-1",
+		BucketLookup: minio.BucketLookupPath,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client, fake
+}
+
+// 生成身份文件，返回文件路径和对应的公钥
+func writeTestIdentity(t *testing.T) (string, string) {
+	t.Helper()
+
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "identity")
+	content := cseSecretKeyPrefix + base64.RawURLEncoding.EncodeToString(key.Bytes()) + "\n"
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path, formatRecipient(key.PublicKey())
+}
+
+// 可压缩且跨越多个加密分块的测试数据
+func testPlaintext() []byte {
+	var b bytes.Buffer
+	for i := 0; b.Len() < 3*cseChunkSize+1234; i++ {
+		fmt.Fprintf(&b, "line %d: minx round trip test\n", i)
+	}
+	return b.Bytes()
+}
+
+func TestPrepareUploadRoundTrip(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	client, fake := newTestClient(t)
+	plain := testPlaintext()
+	size := int64(len(plain))
+
+	identity, recipient := writeTestIdentity(t)
+	passphraseFile := filepath.Join(t.TempDir(), "passphrase")
+	if err := os.WriteFile(passphraseFile, []byte("correct horse\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		opts *TransferOptions
+		dec  *Decoder
+	}{
+		{"plain", &TransferOptions{}, &Decoder{}},
+		{"gzip", &TransferOptions{Compress: "gzip"}, &Decoder{}},
+		{"zstd", &TransferOptions{Compress: "zstd"}, &Decoder{}},
+		{"cse", &TransferOptions{Encrypt: &EncryptOptions{Recipients: []string{recipient}}}, &Decoder{identity: identity}},
+		{"cse+gzip", &TransferOptions{Compress: "gzip", Encrypt: &EncryptOptions{Recipients: []string{recipient}}}, &Decoder{identity: identity}},
+		{"cse+zstd passphrase", &TransferOptions{Compress: "zstd", Encrypt: &EncryptOptions{Passphrase: true, PassphraseFile: passphraseFile}}, &Decoder{passphraseFile: passphraseFile}},
+	}
+
+	ranges := []struct{ offset, length int64 }{
+		{0, -1},
+		{0, 0},
+		{1, 10},
+		{cseChunkSize - 1, 2},
+		{cseChunkSize, -1},
+		{cseChunkSize + 17, cseChunkSize + 5},
+		{2*cseChunkSize + 3, 100},
+		{size - 1, -1},
+		{size - 10, 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader, uploadSize, putOpts, err := tt.opts.prepareUpload("obj", "text/plain", bytes.NewReader(plain), size)
+			if err != nil {
+				t.Fatal(err)
+			}
+			stored, err := io.ReadAll(reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if uploadSize >= 0 && uploadSize != int64(len(stored)) {
+				t.Fatalf("声明的上传大小 %d 与实际 %d 不符", uploadSize, len(stored))
+			}
+			if tt.opts.Compress != "" && tt.opts.Encrypt == nil && putOpts.ContentEncoding != tt.opts.Compress {
+				t.Errorf("Content-Encoding = %q, 期望 %q", putOpts.ContentEncoding, tt.opts.Compress)
+			}
+			if tt.opts.Encrypt != nil && (putOpts.ContentEncoding != "" || putOpts.ContentType != "application/octet-stream") {
+				t.Errorf("加密对象不应声明内容头: Content-Encoding %q, Content-Type %q", putOpts.ContentEncoding, putOpts.ContentType)
+			}
+			if (tt.opts.Compress != "" || tt.opts.Encrypt != nil) && bytes.Equal(stored, plain) {
+				t.Fatal("存储的内容与明文相同")
+			}
+
+			key := "bkt/" + tt.name
+			fake.mu.Lock()
+			fake.objects[key] = stored
+			fake.mu.Unlock()
+			info := minio.ObjectInfo{Key: tt.name, Size: int64(len(stored)), UserMetadata: putOpts.UserMetadata}
+
+			if got := tt.dec.PlainSize(info); got != size {
+				t.Errorf("PlainSize = %d, 期望 %d", got, size)
+			}
+
+			for _, r := range ranges {
+				want := plain[r.offset:]
+				if r.length >= 0 && r.length < int64(len(want)) {
+					want = want[:r.length]
+				}
+
+				rc, err := tt.dec.Open(context.Background(), client, "bkt", info, nil, r.offset, r.length)
+				if err != nil {
+					t.Fatalf("Open(%d, %d): %v", r.offset, r.length, err)
+				}
+				got, err := io.ReadAll(rc)
+				rc.Close()
+				if err != nil {
+					t.Fatalf("Open(%d, %d) 读取失败: %v", r.offset, r.length, err)
+				}
+				if !bytes.Equal(got, want) {
+					t.Errorf("Open(%d, %d) 返回 %d 字节，期望 %d 字节", r.offset, r.length, len(got), len(want))
+				}
+			}
+
+			// --raw 原样返回存储的内容
+			rc, err := (&Decoder{raw: true}).Open(context.Background(), client, "bkt", info, nil, 0, -1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, _ := io.ReadAll(rc)
+			rc.Close()
+			if !bytes.Equal(got, stored) {
+				t.Errorf("raw 读取返回 %d 字节，期望 %d 字节", len(got), len(stored))
+			}
+		})
+	}
+}
+
+func TestDecoderWrongIdentity(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	client, fake := newTestClient(t)
+
+	_, recipient := writeTestIdentity(t)
+	other, _ := writeTestIdentity(t)
+
+	opts := &TransferOptions{Encrypt: &EncryptOptions{Recipients: []string{recipient}}}
+	reader, _, putOpts, err := opts.prepareUpload("obj", "text/plain", strings.NewReader("secret"), 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake.mu.Lock()
+	fake.objects["bkt/obj"] = stored
+	fake.mu.Unlock()
+	info := minio.ObjectInfo{Key: "obj", Size: int64(len(stored)), UserMetadata: putOpts.UserMetadata}
+
+	if _, err := (&Decoder{identity: other}).Open(context.Background(), client, "bkt", info, nil, 0, -1); err == nil {
+		t.Fatal("使用不匹配的身份应当无法解密")
+	}
+}
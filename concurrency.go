@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	maxWorkers       = 64              // 固定并发数的上限
+	autoMaxWorkers   = 32              // 自适应模式的并发上限
+	autoStartWorkers = 4               // 自适应模式的初始并发数
+	autoInterval     = 3 * time.Second // 自适应模式的调整周期
+)
+
+// Concurrency 控制同时进行的传输数量，auto 模式下根据吞吐量和错误率动态调整
+type Concurrency struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	limit  int
+	active int
+	max    int
+	auto   bool
+
+	done     int
+	errors   int
+	lastRate float64
+	stop     chan struct{}
+}
+
+// 根据 -w 参数创建并发控制，取值为数字或 auto
+func newConcurrency(c *cli.Context) (*Concurrency, error) {
+	value := strings.TrimSpace(c.String("w"))
+
+	cc := &Concurrency{stop: make(chan struct{})}
+	cc.cond = sync.NewCond(&cc.mu)
+
+	if value == "auto" {
+		cc.auto = true
+		cc.limit = autoStartWorkers
+		cc.max = autoMaxWorkers
+		go cc.adjustLoop()
+		return cc, nil
+	}
+
+	workers, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, fmt.Errorf("无效的并发数 '%s'，应为数字或 auto", value)
+	}
+	if workers < 1 {
+		workers = 1
+	} else if workers > maxWorkers {
+		workers = maxWorkers
+	}
+
+	cc.limit = workers
+	cc.max = workers
+	return cc, nil
+}
+
+// Workers 返回需要启动的工作线程数
+func (cc *Concurrency) Workers() int {
+	return cc.max
+}
+
+// Acquire 在开始一次传输前调用，超过当前并发上限时等待
+func (cc *Concurrency) Acquire() {
+	cc.mu.Lock()
+	for cc.active >= cc.limit {
+		cc.cond.Wait()
+	}
+	cc.active++
+	cc.mu.Unlock()
+}
+
+// Release 在一次传输结束后调用
+func (cc *Concurrency) Release(err error) {
+	cc.mu.Lock()
+	cc.active--
+	cc.done++
+	if err != nil {
+		cc.errors++
+	}
+	cc.mu.Unlock()
+	cc.cond.Broadcast()
+}
+
+// Stop 停止自适应调整
+func (cc *Concurrency) Stop() {
+	if cc.auto {
+		close(cc.stop)
+	}
+}
+
+// 周期性地根据吞吐量和错误率调整并发上限
+func (cc *Concurrency) adjustLoop() {
+	ticker := time.NewTicker(autoInterval)
+	defer ticker.Stop()
+
+	lastBytes := atomic.LoadInt64(&transferredBytes)
+	for {
+		select {
+		case <-cc.stop:
+			return
+		case <-ticker.C:
+		}
+
+		bytes := atomic.LoadInt64(&transferredBytes)
+		rate := float64(bytes-lastBytes) / autoInterval.Seconds()
+		lastBytes = bytes
+
+		cc.mu.Lock()
+		switch {
+		case cc.errors > 0 && cc.errors*10 >= cc.done:
+			// 错误率超过 10%，并发减半
+			cc.limit = max(1, cc.limit/2)
+		case rate > cc.lastRate*1.05 && cc.active >= cc.limit:
+			// 吞吐量仍在增长且并发已用满，继续增加
+			cc.limit = min(cc.max, cc.limit+1)
+		case rate < cc.lastRate*0.9:
+			// 吞吐量下降，说明并发过高
+			cc.limit = max(1, cc.limit-1)
+		}
+		cc.lastRate = rate
+		cc.done = 0
+		cc.errors = 0
+		cc.mu.Unlock()
+		cc.cond.Broadcast()
+	}
+}
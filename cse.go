@@ -0,0 +1,807 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/encrypt"
+	"github.com/urfave/cli/v2"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+	"golang.org/x/crypto/scrypt"
+)
+
+// 客户端加密格式:
+// 对象内容按 64 KiB 明文分块，每块使用 ChaCha20-Poly1305 单独加密并附带 16 字节认证标签。
+// 随机数由块序号和末块标记组成，截断、重排或替换分块都会导致解密失败。
+// 每个对象使用随机的文件密钥，文件密钥分别用每个接收者的 X25519 公钥或口令 (scrypt) 包装后保存在元数据中。
+const (
+	cseVersion    = "v1"
+	cseChunkSize  = 64 * 1024
+	cseTagSize    = chacha20poly1305.Overhead
+	cseScryptLogN = 15
+
+	cseMetaVersion     = "Minx-Cse"
+	cseMetaStanzas     = "Minx-Cse-Stanzas"
+	cseMetaContentType = "Minx-Cse-Content-Type"
+
+	csePublicKeyPrefix = "minx-pub-"
+	cseSecretKeyPrefix = "MINX-SECRET-KEY-"
+)
+
+// EncryptOptions 描述客户端加密的接收者或口令，以及解密时使用的身份文件
+type EncryptOptions struct {
+	Recipients     []string `json:"recipients,omitempty"`      // X25519 公钥 (minx-pub-...)
+	PassphraseFile string   `json:"passphrase_file,omitempty"` // 口令文件，未指定时读取 MINX_PASSPHRASE 环境变量
+	Passphrase     bool     `json:"passphrase,omitempty"`      // 使用口令加密
+	Identity       string   `json:"identity,omitempty"`        // 解密用的身份文件，默认 ~/.minx/identity
+}
+
+// 包装后的文件密钥
+type cseStanza struct {
+	Type string `json:"type"`           // x25519 或 scrypt
+	Arg  string `json:"arg"`            // x25519 为临时公钥，scrypt 为盐
+	LogN int    `json:"logn,omitempty"` // scrypt 的成本参数
+	Body string `json:"body"`           // 加密后的文件密钥
+}
+
+// 客户端加密相关参数
+func encryptFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "encrypt",
+			Usage: "上传前在客户端加密 (需要 --recipient 或口令)",
+		},
+		&cli.StringSliceFlag{
+			Name:  "recipient",
+			Usage: "加密接收者的公钥 (minx-pub-...，由 minx keygen 生成)，可多次指定",
+		},
+		&cli.StringFlag{
+			Name:  "passphrase-file",
+			Usage: "口令文件，未指定时使用 MINX_PASSPHRASE 环境变量",
+		},
+	}
+}
+
+// 解密相关参数
+func decryptFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:  "identity",
+			Usage: "解密用的身份文件 (默认 ~/.minx/identity)",
+		},
+		&cli.StringFlag{
+			Name:  "passphrase-file",
+			Usage: "解密用的口令文件，未指定时使用 MINX_PASSPHRASE 环境变量",
+		},
+		&cli.BoolFlag{
+			Name:  "raw",
+			Usage: "不解密、不解压，按原样输出存储的内容",
+		},
+	}
+}
+
+// 根据命令行参数构建加密选项，未指定 --encrypt 时返回 nil
+func encryptOptionsFromContext(c *cli.Context) (*EncryptOptions, error) {
+	if !c.Bool("encrypt") {
+		if len(c.StringSlice("recipient")) > 0 {
+			return nil, fmt.Errorf("--recipient 需要与 --encrypt 一起使用")
+		}
+		return nil, nil
+	}
+
+	opts := &EncryptOptions{
+		Recipients:     c.StringSlice("recipient"),
+		PassphraseFile: c.String("passphrase-file"),
+	}
+	for _, r := range opts.Recipients {
+		if _, err := parseRecipient(r); err != nil {
+			return nil, err
+		}
+	}
+
+	// 未指定接收者时使用口令加密
+	if len(opts.Recipients) == 0 || opts.PassphraseFile != "" {
+		opts.Passphrase = true
+		if _, err := readPassphrase(opts.PassphraseFile); err != nil {
+			return nil, err
+		}
+	}
+
+	return opts, nil
+}
+
+// 读取口令
+func readPassphrase(path string) ([]byte, error) {
+	if path == "" {
+		if p := os.Getenv("MINX_PASSPHRASE"); p != "" {
+			return []byte(p), nil
+		}
+		return nil, fmt.Errorf("需要通过 --recipient 指定接收者，或通过 --passphrase-file / MINX_PASSPHRASE 提供口令")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("无法读取口令文件: %w", err)
+	}
+
+	passphrase := strings.TrimRight(string(data), "\r\n")
+	if passphrase == "" {
+		return nil, fmt.Errorf("口令文件 '%s' 为空", path)
+	}
+	return []byte(passphrase), nil
+}
+
+// 解析接收者公钥
+func parseRecipient(s string) (*ecdh.PublicKey, error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, csePublicKeyPrefix) {
+		return nil, fmt.Errorf("无效的公钥 '%s'，应以 %s 开头", s, csePublicKeyPrefix)
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(s, csePublicKeyPrefix))
+	if err != nil {
+		return nil, fmt.Errorf("无效的公钥 '%s': %w", s, err)
+	}
+	return ecdh.X25519().NewPublicKey(data)
+}
+
+// 读取身份文件中的私钥，每行一个，# 开头为注释
+func loadIdentities(path string) ([]*ecdh.PrivateKey, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var keys []*ecdh.PrivateKey
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if !strings.HasPrefix(line, cseSecretKeyPrefix) {
+			return nil, fmt.Errorf("身份文件 '%s' 中有无效的私钥", path)
+		}
+
+		data, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(line, cseSecretKeyPrefix))
+		if err != nil {
+			return nil, fmt.Errorf("身份文件 '%s' 中有无效的私钥: %w", path, err)
+		}
+		key, err := ecdh.X25519().NewPrivateKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("身份文件 '%s' 中有无效的私钥: %w", path, err)
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, scanner.Err()
+}
+
+// 默认身份文件路径
+func defaultIdentityPath() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(homeDir, ".minx", "identity")
+}
+
+// 公钥的文本形式
+func formatRecipient(key *ecdh.PublicKey) string {
+	return csePublicKeyPrefix + base64.RawURLEncoding.EncodeToString(key.Bytes())
+}
+
+// 用于包装文件密钥的 AEAD，每个包装密钥只使用一次，因此随机数固定为零
+func sealFileKey(wrapKey, fileKey []byte) ([]byte, error) {
+	aead, err := chacha20poly1305.New(wrapKey)
+	if err != nil {
+		return nil, err
+	}
+	return aead.Seal(nil, make([]byte, chacha20poly1305.NonceSize), fileKey, nil), nil
+}
+
+// 解开包装的文件密钥
+func openFileKey(wrapKey, body []byte) ([]byte, error) {
+	aead, err := chacha20poly1305.New(wrapKey)
+	if err != nil {
+		return nil, err
+	}
+	return aead.Open(nil, make([]byte, chacha20poly1305.NonceSize), body, nil)
+}
+
+// 由 X25519 共享密钥派生包装密钥
+func x25519WrapKey(shared, ephemeral, recipient []byte) ([]byte, error) {
+	salt := append(append([]byte{}, ephemeral...), recipient...)
+	key := make([]byte, chacha20poly1305.KeySize)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, shared, salt, []byte("minx-cse-x25519")), key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// 为新对象生成文件密钥，并为每个接收者生成包装后的密钥
+func newEnvelope(opts *EncryptOptions) ([]byte, []cseStanza, error) {
+	fileKey := make([]byte, chacha20poly1305.KeySize)
+	if _, err := rand.Read(fileKey); err != nil {
+		return nil, nil, err
+	}
+
+	var stanzas []cseStanza
+	for _, r := range opts.Recipients {
+		recipient, err := parseRecipient(r)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, nil, err
+		}
+		shared, err := ephemeral.ECDH(recipient)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		wrapKey, err := x25519WrapKey(shared, ephemeral.PublicKey().Bytes(), recipient.Bytes())
+		if err != nil {
+			return nil, nil, err
+		}
+		body, err := sealFileKey(wrapKey, fileKey)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		stanzas = append(stanzas, cseStanza{
+			Type: "x25519",
+			Arg:  base64.RawURLEncoding.EncodeToString(ephemeral.PublicKey().Bytes()),
+			Body: base64.RawURLEncoding.EncodeToString(body),
+		})
+	}
+
+	if opts.Passphrase {
+		passphrase, err := readPassphrase(opts.PassphraseFile)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		salt := make([]byte, 16)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, nil, err
+		}
+		wrapKey, err := scrypt.Key(passphrase, salt, 1<<cseScryptLogN, 8, 1, chacha20poly1305.KeySize)
+		if err != nil {
+			return nil, nil, err
+		}
+		body, err := sealFileKey(wrapKey, fileKey)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		stanzas = append(stanzas, cseStanza{
+			Type: "scrypt",
+			Arg:  base64.RawURLEncoding.EncodeToString(salt),
+			LogN: cseScryptLogN,
+			Body: base64.RawURLEncoding.EncodeToString(body),
+		})
+	}
+
+	return fileKey, stanzas, nil
+}
+
+// 加密上传: 返回加密后的数据流、密文长度和要写入的元数据
+func encryptUpload(opts *EncryptOptions, r io.Reader, size int64, contentType string) (io.Reader, int64, map[string]string, error) {
+	fileKey, stanzas, err := newEnvelope(opts)
+	if err != nil {
+		return nil, 0, nil, fmt.Errorf("生成加密密钥失败: %w", err)
+	}
+
+	data, err := json.Marshal(stanzas)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+
+	reader, err := newEncryptReader(r, fileKey)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+
+	meta := map[string]string{
+		cseMetaVersion:     cseVersion,
+		cseMetaStanzas:     base64.StdEncoding.EncodeToString(data),
+		cseMetaContentType: contentType,
+	}
+	return reader, encryptedSize(size), meta, nil
+}
+
+// 由明文长度计算密文长度，未知时返回 -1
+func encryptedSize(plain int64) int64 {
+	if plain < 0 {
+		return -1
+	}
+	chunks := plain / cseChunkSize
+	if plain%cseChunkSize != 0 || plain == 0 {
+		chunks++
+	}
+	return plain + chunks*cseTagSize
+}
+
+// 由密文长度计算分块数和明文长度
+func decryptedSize(cipherSize int64) (int64, int64, error) {
+	if cipherSize < cseTagSize {
+		return 0, 0, fmt.Errorf("加密数据长度无效")
+	}
+	full := int64(cseChunkSize + cseTagSize)
+	chunks := (cipherSize + full - 1) / full
+	plain := cipherSize - chunks*cseTagSize
+	if plain < 0 || (chunks > 1 && cipherSize-(chunks-1)*full <= cseTagSize) {
+		return 0, 0, fmt.Errorf("加密数据长度无效")
+	}
+	return chunks, plain, nil
+}
+
+// 分块的随机数: 前 11 字节为块序号，最后 1 字节标记是否为末块
+func cseNonce(counter uint64, final bool) []byte {
+	nonce := make([]byte, chacha20poly1305.NonceSize)
+	binary.BigEndian.PutUint64(nonce[3:11], counter)
+	if final {
+		nonce[11] = 1
+	}
+	return nonce
+}
+
+// encryptReader 按块加密数据流，长度未知时通过预读一个字节判断末块
+type encryptReader struct {
+	src     io.Reader
+	aead    cipher.AEAD
+	plain   []byte
+	have    int
+	sealed  []byte
+	out     []byte
+	counter uint64
+	done    bool
+}
+
+func newEncryptReader(src io.Reader, key []byte) (*encryptReader, error) {
+	aead, err := chacha20poly1305.New(key)
+	if err != nil {
+		return nil, err
+	}
+	return &encryptReader{
+		src:    src,
+		aead:   aead,
+		plain:  make([]byte, cseChunkSize+1),
+		sealed: make([]byte, 0, cseChunkSize+cseTagSize),
+	}, nil
+}
+
+// Read 实现 io.Reader 接口
+func (r *encryptReader) Read(p []byte) (int, error) {
+	for len(r.out) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		if err := r.seal(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, r.out)
+	r.out = r.out[n:]
+	return n, nil
+}
+
+// 读取并加密下一块
+func (r *encryptReader) seal() error {
+	n, err := io.ReadFull(r.src, r.plain[r.have:])
+	r.have += n
+
+	final := false
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		final = true
+	} else if err != nil {
+		return err
+	}
+
+	size := r.have
+	if !final {
+		size = cseChunkSize
+	}
+
+	r.out = r.aead.Seal(r.sealed[:0], cseNonce(r.counter, final), r.plain[:size], nil)
+	r.counter++
+
+	if final {
+		r.done = true
+	} else {
+		// 预读的一个字节属于下一块
+		r.plain[0] = r.plain[cseChunkSize]
+		r.have = 1
+	}
+	return nil
+}
+
+// decryptReader 从第 counter 块开始解密，跳过首块的 skip 个字节，最多输出 remaining 个字节
+type decryptReader struct {
+	src       io.ReadCloser
+	aead      cipher.AEAD
+	counter   uint64
+	last      uint64
+	lastSize  int
+	skip      int
+	remaining int64
+	buf       []byte
+	out       []byte
+}
+
+// Read 实现 io.Reader 接口
+func (r *decryptReader) Read(p []byte) (int, error) {
+	for len(r.out) == 0 {
+		if r.remaining == 0 || r.counter > r.last {
+			return 0, io.EOF
+		}
+
+		size := cseChunkSize + cseTagSize
+		if r.counter == r.last {
+			size = r.lastSize
+		}
+
+		if _, err := io.ReadFull(r.src, r.buf[:size]); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return 0, err
+		}
+
+		plain, err := r.aead.Open(r.buf[:0], cseNonce(r.counter, r.counter == r.last), r.buf[:size], nil)
+		if err != nil {
+			return 0, permanentError{fmt.Errorf("解密第 %d 块失败，数据可能已损坏或被篡改", r.counter)}
+		}
+		r.counter++
+
+		if r.skip > 0 {
+			plain = plain[r.skip:]
+			r.skip = 0
+		}
+		if r.remaining >= 0 && int64(len(plain)) > r.remaining {
+			plain = plain[:r.remaining]
+		}
+		if r.remaining > 0 {
+			r.remaining -= int64(len(plain))
+		}
+		r.out = plain
+	}
+
+	n := copy(p, r.out)
+	r.out = r.out[n:]
+	return n, nil
+}
+
+// Close 关闭底层数据流
+func (r *decryptReader) Close() error {
+	return r.src.Close()
+}
+
+// 在元数据中查找键，兼容 StatObject 和带元数据的列表两种返回格式
+func userMeta(info minio.ObjectInfo, key string) string {
+	for k, v := range info.UserMetadata {
+		k = strings.TrimPrefix(strings.ToLower(k), "x-amz-meta-")
+		if k == strings.ToLower(key) {
+			return v
+		}
+	}
+	return ""
+}
+
+// 判断对象是否经过客户端加密
+func isClientEncrypted(info minio.ObjectInfo) bool {
+	return userMeta(info, cseMetaVersion) != ""
+}
+
+// 客户端加密对象的信封摘要，用于 stat 显示
+func describeEnvelope(info minio.ObjectInfo) string {
+	if !isClientEncrypted(info) {
+		return ""
+	}
+
+	data, err := base64.StdEncoding.DecodeString(userMeta(info, cseMetaStanzas))
+	var stanzas []cseStanza
+	if err != nil || json.Unmarshal(data, &stanzas) != nil {
+		return userMeta(info, cseMetaVersion) + " (信封无效)"
+	}
+
+	recipients, passphrase := 0, false
+	for _, s := range stanzas {
+		switch s.Type {
+		case "x25519":
+			recipients++
+		case "scrypt":
+			passphrase = true
+		}
+	}
+
+	desc := fmt.Sprintf("%s, %d 个接收者", userMeta(info, cseMetaVersion), recipients)
+	if passphrase {
+		desc += ", 口令"
+	}
+	return desc
+}
+
+// Decoder 负责打开对象并在需要时透明解密和解压，身份和口令在首次遇到加密对象时才加载
+type Decoder struct {
+	raw            bool
+	identity       string
+	passphraseFile string
+
+	once       sync.Once
+	identities []*ecdh.PrivateKey
+	passphrase []byte
+	loadErr    error
+}
+
+// 根据命令行参数创建解码器，c 为 nil 时使用默认身份文件和 MINX_PASSPHRASE
+func newDecoder(c *cli.Context) *Decoder {
+	d := &Decoder{}
+	if c != nil {
+		d.raw = c.Bool("raw")
+		d.identity = c.String("identity")
+		d.passphraseFile = c.String("passphrase-file")
+	}
+	return d
+}
+
+// 根据计划操作中保存的选项创建解码器
+func decoderFromOptions(o *TransferOptions) *Decoder {
+	d := &Decoder{}
+	if o != nil && o.Encrypt != nil {
+		d.identity = o.Encrypt.Identity
+		d.passphraseFile = o.Encrypt.PassphraseFile
+	}
+	return d
+}
+
+// 记录解密参数，用于失败重试
+func (d *Decoder) options() *EncryptOptions {
+	if d.identity == "" && d.passphraseFile == "" {
+		return nil
+	}
+	return &EncryptOptions{Identity: d.identity, PassphraseFile: d.passphraseFile}
+}
+
+// 加载身份和口令
+func (d *Decoder) load() error {
+	d.once.Do(func() {
+		path := d.identity
+		if path == "" {
+			path = defaultIdentityPath()
+		}
+
+		if path != "" {
+			keys, err := loadIdentities(path)
+			if err != nil && (d.identity != "" || !errors.Is(err, os.ErrNotExist)) {
+				d.loadErr = fmt.Errorf("无法读取身份文件: %w", err)
+				return
+			}
+			d.identities = keys
+		}
+
+		if passphrase, err := readPassphrase(d.passphraseFile); err == nil {
+			d.passphrase = passphrase
+		} else if d.passphraseFile != "" {
+			d.loadErr = err
+			return
+		}
+
+		if len(d.identities) == 0 && d.passphrase == nil {
+			d.loadErr = fmt.Errorf("文件经过客户端加密，需要身份文件 (~/.minx/identity 或 --identity) 或口令 (MINX_PASSPHRASE 或 --passphrase-file)")
+		}
+	})
+	return d.loadErr
+}
+
+// PlainSize 返回对象解密和解压后的大小，info 需要包含元数据，压缩前的大小未知时返回 -1
+func (d *Decoder) PlainSize(info minio.ObjectInfo) int64 {
+	if d.raw {
+		return info.Size
+	}
+	if compressionOf(info) != "" {
+		return originalSize(info)
+	}
+	if !isClientEncrypted(info) {
+		return info.Size
+	}
+	_, plain, err := decryptedSize(info.Size)
+	if err != nil {
+		return info.Size
+	}
+	return plain
+}
+
+// 解开对象的文件密钥
+func (d *Decoder) fileKey(info minio.ObjectInfo) ([]byte, error) {
+	if v := userMeta(info, cseMetaVersion); v != cseVersion {
+		return nil, fmt.Errorf("不支持的客户端加密版本 '%s'", v)
+	}
+
+	data, err := base64.StdEncoding.DecodeString(userMeta(info, cseMetaStanzas))
+	if err != nil {
+		return nil, fmt.Errorf("加密信封无效: %w", err)
+	}
+	var stanzas []cseStanza
+	if err := json.Unmarshal(data, &stanzas); err != nil {
+		return nil, fmt.Errorf("加密信封无效: %w", err)
+	}
+
+	if err := d.load(); err != nil {
+		return nil, err
+	}
+
+	for _, s := range stanzas {
+		arg, err := base64.RawURLEncoding.DecodeString(s.Arg)
+		if err != nil {
+			continue
+		}
+		body, err := base64.RawURLEncoding.DecodeString(s.Body)
+		if err != nil {
+			continue
+		}
+
+		switch s.Type {
+		case "x25519":
+			ephemeral, err := ecdh.X25519().NewPublicKey(arg)
+			if err != nil {
+				continue
+			}
+			for _, identity := range d.identities {
+				shared, err := identity.ECDH(ephemeral)
+				if err != nil {
+					continue
+				}
+				wrapKey, err := x25519WrapKey(shared, arg, identity.PublicKey().Bytes())
+				if err != nil {
+					continue
+				}
+				if key, err := openFileKey(wrapKey, body); err == nil {
+					return key, nil
+				}
+			}
+
+		case "scrypt":
+			if d.passphrase == nil || s.LogN <= 0 || s.LogN > 22 {
+				continue
+			}
+			wrapKey, err := scrypt.Key(d.passphrase, arg, 1<<s.LogN, 8, 1, chacha20poly1305.KeySize)
+			if err != nil {
+				continue
+			}
+			if key, err := openFileKey(wrapKey, body); err == nil {
+				return key, nil
+			}
+		}
+	}
+
+	return nil, permanentError{fmt.Errorf("没有匹配的身份或口令，无法解密 '/%s'", info.Key)}
+}
+
+// 打开对象并在需要时解密，返回从明文偏移 offset 开始、长度为 length (小于 0 表示到末尾) 的数据
+// 客户端加密的对象只下载覆盖所需范围的分块并解密，因此断点续传和范围读取同样适用
+func (d *Decoder) openDecrypted(ctx context.Context, client *minio.Client, bucket string, info minio.ObjectInfo, sse encrypt.ServerSide, offset, length int64) (io.ReadCloser, error) {
+	if d.raw || !isClientEncrypted(info) {
+		opts := minio.GetObjectOptions{ServerSideEncryption: sse}
+		if length >= 0 {
+			if length == 0 {
+				return io.NopCloser(strings.NewReader("")), nil
+			}
+			if err := opts.SetRange(offset, offset+length-1); err != nil {
+				return nil, err
+			}
+		} else if offset > 0 {
+			if err := opts.SetRange(offset, 0); err != nil {
+				return nil, err
+			}
+		}
+		return client.GetObject(ctx, bucket, info.Key, opts)
+	}
+
+	key, err := d.fileKey(info)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := chacha20poly1305.New(key)
+	if err != nil {
+		return nil, err
+	}
+
+	chunks, plainSize, err := decryptedSize(info.Size)
+	if err != nil {
+		return nil, err
+	}
+	if offset >= plainSize && plainSize > 0 {
+		return io.NopCloser(strings.NewReader("")), nil
+	}
+
+	full := int64(cseChunkSize + cseTagSize)
+	first := offset / cseChunkSize
+	opts := minio.GetObjectOptions{ServerSideEncryption: sse}
+	if first > 0 {
+		if err := opts.SetRange(first*full, 0); err != nil {
+			return nil, err
+		}
+	}
+
+	obj, err := client.GetObject(ctx, bucket, info.Key, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &decryptReader{
+		src:       obj,
+		aead:      aead,
+		counter:   uint64(first),
+		last:      uint64(chunks - 1),
+		lastSize:  int(info.Size - (chunks-1)*full),
+		skip:      int(offset - first*cseChunkSize),
+		remaining: length,
+		buf:       make([]byte, full),
+	}, nil
+}
+
+// 生成密钥对操作
+func keygenAction(c *cli.Context) error {
+	path := c.String("o")
+	if path == "" {
+		path = defaultIdentityPath()
+	}
+
+	if fileExists(path) && !c.Bool("f") {
+		return fmt.Errorf("身份文件 '%s' 已存在，使用 -f 追加新的密钥", path)
+	}
+
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return fmt.Errorf("生成密钥失败: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("无法创建目录: %w", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("无法写入身份文件: %w", err)
+	}
+	defer file.Close()
+
+	public := formatRecipient(key.PublicKey())
+	if _, err := fmt.Fprintf(file, "# 公钥: %s\n%s%s\n", public, cseSecretKeyPrefix, base64.RawURLEncoding.EncodeToString(key.Bytes())); err != nil {
+		return fmt.Errorf("无法写入身份文件: %w", err)
+	}
+
+	fmt.Printf("私钥已保存到: %s\n", path)
+	fmt.Printf("公钥: %s\n", public)
+	return nil
+}
+
+// 列表结果可能不含用户元数据 (非 MinIO 服务)，此时重新获取对象信息以判断是否经过客户端加密
+func (d *Decoder) Resolve(ctx context.Context, client *minio.Client, bucket string, info minio.ObjectInfo, sse encrypt.ServerSide) (minio.ObjectInfo, error) {
+	if d.raw || info.UserMetadata != nil {
+		return info, nil
+	}
+	return client.StatObject(ctx, bucket, info.Key, minio.StatObjectOptions{ServerSideEncryption: sse})
+}
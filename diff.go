@@ -0,0 +1,488 @@
+package main
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/urfave/cli/v2"
+)
+
+// 差异类型
+const (
+	diffOnlyLeft  = "only-left"
+	diffOnlyRight = "only-right"
+	diffSize      = "size"
+	diffMtime     = "mtime"
+	diffContent   = "content"
+)
+
+// 差异类型的显示标记和名称
+var diffMarks = map[string]string{
+	diffOnlyLeft:  "-",
+	diffOnlyRight: "+",
+	diffSize:      "~",
+	diffMtime:     "~",
+	diffContent:   "!",
+}
+
+var diffLabels = map[string]string{
+	diffOnlyLeft:  "仅左侧",
+	diffOnlyRight: "仅右侧",
+	diffSize:      "大小不同",
+	diffMtime:     "时间不同",
+	diffContent:   "内容不同",
+}
+
+// 汇总时的显示顺序
+var diffOrder = []string{diffOnlyLeft, diffOnlyRight, diffSize, diffMtime, diffContent}
+
+// 未分片上传且未使用 KMS、SSE-C 加密的对象，ETag 即内容的 MD5
+var md5ETag = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+// 递归列出远程前缀下的文件，键为相对于前缀的路径，忽略目录对象和回收站中的对象
+func listRemoteFiles(ctx context.Context, client *minio.Client, bucket, prefix, trash string) (map[string]minio.ObjectInfo, error) {
+	files := make(map[string]minio.ObjectInfo)
+
+	for object := range client.ListObjects(ctx, bucket, minio.ListObjectsOptions{
+		Prefix:       prefix,
+		Recursive:    true,
+		WithMetadata: true,
+	}) {
+		if object.Err != nil {
+			return nil, fmt.Errorf("列出远程对象时出错: %w", object.Err)
+		}
+
+		// 忽略目录对象
+		if strings.HasSuffix(object.Key, "/") || inTrash(trash, prefix, object.Key) {
+			continue
+		}
+
+		files[strings.TrimPrefix(object.Key, prefix)] = object
+	}
+
+	return files, nil
+}
+
+// 遍历本地目录下的文件，跳过隐藏文件和目录，相对路径使用 / 分隔
+func walkLocalFiles(root string, fn func(relPath string, info os.FileInfo) error) error {
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		// 跳过隐藏文件和目录
+		if strings.HasPrefix(filepath.Base(path), ".") && path != root {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if info.IsDir() {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+
+		// 转换 Windows 路径分隔符
+		return fn(filepath.ToSlash(relPath), info)
+	})
+}
+
+// 比较本地文件和远程对象，sync 据此判断是否需要上传: 大小不同或本地较新
+// remoteSize 小于 0 (压缩后大小未知) 时不比较大小，相同时返回空字符串
+func compareLocalRemote(local os.FileInfo, remoteSize int64, remoteTime time.Time) (string, string) {
+	if remoteSize >= 0 && local.Size() != remoteSize {
+		return diffSize, fmt.Sprintf("大小不同 (本地 %s, 远程 %s)", formatSize(local.Size()), formatSize(remoteSize))
+	}
+	if local.ModTime().After(remoteTime) {
+		return diffMtime, "本地较新"
+	}
+	return "", ""
+}
+
+// ETag 是否为内容的 MD5: 单次上传且未使用 KMS 或 SSE-C 加密，按对象实际的响应头判断
+func etagIsMD5(info minio.ObjectInfo) bool {
+	etag := strings.ToLower(strings.Trim(info.ETag, `"`))
+	return md5ETag.MatchString(etag) &&
+		info.Metadata.Get("X-Amz-Mp-Parts-Count") == "" &&
+		info.Metadata.Get("X-Amz-Server-Side-Encryption-Customer-Algorithm") == "" &&
+		info.Metadata.Get("X-Amz-Server-Side-Encryption") != "aws:kms"
+}
+
+// diffSide 是比较的一侧: 本地目录或远程前缀
+type diffSide struct {
+	name   string // 显示的路径
+	root   string // 本地目录，远程时为空
+	prefix string // 远程前缀，以 "/" 结尾或为空
+
+	local  map[string]os.FileInfo
+	remote map[string]minio.ObjectInfo
+}
+
+// 判断参数是否表示本地路径: 以 .、..、~ 开头或带有 file: 前缀，其余按远程路径处理
+func localDiffPath(arg string) (string, bool) {
+	if rest, ok := strings.CutPrefix(arg, "file:"); ok {
+		return rest, true
+	}
+	if arg == "~" || strings.HasPrefix(arg, "~/") {
+		if homeDir, err := os.UserHomeDir(); err == nil {
+			return homeDir + arg[1:], true
+		}
+	}
+	if arg == "." || arg == ".." || strings.HasPrefix(arg, "./") || strings.HasPrefix(arg, "../") {
+		return arg, true
+	}
+	return "", false
+}
+
+// 列出一侧的全部文件
+func loadDiffSide(ctx context.Context, manager *SessionManager, client *minio.Client, session *Session, arg string) (*diffSide, error) {
+	if path, ok := localDiffPath(arg); ok {
+		root, err := filepath.Abs(path)
+		if err != nil {
+			return nil, err
+		}
+
+		info, err := os.Stat(root)
+		if err != nil {
+			return nil, fmt.Errorf("无法访问本地路径: %w", err)
+		}
+		if !info.IsDir() {
+			return nil, fmt.Errorf("本地路径 '%s' 必须是目录", root)
+		}
+
+		side := &diffSide{name: root, root: root, local: make(map[string]os.FileInfo)}
+		err = walkLocalFiles(root, func(relPath string, info os.FileInfo) error {
+			side.local[relPath] = info
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("遍历本地目录失败: %w", err)
+		}
+		return side, nil
+	}
+
+	formattedPath, err := manager.FormatPath(arg)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := strings.TrimPrefix(formattedPath, "/")
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+
+	files, err := listRemoteFiles(ctx, client, session.BucketName, prefix, session.Trash)
+	if err != nil {
+		return nil, err
+	}
+	return &diffSide{name: "/" + prefix, prefix: prefix, remote: files}, nil
+}
+
+// 是否为本地目录
+func (s *diffSide) isLocal() bool {
+	return s.local != nil
+}
+
+// 一侧的全部相对路径
+func (s *diffSide) paths() []string {
+	var paths []string
+	for relPath := range s.local {
+		paths = append(paths, relPath)
+	}
+	for relPath := range s.remote {
+		paths = append(paths, relPath)
+	}
+	return paths
+}
+
+// 是否存在指定文件
+func (s *diffSide) has(relPath string) bool {
+	if s.isLocal() {
+		_, ok := s.local[relPath]
+		return ok
+	}
+	_, ok := s.remote[relPath]
+	return ok
+}
+
+// differ 比较两侧的同名文件
+type differ struct {
+	client   *minio.Client
+	bucket   string
+	sse      string // --sse，读取 SSE-C 加密的对象时需要
+	dec      *Decoder
+	checksum bool
+}
+
+// 文件内容的 MD5，远程对象的 ETag 可信时直接使用，否则下载 (解密、解压后) 计算
+func (d *differ) hash(ctx context.Context, side *diffSide, relPath string) (string, error) {
+	hasher := md5.New()
+
+	if side.isLocal() {
+		file, err := os.Open(filepath.Join(side.root, filepath.FromSlash(relPath)))
+		if err != nil {
+			return "", err
+		}
+		defer file.Close()
+
+		if _, err := io.Copy(hasher, newTransferReader(file)); err != nil {
+			return "", err
+		}
+		return hex.EncodeToString(hasher.Sum(nil)), nil
+	}
+
+	objectName := side.prefix + relPath
+	sse, err := readEncryption(d.sse, objectName)
+	if err != nil {
+		return "", err
+	}
+
+	// 列表中没有加密和分片信息，需要读取对象的响应头
+	info, err := d.client.StatObject(ctx, d.bucket, objectName, minio.StatObjectOptions{ServerSideEncryption: sse})
+	if err != nil {
+		return "", err
+	}
+
+	if etagIsMD5(info) && (d.dec.raw || (!isClientEncrypted(info) && compressionOf(info) == "")) {
+		return strings.ToLower(strings.Trim(info.ETag, `"`)), nil
+	}
+
+	obj, err := d.dec.Open(ctx, d.client, d.bucket, info, sse, 0, -1)
+	if err != nil {
+		return "", err
+	}
+	defer obj.Close()
+
+	if _, err := io.Copy(hasher, newTransferReader(obj)); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// 比较用的大小，远程为还原后的大小，未知时返回 -1
+func (d *differ) size(ctx context.Context, side *diffSide, relPath string) int64 {
+	if side.isLocal() {
+		return side.local[relPath].Size()
+	}
+	return syncRemoteSize(ctx, d.client, d.bucket, side.remote[relPath], nil)
+}
+
+// 修改时间，远程为上传时间
+func (d *differ) modTime(side *diffSide, relPath string) time.Time {
+	if side.isLocal() {
+		return side.local[relPath].ModTime()
+	}
+	return side.remote[relPath].LastModified
+}
+
+// 比较两侧都存在的文件，相同时返回空字符串
+// 本地与远程比较时使用与 sync 相同的规则: 大小不同或本地较新即为不同，远程的修改时间是上传时间，较新不算不同
+// 两侧同为本地或远程时比较修改时间是否一致
+// 使用 --checksum 时按内容比较，不再比较修改时间
+func (d *differ) compare(ctx context.Context, left, right *diffSide, relPath string) (string, string, error) {
+	kind, reason := "", ""
+
+	switch {
+	case left.isLocal() && !right.isLocal():
+		kind, reason = compareLocalRemote(left.local[relPath], d.size(ctx, right, relPath), d.modTime(right, relPath))
+
+	case !left.isLocal() && right.isLocal():
+		kind, reason = compareLocalRemote(right.local[relPath], d.size(ctx, left, relPath), d.modTime(left, relPath))
+
+	default:
+		ls, rs := d.size(ctx, left, relPath), d.size(ctx, right, relPath)
+		lt, rt := d.modTime(left, relPath), d.modTime(right, relPath)
+		if ls >= 0 && rs >= 0 && ls != rs {
+			kind, reason = diffSize, fmt.Sprintf("大小不同 (左侧 %s, 右侧 %s)", formatSize(ls), formatSize(rs))
+		} else if !lt.Truncate(time.Second).Equal(rt.Truncate(time.Second)) {
+			kind, reason = diffMtime, fmt.Sprintf("修改时间不同 (左侧 %s, 右侧 %s)",
+				lt.Local().Format("2006-01-02 15:04:05"), rt.Local().Format("2006-01-02 15:04:05"))
+		}
+	}
+
+	if !d.checksum || kind == diffSize {
+		return kind, reason, nil
+	}
+
+	lh, err := d.hash(ctx, left, relPath)
+	if err != nil {
+		return "", "", fmt.Errorf("读取 '%s' 失败: %w", left.name+relPath, err)
+	}
+	rh, err := d.hash(ctx, right, relPath)
+	if err != nil {
+		return "", "", fmt.Errorf("读取 '%s' 失败: %w", right.name+relPath, err)
+	}
+	if lh != rh {
+		return diffContent, "内容不同", nil
+	}
+	return "", "", nil
+}
+
+// diffEntry 是一条差异
+type diffEntry struct {
+	path   string
+	kind   string
+	reason string
+}
+
+// 比较操作，存在差异时退出码为 1，出错时为 2
+func diffAction(c *cli.Context) error {
+	count, err := runDiff(c)
+	if err != nil {
+		return cli.Exit(fmt.Sprintf("错误: %v", err), 2)
+	}
+	if count > 0 {
+		return cli.Exit("", 1)
+	}
+	return nil
+}
+
+// 比较两侧并输出差异，返回差异数量
+func runDiff(c *cli.Context) (int, error) {
+	if c.NArg() < 2 {
+		return 0, fmt.Errorf("需要指定要比较的两个路径")
+	}
+
+	manager, err := initSessionManager()
+	if err != nil {
+		return 0, err
+	}
+
+	client, err := manager.GetClient()
+	if err != nil {
+		return 0, err
+	}
+
+	session, err := manager.CurrentSession()
+	if err != nil {
+		return 0, err
+	}
+
+	ctx := context.Background()
+
+	left, err := loadDiffSide(ctx, manager, client, session, c.Args().Get(0))
+	if err != nil {
+		return 0, err
+	}
+	right, err := loadDiffSide(ctx, manager, client, session, c.Args().Get(1))
+	if err != nil {
+		return 0, err
+	}
+
+	d := &differ{
+		client:   client,
+		bucket:   session.BucketName,
+		sse:      c.String("sse"),
+		dec:      newDecoder(c),
+		checksum: c.Bool("checksum"),
+	}
+
+	// 只在一侧存在的文件直接记录，两侧都存在的交给工作线程比较
+	var entries []diffEntry
+	var both []string
+	for _, relPath := range left.paths() {
+		if right.has(relPath) {
+			both = append(both, relPath)
+		} else {
+			entries = append(entries, diffEntry{path: relPath, kind: diffOnlyLeft, reason: diffLabels[diffOnlyLeft]})
+		}
+	}
+	for _, relPath := range right.paths() {
+		if !left.has(relPath) {
+			entries = append(entries, diffEntry{path: relPath, kind: diffOnlyRight, reason: diffLabels[diffOnlyRight]})
+		}
+	}
+
+	concurrency, err := newConcurrency(c)
+	if err != nil {
+		return 0, err
+	}
+	defer concurrency.Stop()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var firstErr error
+	jobCh := make(chan string)
+
+	for i := 0; i < concurrency.Workers(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for relPath := range jobCh {
+				concurrency.Acquire()
+				kind, reason, err := d.compare(ctx, left, right, relPath)
+				concurrency.Release(err)
+
+				mu.Lock()
+				if err != nil && firstErr == nil {
+					firstErr = err
+				} else if kind != "" {
+					entries = append(entries, diffEntry{path: relPath, kind: kind, reason: reason})
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, relPath := range both {
+		jobCh <- relPath
+	}
+	close(jobCh)
+	wg.Wait()
+
+	if firstErr != nil {
+		return 0, firstErr
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].path < entries[j].path
+	})
+
+	quiet := c.Bool("quiet")
+	if !quiet {
+		fmt.Printf("--- %s\n+++ %s\n", left.name, right.name)
+	}
+
+	counts := make(map[string]int)
+	for _, e := range entries {
+		counts[e.kind]++
+		fmt.Printf("%s %s", diffMarks[e.kind], e.path)
+		if !quiet {
+			fmt.Printf("  (%s)", e.reason)
+		}
+		fmt.Println()
+	}
+
+	if !quiet {
+		if len(entries) == 0 {
+			fmt.Printf("没有差异 (比较了 %d 个同名文件)\n", len(both))
+		} else {
+			var parts []string
+			for _, kind := range diffOrder {
+				if counts[kind] > 0 {
+					parts = append(parts, fmt.Sprintf("%s %d 个", diffLabels[kind], counts[kind]))
+				}
+			}
+			fmt.Printf("共 %d 处差异: %s\n", len(entries), strings.Join(parts, ", "))
+		}
+	}
+
+	return len(entries), nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// 创建指定大小和修改时间的本地文件
+func testLocalFile(t *testing.T, size int, mtime time.Time) os.FileInfo {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(path, make([]byte, size), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return info
+}
+
+func TestCompareLocalRemote(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	local := testLocalFile(t, 100, now)
+
+	tests := []struct {
+		name       string
+		remoteSize int64
+		remoteTime time.Time
+		want       string
+	}{
+		{"相同", 100, now, ""},
+		{"远程较新 (上传后的状态)", 100, now.Add(time.Hour), ""},
+		{"本地较新", 100, now.Add(-time.Hour), diffMtime},
+		{"大小不同", 99, now, diffSize},
+		{"大小不同且本地较新", 99, now.Add(-time.Hour), diffSize},
+		{"远程大小未知", -1, now, ""},
+		{"远程大小未知且本地较新", -1, now.Add(-time.Second), diffMtime},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kind, reason := compareLocalRemote(local, tt.remoteSize, tt.remoteTime)
+			if kind != tt.want {
+				t.Errorf("compareLocalRemote = %q (%s), 期望 %q", kind, reason, tt.want)
+			}
+			if (kind == "") != (reason == "") {
+				t.Errorf("类型 %q 与原因 %q 不一致", kind, reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/urfave/cli/v2"
+)
+
+// DiskUsage 汇总一个前缀下的对象数量和空间，同时用于文本和 JSON 输出
+type DiskUsage struct {
+	Prefix            string `json:"prefix"`
+	Objects           int64  `json:"objects"`
+	Size              int64  `json:"size"`
+	NoncurrentObjects int64  `json:"noncurrent_objects,omitempty"` // 非当前版本，需要 --versions
+	NoncurrentSize    int64  `json:"noncurrent_size,omitempty"`
+	DeleteMarkers     int64  `json:"delete_markers,omitempty"`
+	Uploads           int64  `json:"uploads,omitempty"` // 未完成的分片上传，需要 --uploads
+	UploadSize        int64  `json:"upload_size,omitempty"`
+}
+
+// 所有类别的总空间
+func (u *DiskUsage) totalSize() int64 {
+	return u.Size + u.NoncurrentSize + u.UploadSize
+}
+
+// usageScan 控制统计范围
+type usageScan struct {
+	prefix   string // 统计的目录，以 "/" 结尾或为空
+	depth    int    // 按子目录分组的最大层数，0 表示只统计总量
+	versions bool   // 包含非当前版本和删除标记
+	uploads  bool   // 包含未完成的分片上传
+	trash    string // 回收站前缀，其中的对象不计入
+}
+
+// 统计前缀下的空间占用，流式处理完整的递归列表，不缓存对象
+// 返回总量和按子目录分组的结果，子目录的统计包含其下所有层级
+func (s usageScan) run(ctx context.Context, client *minio.Client, bucket string) (*DiskUsage, map[string]*DiskUsage, error) {
+	total := &DiskUsage{Prefix: s.prefix}
+	groups := make(map[string]*DiskUsage)
+
+	// 将对象计入总量和所在的各级子目录
+	add := func(key string, apply func(u *DiskUsage)) {
+		apply(total)
+
+		dirs := strings.Split(strings.TrimPrefix(key, s.prefix), "/")
+		dirs = dirs[:len(dirs)-1]
+		for level := 1; level <= s.depth && level <= len(dirs); level++ {
+			name := s.prefix + strings.Join(dirs[:level], "/") + "/"
+			u, ok := groups[name]
+			if !ok {
+				u = &DiskUsage{Prefix: name}
+				groups[name] = u
+			}
+			apply(u)
+		}
+	}
+
+	for object := range client.ListObjects(ctx, bucket, minio.ListObjectsOptions{
+		Prefix:       s.prefix,
+		Recursive:    true,
+		WithVersions: s.versions,
+	}) {
+		if object.Err != nil {
+			return nil, nil, fmt.Errorf("列出对象时出错: %w", object.Err)
+		}
+		if inTrash(s.trash, s.prefix, object.Key) {
+			continue
+		}
+
+		size := object.Size
+		switch {
+		case object.IsDeleteMarker:
+			add(object.Key, func(u *DiskUsage) { u.DeleteMarkers++ })
+		case s.versions && !object.IsLatest:
+			add(object.Key, func(u *DiskUsage) { u.NoncurrentObjects++; u.NoncurrentSize += size })
+		default:
+			add(object.Key, func(u *DiskUsage) { u.Objects++; u.Size += size })
+		}
+	}
+
+	if s.uploads {
+		for upload := range client.ListIncompleteUploads(ctx, bucket, s.prefix, true) {
+			if upload.Err != nil {
+				return nil, nil, fmt.Errorf("列出未完成的上传时出错: %w", upload.Err)
+			}
+			_, size, err := uploadedParts(ctx, client, bucket, upload.Key, upload.UploadID)
+			if err != nil {
+				return nil, nil, fmt.Errorf("列出分片时出错 '/%s': %w", upload.Key, err)
+			}
+			add(upload.Key, func(u *DiskUsage) { u.Uploads++; u.UploadSize += size })
+		}
+	}
+
+	return total, groups, nil
+}
+
+// 以文本形式输出一行统计
+func printDiskUsage(u *DiskUsage, scan usageScan) {
+	line := fmt.Sprintf("%10s  %8d", formatSize(u.Size), u.Objects)
+	if scan.versions {
+		line += fmt.Sprintf("  %10s  %8d  %6d", formatSize(u.NoncurrentSize), u.NoncurrentObjects, u.DeleteMarkers)
+	}
+	if scan.uploads {
+		line += fmt.Sprintf("  %10s  %6d", formatSize(u.UploadSize), u.Uploads)
+	}
+	fmt.Printf("%s  /%s\n", line, u.Prefix)
+}
+
+// 空间占用统计操作
+func duAction(c *cli.Context) error {
+	manager, err := initSessionManager()
+	if err != nil {
+		return err
+	}
+
+	client, err := manager.GetClient()
+	if err != nil {
+		return err
+	}
+
+	session, err := manager.CurrentSession()
+	if err != nil {
+		return err
+	}
+
+	sortBy := c.String("sort")
+	switch sortBy {
+	case "name", "size", "count":
+	default:
+		return fmt.Errorf("无效的排序方式 '%s'，应为 name、size 或 count", sortBy)
+	}
+
+	depth := c.Int("depth")
+	if depth < 0 {
+		return fmt.Errorf("--depth 不能为负数")
+	}
+	if c.Bool("summarize") {
+		depth = 0
+	}
+
+	paths := c.Args().Slice()
+	if len(paths) == 0 {
+		paths = []string{""}
+	}
+
+	ctx := context.Background()
+
+	for _, path := range paths {
+		formattedPath, err := manager.FormatPath(path)
+		if err != nil {
+			return err
+		}
+
+		prefix := strings.TrimPrefix(formattedPath, "/")
+		if prefix != "" && !strings.HasSuffix(prefix, "/") {
+			prefix += "/"
+		}
+
+		scan := usageScan{prefix: prefix, depth: depth, versions: c.Bool("versions"), uploads: c.Bool("uploads"), trash: session.Trash}
+		total, groups, err := scan.run(ctx, client, session.BucketName)
+		if err != nil {
+			return err
+		}
+
+		entries := make([]*DiskUsage, 0, len(groups)+1)
+		for _, u := range groups {
+			entries = append(entries, u)
+		}
+		sort.Slice(entries, func(i, j int) bool {
+			switch sortBy {
+			case "size":
+				if entries[i].totalSize() != entries[j].totalSize() {
+					return entries[i].totalSize() > entries[j].totalSize()
+				}
+			case "count":
+				if entries[i].Objects != entries[j].Objects {
+					return entries[i].Objects > entries[j].Objects
+				}
+			}
+			return entries[i].Prefix < entries[j].Prefix
+		})
+		// 与 du 相同，总量最后输出
+		entries = append(entries, total)
+
+		if c.Bool("json") {
+			for _, u := range entries {
+				data, err := json.Marshal(u)
+				if err != nil {
+					return fmt.Errorf("无法序列化统计结果: %w", err)
+				}
+				fmt.Println(string(data))
+			}
+			continue
+		}
+
+		header := fmt.Sprintf("%10s  %8s", "大小", "对象数")
+		if scan.versions {
+			header += fmt.Sprintf("  %10s  %8s  %6s", "历史版本", "版本数", "删除标记")
+		}
+		if scan.uploads {
+			header += fmt.Sprintf("  %10s  %6s", "未完成上传", "上传数")
+		}
+		if !c.Bool("quiet") {
+			fmt.Printf("%s  路径\n", header)
+		}
+		for _, u := range entries {
+			printDiskUsage(u, scan)
+		}
+	}
+
+	return nil
+}
+
+// 未完成的分片上传已上传的分片数和总大小，列表结果不包含大小，需要逐个列出分片
+func uploadedParts(ctx context.Context, client *minio.Client, bucket, objectName, uploadID string) (int, int64, error) {
+	core := minio.Core{Client: client}
+
+	var parts int
+	var size int64
+	marker := 0
+	for {
+		result, err := core.ListObjectParts(ctx, bucket, objectName, uploadID, marker, 1000)
+		if err != nil {
+			return 0, 0, err
+		}
+		for _, part := range result.ObjectParts {
+			parts++
+			size += part.Size
+		}
+		if !result.IsTruncated {
+			return parts, size, nil
+		}
+		marker = result.NextPartNumberMarker
+	}
+}
@@ -0,0 +1,432 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/notification"
+	"github.com/urfave/cli/v2"
+)
+
+// 通知目标的类型，由 ARN 中的服务决定
+const (
+	eventTargetQueue  = "queue"  // sqs，MinIO 的 webhook、AMQP、Kafka 等目标均为此类型
+	eventTargetTopic  = "topic"  // sns
+	eventTargetLambda = "lambda" // lambda
+)
+
+// EventRule 是一条存储桶通知规则，用于显示和 JSON 导入导出
+type EventRule struct {
+	ID     string   `json:"id,omitempty"`
+	ARN    string   `json:"arn"`
+	Type   string   `json:"type,omitempty"` // queue、topic 或 lambda，导入时可省略
+	Events []string `json:"events"`
+	Prefix string   `json:"prefix,omitempty"`
+	Suffix string   `json:"suffix,omitempty"`
+}
+
+// EventConfig 是存储桶的完整通知配置
+type EventConfig struct {
+	Bucket string      `json:"bucket,omitempty"`
+	Rules  []EventRule `json:"rules"`
+}
+
+// 根据 ARN 确定目标类型
+func eventTargetType(arn notification.Arn) (string, error) {
+	switch arn.Service {
+	case "sqs":
+		return eventTargetQueue, nil
+	case "sns":
+		return eventTargetTopic, nil
+	case "lambda":
+		return eventTargetLambda, nil
+	default:
+		return "", fmt.Errorf("不支持的通知目标 '%s'，ARN 的服务应为 sqs、sns 或 lambda", arn)
+	}
+}
+
+// 事件名的显示形式: 对应简称的事件显示简称，其余保留完整名称
+func eventDisplayName(event notification.EventType) string {
+	for alias, e := range eventAliases {
+		if e == event {
+			return alias
+		}
+	}
+	return string(event)
+}
+
+// 从过滤规则中取出前缀和后缀
+func filterPrefixSuffix(filter *notification.Filter) (string, string) {
+	var prefix, suffix string
+	if filter == nil {
+		return prefix, suffix
+	}
+	for _, rule := range filter.S3Key.FilterRules {
+		switch strings.ToLower(rule.Name) {
+		case "prefix":
+			prefix = rule.Value
+		case "suffix":
+			suffix = rule.Value
+		}
+	}
+	return prefix, suffix
+}
+
+// 将一条通知配置转换为规则
+func newEventRule(config notification.Config, arn, kind string) EventRule {
+	rule := EventRule{ID: config.ID, ARN: arn, Type: kind}
+	for _, event := range config.Events {
+		rule.Events = append(rule.Events, string(event))
+	}
+	rule.Prefix, rule.Suffix = filterPrefixSuffix(config.Filter)
+	return rule
+}
+
+// 将存储桶的通知配置转换为规则列表，没有规则时返回空列表，以便导出的文件可以再导入
+func eventRulesFromConfig(config notification.Configuration) []EventRule {
+	rules := []EventRule{}
+	for _, c := range config.QueueConfigs {
+		rules = append(rules, newEventRule(c.Config, c.Queue, eventTargetQueue))
+	}
+	for _, c := range config.TopicConfigs {
+		rules = append(rules, newEventRule(c.Config, c.Topic, eventTargetTopic))
+	}
+	for _, c := range config.LambdaConfigs {
+		rules = append(rules, newEventRule(c.Config, c.Lambda, eventTargetLambda))
+	}
+	return rules
+}
+
+// 将规则添加到通知配置，与已有规则的目标、过滤条件相同且事件重叠时报错，其他重叠由服务端检查
+func addEventRule(config *notification.Configuration, rule EventRule) error {
+	arn, err := notification.NewArnFromString(rule.ARN)
+	if err != nil {
+		return fmt.Errorf("无效的 ARN '%s': %w", rule.ARN, err)
+	}
+
+	kind, err := eventTargetType(arn)
+	if err != nil {
+		return err
+	}
+	if rule.Type != "" && rule.Type != kind {
+		return fmt.Errorf("规则类型 '%s' 与 ARN '%s' 不符", rule.Type, rule.ARN)
+	}
+
+	events, err := parseEventTypes(strings.Join(rule.Events, ","))
+	if err != nil {
+		return err
+	}
+
+	// minio-go 按指针比较过滤条件，重复的规则需要在这里检查
+	for _, existing := range eventRulesFromConfig(*config) {
+		if existing.ARN != rule.ARN || existing.Prefix != rule.Prefix || existing.Suffix != rule.Suffix {
+			continue
+		}
+		for _, e := range existing.Events {
+			for _, event := range events {
+				if notification.EventType(e) == event {
+					return fmt.Errorf("目标 '%s' 已有相同过滤条件的规则监听事件 %s", rule.ARN, eventDisplayName(event))
+				}
+			}
+		}
+	}
+
+	c := notification.NewConfig(arn)
+	c.ID = rule.ID
+	c.AddEvents(events...)
+	if rule.Prefix != "" {
+		c.AddFilterPrefix(rule.Prefix)
+	}
+	if rule.Suffix != "" {
+		c.AddFilterSuffix(rule.Suffix)
+	}
+
+	switch kind {
+	case eventTargetQueue:
+		config.AddQueue(c)
+	case eventTargetTopic:
+		config.AddTopic(c)
+	case eventTargetLambda:
+		config.AddLambda(c)
+	}
+	return nil
+}
+
+// 输出通知规则
+func printEventRules(rules []EventRule) {
+	if len(rules) == 0 {
+		fmt.Println("没有通知规则")
+		return
+	}
+
+	for i, rule := range rules {
+		events := make([]string, len(rule.Events))
+		for j, event := range rule.Events {
+			events[j] = eventDisplayName(notification.EventType(event))
+		}
+
+		fmt.Printf("[%d] %s (%s)\n", i+1, rule.ARN, rule.Type)
+		if rule.ID != "" {
+			fmt.Printf("    ID:   %s\n", rule.ID)
+		}
+		fmt.Printf("    事件: %s\n", strings.Join(events, ", "))
+		if rule.Prefix != "" {
+			fmt.Printf("    前缀: %s\n", rule.Prefix)
+		}
+		if rule.Suffix != "" {
+			fmt.Printf("    后缀: %s\n", rule.Suffix)
+		}
+	}
+}
+
+// 获取当前会话存储桶的通知配置
+func currentEventConfig(ctx context.Context) (*minio.Client, *Session, notification.Configuration, error) {
+	manager, err := initSessionManager()
+	if err != nil {
+		return nil, nil, notification.Configuration{}, err
+	}
+
+	client, err := manager.GetClient()
+	if err != nil {
+		return nil, nil, notification.Configuration{}, err
+	}
+
+	session, err := manager.CurrentSession()
+	if err != nil {
+		return nil, nil, notification.Configuration{}, err
+	}
+
+	config, err := client.GetBucketNotification(ctx, session.BucketName)
+	if err != nil {
+		return nil, nil, notification.Configuration{}, fmt.Errorf("无法获取通知配置: %w", err)
+	}
+
+	return client, session, config, nil
+}
+
+// 写入通知配置，dry-run 模式下只输出修改后的规则
+func saveEventConfig(ctx context.Context, c *cli.Context, client *minio.Client, session *Session, config notification.Configuration) error {
+	if isDryRun(c) {
+		fmt.Printf("修改后的通知规则 (bucket: %s):\n", session.BucketName)
+		printEventRules(eventRulesFromConfig(config))
+		fmt.Println("dry-run 模式，未执行任何写操作")
+		return nil
+	}
+
+	rules := eventRulesFromConfig(config)
+	if len(rules) == 0 {
+		if err := client.RemoveAllBucketNotification(ctx, session.BucketName); err != nil {
+			return fmt.Errorf("无法清除通知配置: %w", err)
+		}
+		return nil
+	}
+
+	if err := client.SetBucketNotification(ctx, session.BucketName, config); err != nil {
+		return fmt.Errorf("无法写入通知配置: %w", err)
+	}
+	return nil
+}
+
+// 添加通知规则操作
+func eventAddAction(c *cli.Context) error {
+	if c.NArg() < 1 {
+		return fmt.Errorf("需要指定通知目标的 ARN，例如 arn:minio:sqs::primary:webhook")
+	}
+
+	ctx := context.Background()
+	client, session, config, err := currentEventConfig(ctx)
+	if err != nil {
+		return err
+	}
+
+	rule := EventRule{
+		ID:     c.String("id"),
+		ARN:    c.Args().First(),
+		Events: strings.Split(c.String("events"), ","),
+		Prefix: strings.TrimPrefix(c.String("prefix"), "/"),
+		Suffix: c.String("suffix"),
+	}
+	if err := addEventRule(&config, rule); err != nil {
+		return err
+	}
+
+	if err := saveEventConfig(ctx, c, client, session, config); err != nil {
+		return err
+	}
+	if !isDryRun(c) {
+		fmt.Printf("已添加通知规则: %s\n", rule.ARN)
+	}
+	return nil
+}
+
+// 列出通知规则操作
+func eventLsAction(c *cli.Context) error {
+	_, session, config, err := currentEventConfig(context.Background())
+	if err != nil {
+		return err
+	}
+
+	rules := eventRulesFromConfig(config)
+	if c.Bool("json") {
+		data, err := json.MarshalIndent(EventConfig{Bucket: session.BucketName, Rules: rules}, "", "  ")
+		if err != nil {
+			return fmt.Errorf("无法序列化通知配置: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	printEventRules(rules)
+	return nil
+}
+
+// 删除通知规则操作
+// 指定 ARN 或 ID 时删除匹配的规则，同时指定 --events、--prefix 或 --suffix 时只删除条件完全相同的规则
+func eventRmAction(c *cli.Context) error {
+	all := c.Bool("all")
+	if c.NArg() < 1 && !all {
+		return fmt.Errorf("需要指定要删除规则的 ARN 或 ID，或使用 --all 删除全部规则")
+	}
+
+	ctx := context.Background()
+	client, session, config, err := currentEventConfig(ctx)
+	if err != nil {
+		return err
+	}
+
+	rules := eventRulesFromConfig(config)
+	target := c.Args().First()
+	exact := c.IsSet("events") || c.IsSet("prefix") || c.IsSet("suffix")
+
+	var events []notification.EventType
+	if c.IsSet("events") {
+		if events, err = parseEventTypes(c.String("events")); err != nil {
+			return err
+		}
+	}
+	prefix := strings.TrimPrefix(c.String("prefix"), "/")
+
+	var kept []EventRule
+	removed := 0
+	for _, rule := range rules {
+		match := all || rule.ARN == target || (rule.ID != "" && rule.ID == target)
+		if match && exact {
+			ruleEvents := make([]notification.EventType, len(rule.Events))
+			for i, event := range rule.Events {
+				ruleEvents[i] = notification.EventType(event)
+			}
+			match = (events == nil || notification.EqualEventTypeList(ruleEvents, events)) &&
+				(!c.IsSet("prefix") || rule.Prefix == prefix) &&
+				(!c.IsSet("suffix") || rule.Suffix == c.String("suffix"))
+		}
+
+		if match {
+			removed++
+			continue
+		}
+		kept = append(kept, rule)
+	}
+
+	if removed == 0 {
+		return fmt.Errorf("没有匹配的通知规则")
+	}
+
+	var updated notification.Configuration
+	for _, rule := range kept {
+		if err := addEventRule(&updated, rule); err != nil {
+			return err
+		}
+	}
+
+	if err := saveEventConfig(ctx, c, client, session, updated); err != nil {
+		return err
+	}
+	if !isDryRun(c) {
+		fmt.Printf("已删除 %d 条通知规则\n", removed)
+	}
+	return nil
+}
+
+// 导出通知配置操作
+func eventExportAction(c *cli.Context) error {
+	_, session, config, err := currentEventConfig(context.Background())
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(EventConfig{Bucket: session.BucketName, Rules: eventRulesFromConfig(config)}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("无法序列化通知配置: %w", err)
+	}
+
+	if c.NArg() < 1 || c.Args().First() == "-" {
+		fmt.Println(string(data))
+		return nil
+	}
+
+	path := c.Args().First()
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("无法写入文件: %w", err)
+	}
+	fmt.Printf("通知配置已导出到: %s\n", path)
+	return nil
+}
+
+// 导入通知配置操作，默认替换存储桶的全部规则，--merge 时添加到已有规则中
+func eventImportAction(c *cli.Context) error {
+	if c.NArg() < 1 {
+		return fmt.Errorf("需要指定配置文件 (- 表示标准输入)")
+	}
+
+	var data []byte
+	var err error
+	if path := c.Args().First(); path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return fmt.Errorf("无法读取配置文件: %w", err)
+	}
+
+	var imported EventConfig
+	if err := json.Unmarshal(data, &imported); err != nil {
+		return fmt.Errorf("无法解析配置文件: %w", err)
+	}
+	if imported.Rules == nil {
+		return errors.New("配置文件中缺少 rules")
+	}
+
+	ctx := context.Background()
+	client, session, config, err := currentEventConfig(ctx)
+	if err != nil {
+		return err
+	}
+
+	if imported.Bucket != "" && imported.Bucket != session.BucketName && !c.Bool("quiet") {
+		fmt.Fprintf(os.Stderr, "注意: 配置导出自 %s，将导入到 %s\n", imported.Bucket, session.BucketName)
+	}
+
+	if !c.Bool("merge") {
+		config = notification.Configuration{}
+	}
+	for i, rule := range imported.Rules {
+		if err := addEventRule(&config, rule); err != nil {
+			return fmt.Errorf("第 %d 条规则: %w", i+1, err)
+		}
+	}
+
+	if err := saveEventConfig(ctx, c, client, session, config); err != nil {
+		return err
+	}
+	if !isDryRun(c) {
+		fmt.Printf("已导入 %d 条通知规则\n", len(imported.Rules))
+	}
+	return nil
+}
@@ -0,0 +1,509 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/urfave/cli/v2"
+	"minx/wildcard"
+)
+
+// 大小条件: op 为 '+' 表示大于，'-' 表示小于，0 表示等于
+type sizeCond struct {
+	op   byte
+	size int64
+}
+
+// findQuery 描述 find 的匹配条件，所有条件同时满足才算匹配
+type findQuery struct {
+	name   string
+	regex  *regexp.Regexp
+	sizes  []sizeCond
+	newer  time.Time
+	older  time.Time
+	dirs   bool // 查找目录而不是文件
+	meta   map[string]string
+	tags   map[string]string
+	hasCmp bool // 是否有目录无法满足的条件 (大小、时间、元数据、标签)
+}
+
+// 根据命令行参数构建匹配条件
+func parseFindQuery(c *cli.Context) (*findQuery, error) {
+	q := &findQuery{name: c.String("name")}
+
+	if c.Bool("regex") {
+		if q.name == "" {
+			return nil, fmt.Errorf("--regex 需要与 --name 一起使用")
+		}
+		re, err := regexp.Compile(q.name)
+		if err != nil {
+			return nil, fmt.Errorf("无效的正则表达式 '%s': %w", q.name, err)
+		}
+		q.regex = re
+	}
+
+	for _, s := range c.StringSlice("size") {
+		cond := sizeCond{}
+		if strings.HasPrefix(s, "+") || strings.HasPrefix(s, "-") {
+			cond.op = s[0]
+			s = s[1:]
+		}
+		size, err := parseSize(s)
+		if err != nil {
+			return nil, err
+		}
+		cond.size = size
+		q.sizes = append(q.sizes, cond)
+	}
+
+	var err error
+	if s := c.String("newer"); s != "" {
+		if q.newer, err = parseAge(s); err != nil {
+			return nil, err
+		}
+	}
+	if s := c.String("older"); s != "" {
+		if q.older, err = parseAge(s); err != nil {
+			return nil, err
+		}
+	}
+
+	switch c.String("type") {
+	case "", "f":
+	case "d":
+		q.dirs = true
+	default:
+		return nil, fmt.Errorf("无效的类型 '%s'，应为 f 或 d", c.String("type"))
+	}
+
+	if q.meta, err = parseKeyValues(c.StringSlice("meta"), "元数据"); err != nil {
+		return nil, err
+	}
+	if q.tags, err = parseKeyValues(c.StringSlice("tag"), "标签"); err != nil {
+		return nil, err
+	}
+
+	q.hasCmp = len(q.sizes) > 0 || !q.newer.IsZero() || !q.older.IsZero() || len(q.meta) > 0 || len(q.tags) > 0
+	return q, nil
+}
+
+// 解析时间条件: 7d、2w、12h、30m 等相对时间，或 2006-01-02、RFC3339 格式的绝对时间
+func parseAge(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+
+	// time.ParseDuration 不支持天和周
+	unit := time.Duration(0)
+	switch {
+	case strings.HasSuffix(s, "d"):
+		unit = 24 * time.Hour
+	case strings.HasSuffix(s, "w"):
+		unit = 7 * 24 * time.Hour
+	}
+	if unit != 0 {
+		n, err := strconv.ParseFloat(s[:len(s)-1], 64)
+		if err != nil || n < 0 {
+			return time.Time{}, fmt.Errorf("无效的时间 '%s'", s)
+		}
+		return time.Now().Add(-time.Duration(n * float64(unit))), nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return time.Time{}, fmt.Errorf("无效的时间 '%s'，应为 7d、12h 或 2006-01-02 等格式", s)
+	}
+	return time.Now().Add(-d), nil
+}
+
+// 匹配名称: --regex 时对完整路径使用正则，否则模式含 "/" 时匹配完整路径，不含时匹配文件名
+func (q *findQuery) matchName(key string) bool {
+	if q.name == "" {
+		return true
+	}
+
+	full := "/" + strings.TrimSuffix(key, "/")
+	if q.regex != nil {
+		return q.regex.MatchString(full)
+	}
+	if strings.Contains(q.name, "/") {
+		return wildcard.Match(q.name, full)
+	}
+	return wildcard.Match(q.name, path.Base(full))
+}
+
+// 列表需要带上元数据的情况
+func (q *findQuery) needsMetadata() bool {
+	return len(q.meta) > 0 || len(q.tags) > 0
+}
+
+// 判断文件是否满足所有条件，元数据和标签在列表结果中缺失时单独获取
+func (q *findQuery) matchFile(ctx context.Context, client *minio.Client, bucket string, object minio.ObjectInfo) (bool, error) {
+	if !q.matchName(object.Key) {
+		return false, nil
+	}
+
+	for _, cond := range q.sizes {
+		switch {
+		case cond.op == '+' && object.Size <= cond.size,
+			cond.op == '-' && object.Size >= cond.size,
+			cond.op == 0 && object.Size != cond.size:
+			return false, nil
+		}
+	}
+
+	if !q.newer.IsZero() && !object.LastModified.After(q.newer) {
+		return false, nil
+	}
+	if !q.older.IsZero() && !object.LastModified.Before(q.older) {
+		return false, nil
+	}
+
+	if len(q.meta) > 0 {
+		metadata := object.UserMetadata
+		if metadata == nil {
+			info, err := client.StatObject(ctx, bucket, object.Key, minio.StatObjectOptions{})
+			if err != nil {
+				return false, fmt.Errorf("获取元数据失败: %w", err)
+			}
+			metadata = info.UserMetadata
+		}
+		if !matchKeyValues(q.meta, metadata, true) {
+			return false, nil
+		}
+	}
+
+	if len(q.tags) > 0 {
+		tagMap := object.UserTags
+		if tagMap == nil {
+			t, err := client.GetObjectTagging(ctx, bucket, object.Key, minio.GetObjectTaggingOptions{})
+			if err != nil {
+				return false, fmt.Errorf("获取标签失败: %w", err)
+			}
+			tagMap = t.ToMap()
+		}
+		if !matchKeyValues(q.tags, tagMap, false) {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// 判断 actual 是否包含 want 中的所有键，值支持通配符
+// foldKeys 为 true 时键名不区分大小写，并忽略 X-Amz-Meta- 前缀
+func matchKeyValues(want, actual map[string]string, foldKeys bool) bool {
+	for key, pattern := range want {
+		found := false
+		for k, v := range actual {
+			if foldKeys {
+				k = strings.TrimPrefix(strings.ToLower(k), "x-amz-meta-")
+				if k != strings.ToLower(key) {
+					continue
+				}
+			} else if k != key {
+				continue
+			}
+			if wildcard.Match(pattern, v) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// 查找文件操作: 流式处理列表结果，匹配后依次执行 --exec、--get、--delete，未指定任何操作时输出路径
+func findAction(c *cli.Context) error {
+	q, err := parseFindQuery(c)
+	if err != nil {
+		return err
+	}
+
+	execCmd, err := splitCommand(c.String("exec"))
+	if err != nil {
+		return fmt.Errorf("无效的 --exec: %w", err)
+	}
+	getDir := c.String("get")
+	remove := c.Bool("delete")
+	if q.dirs && (getDir != "" || remove) {
+		return fmt.Errorf("--get 和 --delete 只能用于文件")
+	}
+	if q.dirs && q.hasCmp {
+		return fmt.Errorf("目录没有大小、时间、元数据和标签，--type d 只能与 --name 一起使用")
+	}
+	print0 := c.Bool("print0")
+	printPaths := c.Bool("print") || (!print0 && len(execCmd) == 0 && getDir == "" && !remove)
+
+	manager, err := initSessionManager()
+	if err != nil {
+		return err
+	}
+
+	client, err := manager.GetClient()
+	if err != nil {
+		return err
+	}
+
+	session, err := manager.CurrentSession()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	plan := newPlan(c, "find", session)
+	policy := retryPolicyFromContext(c)
+	failures := newFailureReport("find", session)
+	rm := newRemover(client, session, nil)
+
+	var options *TransferOptions
+	if c.String("sse") != "" {
+		options = &TransferOptions{SSE: c.String("sse")}
+	}
+
+	roots := c.Args().Slice()
+	if len(roots) == 0 {
+		roots = []string{""}
+	}
+
+	matched := 0
+
+	// 对匹配的条目执行操作，某个操作失败时跳过后续操作，避免下载失败的文件被删除
+	handle := func(key string, size int64, root string) {
+		matched++
+		display := "/" + key
+
+		if printPaths {
+			fmt.Println(display)
+		}
+		if print0 {
+			fmt.Print(display + "\x00")
+		}
+
+		if len(execCmd) > 0 {
+			args := expandCommand(execCmd, map[string]string{"{}": display}, display)
+
+			if plan != nil {
+				fmt.Printf("将执行: %s\n", strings.Join(args, " "))
+			} else {
+				cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+				cmd.Stdin = os.Stdin
+				cmd.Stdout = os.Stdout
+				cmd.Stderr = os.Stderr
+				if err := cmd.Run(); err != nil {
+					fmt.Fprintf(os.Stderr, "执行失败 '%s': %v\n", display, err)
+					failures.AddError(fmt.Errorf("执行 '%s' 失败: %w", strings.Join(args, " "), err))
+					return
+				}
+			}
+		}
+
+		var actions []PlanAction
+		if getDir != "" {
+			target := filepath.Join(getDir, filepath.FromSlash(strings.TrimPrefix(key, root)))
+			actions = append(actions, PlanAction{Op: opDownload, Source: key, Target: planLocalPath(target), Size: size, Reason: "find 匹配", Options: options})
+		}
+		if remove {
+			actions = append(actions, rm.action(key, size, "find 匹配"))
+		}
+
+		for _, action := range actions {
+			if plan != nil {
+				plan.Add(action)
+				continue
+			}
+
+			attempts, err := runPlanAction(ctx, policy, client, session.BucketName, action)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s失败 '%s': %v\n", opLabels[action.Op], display, err)
+				failures.Add(action, attempts, err)
+				return
+			}
+			rm.record(action)
+			if !c.Bool("quiet") && !printPaths && !print0 {
+				fmt.Printf("已%s: %s\n", opLabels[action.Op], display)
+			}
+		}
+	}
+
+	for _, root := range roots {
+		formattedPath, err := manager.FormatPath(root)
+		if err != nil {
+			return err
+		}
+
+		prefix := strings.TrimPrefix(formattedPath, "/")
+		if prefix != "" && !strings.HasSuffix(prefix, "/") {
+			prefix += "/"
+		}
+
+		seenDirs := make(map[string]bool)
+		for object := range client.ListObjects(ctx, session.BucketName, minio.ListObjectsOptions{
+			Prefix:       prefix,
+			Recursive:    true,
+			WithMetadata: q.needsMetadata(),
+		}) {
+			if object.Err != nil {
+				return fmt.Errorf("列出对象时出错: %w", object.Err)
+			}
+			if inTrash(session.Trash, prefix, object.Key) {
+				continue
+			}
+
+			if q.dirs {
+				// 目录由对象路径推导，列表按字典序返回，每个目录只输出一次
+				rel := strings.TrimPrefix(object.Key, prefix)
+				for i := strings.Index(rel, "/"); i >= 0; i = indexFrom(rel, "/", i+1) {
+					dir := prefix + rel[:i+1]
+					if seenDirs[dir] {
+						continue
+					}
+					seenDirs[dir] = true
+					if q.matchName(dir) {
+						handle(dir, 0, prefix)
+					}
+				}
+				continue
+			}
+
+			if strings.HasSuffix(object.Key, "/") {
+				continue
+			}
+
+			ok, err := q.matchFile(ctx, client, session.BucketName, object)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "检查 '/%s' 失败: %v\n", object.Key, err)
+				failures.AddError(fmt.Errorf("检查 '/%s' 失败: %w", object.Key, err))
+				continue
+			}
+			if ok {
+				handle(object.Key, object.Size, prefix)
+			}
+		}
+	}
+
+	if plan != nil {
+		return plan.Finish(c)
+	}
+	rm.finish("find", "find --delete")
+
+	if !printPaths && !print0 && !c.Bool("quiet") {
+		kind := "文件"
+		if q.dirs {
+			kind = "目录"
+		}
+		fmt.Printf("匹配 %d 个%s\n", matched, kind)
+	}
+	return failures.Finish(c)
+}
+
+// 从 start 开始查找 sep，返回在 s 中的位置，找不到时返回 -1
+func indexFrom(s, sep string, start int) int {
+	if start > len(s) {
+		return -1
+	}
+	if i := strings.Index(s[start:], sep); i >= 0 {
+		return start + i
+	}
+	return -1
+}
+
+// 按 shell 的规则拆分命令: 空白分隔参数，单引号内原样保留，双引号内只有 \" \\ \$ \` 需要转义，引号外的反斜杠转义下一个字符
+func splitCommand(s string) ([]string, error) {
+	var args []string
+	var cur strings.Builder
+	inArg := false
+	quote := rune(0)
+	escaped := false
+
+	for _, r := range s {
+		switch {
+		case escaped:
+			if quote == '"' && !strings.ContainsRune("\"\\$`", r) {
+				cur.WriteRune('\\')
+			}
+			cur.WriteRune(r)
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				cur.WriteRune(r)
+			}
+		case r == '\\':
+			escaped = true
+			inArg = true
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else {
+				cur.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, cur.String())
+				cur.Reset()
+				inArg = false
+			}
+		default:
+			cur.WriteRune(r)
+			inArg = true
+		}
+	}
+
+	if escaped {
+		return nil, fmt.Errorf("命令以反斜杠结尾")
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("引号 %c 没有闭合", quote)
+	}
+	if inArg {
+		args = append(args, cur.String())
+	}
+	return args, nil
+}
+
+// 展开命令参数中的占位符，命令中没有任何占位符时将 fallback 追加为最后一个参数
+func expandCommand(cmd []string, values map[string]string, fallback string) []string {
+	pairs := make([]string, 0, len(values)*2)
+	for placeholder, value := range values {
+		pairs = append(pairs, placeholder, value)
+	}
+	replacer := strings.NewReplacer(pairs...)
+
+	args := make([]string, len(cmd))
+	replaced := false
+	for i, arg := range cmd {
+		for placeholder := range values {
+			if strings.Contains(arg, placeholder) {
+				replaced = true
+			}
+		}
+		args[i] = replacer.Replace(arg)
+	}
+
+	if !replaced {
+		args = append(args, fallback)
+	}
+	return args
+}
@@ -0,0 +1,485 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/lifecycle"
+	"github.com/urfave/cli/v2"
+)
+
+// 生命周期规则的日期格式，服务端要求为 UTC 零点
+const ilmDateLayout = "2006-01-02"
+
+// 生成规则 ID
+func newILMRuleID() (string, error) {
+	buf := make([]byte, 10)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("无法生成规则 ID: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// 解析 YYYY-MM-DD 格式的日期
+func parseILMDate(value, what string) (lifecycle.ExpirationDate, error) {
+	t, err := time.Parse(ilmDateLayout, value)
+	if err != nil {
+		return lifecycle.ExpirationDate{}, fmt.Errorf("无效的%s '%s'，应为 YYYY-MM-DD", what, value)
+	}
+	return lifecycle.ExpirationDate{Time: t}, nil
+}
+
+// 规则的前缀，兼容旧版本放在规则顶层的前缀
+func ilmRulePrefix(rule lifecycle.Rule) string {
+	switch {
+	case rule.RuleFilter.And.Prefix != "":
+		return rule.RuleFilter.And.Prefix
+	case rule.RuleFilter.Prefix != "":
+		return rule.RuleFilter.Prefix
+	}
+	return rule.Prefix
+}
+
+// 规则的标签条件，按 key 排序
+func ilmRuleTags(rule lifecycle.Rule) []string {
+	var tags []string
+	for _, tag := range rule.RuleFilter.And.Tags {
+		tags = append(tags, tag.Key+"="+tag.Value)
+	}
+	if !rule.RuleFilter.Tag.IsEmpty() {
+		tags = append(tags, rule.RuleFilter.Tag.Key+"="+rule.RuleFilter.Tag.Value)
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// 天数或日期的显示形式
+func ilmWhen(days lifecycle.ExpirationDays, date lifecycle.ExpirationDate) string {
+	if !date.IsZero() {
+		return date.Format(ilmDateLayout)
+	}
+	if days > 0 {
+		return fmt.Sprintf("%d 天", days)
+	}
+	return ""
+}
+
+// 表格中显示的一行，空单元格显示为 -
+func ilmRuleRow(rule lifecycle.Rule) []string {
+	row := []string{rule.ID, rule.Status, "/" + ilmRulePrefix(rule), strings.Join(ilmRuleTags(rule), ",")}
+
+	var expire []string
+	if when := ilmWhen(rule.Expiration.Days, rule.Expiration.Date); when != "" {
+		expire = append(expire, when)
+	}
+	if rule.Expiration.DeleteMarker.IsEnabled() {
+		expire = append(expire, "删除标记")
+	}
+	if rule.Expiration.DeleteAll.IsEnabled() {
+		expire = append(expire, "全部版本")
+	}
+	row = append(row, strings.Join(expire, " "))
+
+	var noncurrent string
+	if rule.NoncurrentVersionExpiration.NoncurrentDays > 0 {
+		noncurrent = fmt.Sprintf("%d 天", rule.NoncurrentVersionExpiration.NoncurrentDays)
+	}
+	if n := rule.NoncurrentVersionExpiration.NewerNoncurrentVersions; n > 0 {
+		noncurrent = strings.TrimSpace(fmt.Sprintf("%s 保留 %d 个", noncurrent, n))
+	}
+	row = append(row, noncurrent)
+
+	var transition []string
+	if when := ilmWhen(rule.Transition.Days, rule.Transition.Date); when != "" || rule.Transition.StorageClass != "" {
+		transition = append(transition, strings.TrimSpace(when+" -> "+rule.Transition.StorageClass))
+	}
+	if t := rule.NoncurrentVersionTransition; t.StorageClass != "" {
+		transition = append(transition, fmt.Sprintf("旧版本 %d 天 -> %s", t.NoncurrentDays, t.StorageClass))
+	}
+	row = append(row, strings.Join(transition, ", "))
+
+	var abort string
+	if days := rule.AbortIncompleteMultipartUpload.DaysAfterInitiation; days > 0 {
+		abort = fmt.Sprintf("%d 天", days)
+	}
+	row = append(row, abort)
+
+	for i, cell := range row {
+		if cell == "" {
+			row[i] = "-"
+		}
+	}
+	return row
+}
+
+// 以表格形式输出生命周期规则
+func printILMRules(rules []lifecycle.Rule) {
+	if len(rules) == 0 {
+		fmt.Println("没有生命周期规则")
+		return
+	}
+
+	rows := [][]string{{"ID", "状态", "前缀", "标签", "过期", "旧版本过期", "转换", "清理分片上传"}}
+	for _, rule := range rules {
+		rows = append(rows, ilmRuleRow(rule))
+	}
+
+	// 按显示宽度对齐，中文字符占两列
+	widths := make([]int, len(rows[0]))
+	for _, row := range rows {
+		for i, cell := range row {
+			widths[i] = max(widths[i], displayWidth(cell))
+		}
+	}
+	for _, row := range rows {
+		var line strings.Builder
+		for i, cell := range row {
+			line.WriteString(cell)
+			if i < len(row)-1 {
+				line.WriteString(strings.Repeat(" ", widths[i]-displayWidth(cell)+2))
+			}
+		}
+		fmt.Println(line.String())
+	}
+}
+
+// 检查规则至少包含一个动作，并且 ID 不与其他规则重复
+func validateILMRules(rules []lifecycle.Rule) error {
+	seen := make(map[string]bool)
+	for i, rule := range rules {
+		if rule.ID == "" {
+			return fmt.Errorf("第 %d 条规则缺少 ID", i+1)
+		}
+		if seen[rule.ID] {
+			return fmt.Errorf("规则 ID '%s' 重复", rule.ID)
+		}
+		seen[rule.ID] = true
+
+		if rule.Status != "Enabled" && rule.Status != "Disabled" {
+			return fmt.Errorf("规则 '%s' 的状态 '%s' 无效，应为 Enabled 或 Disabled", rule.ID, rule.Status)
+		}
+		if rule.Expiration.IsNull() && rule.NoncurrentVersionExpiration.IsDaysNull() && rule.NoncurrentVersionExpiration.NewerNoncurrentVersions == 0 &&
+			rule.Transition.IsNull() && rule.NoncurrentVersionTransition.IsStorageClassEmpty() &&
+			rule.AbortIncompleteMultipartUpload.IsDaysNull() && rule.DelMarkerExpiration.IsNull() && rule.AllVersionsExpiration.IsNull() {
+			return fmt.Errorf("规则 '%s' 没有任何过期或转换动作", rule.ID)
+		}
+	}
+	return nil
+}
+
+// 根据命令行参数生成一条规则
+func ilmRuleFromFlags(c *cli.Context) (lifecycle.Rule, error) {
+	rule := lifecycle.Rule{ID: c.String("id"), Status: "Enabled"}
+	if c.Bool("disable") {
+		rule.Status = "Disabled"
+	}
+	if rule.ID == "" {
+		id, err := newILMRuleID()
+		if err != nil {
+			return rule, err
+		}
+		rule.ID = id
+	}
+
+	prefix := strings.TrimPrefix(c.String("prefix"), "/")
+	tagMap, err := parseKeyValues(c.StringSlice("tag"), "标签")
+	if err != nil {
+		return rule, err
+	}
+	var tags []lifecycle.Tag
+	for key, value := range tagMap {
+		tags = append(tags, lifecycle.Tag{Key: key, Value: value})
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Key < tags[j].Key })
+
+	// 只有一个条件时直接放在过滤器中，多个条件需要使用 And
+	switch {
+	case len(tags) == 0:
+		rule.RuleFilter.Prefix = prefix
+	case len(tags) == 1 && prefix == "":
+		rule.RuleFilter.Tag = tags[0]
+	default:
+		rule.RuleFilter.And = lifecycle.And{Prefix: prefix, Tags: tags}
+	}
+
+	if c.IsSet("expire-days") && c.IsSet("expire-date") {
+		return rule, errors.New("--expire-days 和 --expire-date 不能同时使用")
+	}
+	rule.Expiration.Days = lifecycle.ExpirationDays(c.Int("expire-days"))
+	if c.IsSet("expire-date") {
+		if rule.Expiration.Date, err = parseILMDate(c.String("expire-date"), "过期日期"); err != nil {
+			return rule, err
+		}
+	}
+	rule.Expiration.DeleteMarker = lifecycle.ExpireDeleteMarker(c.Bool("expire-delete-marker"))
+
+	rule.NoncurrentVersionExpiration.NoncurrentDays = lifecycle.ExpirationDays(c.Int("noncurrent-expire-days"))
+	rule.NoncurrentVersionExpiration.NewerNoncurrentVersions = c.Int("newer-noncurrent-versions")
+
+	if c.IsSet("transition-days") && c.IsSet("transition-date") {
+		return rule, errors.New("--transition-days 和 --transition-date 不能同时使用")
+	}
+	storageClass := c.String("storage-class")
+	transition := c.IsSet("transition-days") || c.IsSet("transition-date")
+	if (transition || c.IsSet("noncurrent-transition-days")) && storageClass == "" {
+		return rule, errors.New("转换规则需要使用 --storage-class 指定目标存储类别或层级")
+	}
+	if storageClass != "" && !transition && !c.IsSet("noncurrent-transition-days") {
+		return rule, errors.New("--storage-class 需要与 --transition-days、--transition-date 或 --noncurrent-transition-days 一起使用")
+	}
+	if transition {
+		rule.Transition.StorageClass = storageClass
+		rule.Transition.Days = lifecycle.ExpirationDays(c.Int("transition-days"))
+		if c.IsSet("transition-date") {
+			if rule.Transition.Date, err = parseILMDate(c.String("transition-date"), "转换日期"); err != nil {
+				return rule, err
+			}
+		}
+	}
+	if c.IsSet("noncurrent-transition-days") {
+		rule.NoncurrentVersionTransition.StorageClass = storageClass
+		rule.NoncurrentVersionTransition.NoncurrentDays = lifecycle.ExpirationDays(c.Int("noncurrent-transition-days"))
+	}
+
+	rule.AbortIncompleteMultipartUpload.DaysAfterInitiation = lifecycle.ExpirationDays(c.Int("abort-upload-days"))
+
+	return rule, validateILMRules([]lifecycle.Rule{rule})
+}
+
+// 获取当前会话存储桶的生命周期配置，没有配置时返回空配置
+func currentILMConfig(ctx context.Context) (*minio.Client, *Session, *lifecycle.Configuration, error) {
+	manager, err := initSessionManager()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	client, err := manager.GetClient()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	session, err := manager.CurrentSession()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	config, err := client.GetBucketLifecycle(ctx, session.BucketName)
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchLifecycleConfiguration" {
+			return client, session, lifecycle.NewConfiguration(), nil
+		}
+		return nil, nil, nil, fmt.Errorf("无法获取生命周期配置: %w", err)
+	}
+
+	return client, session, config, nil
+}
+
+// 写入生命周期配置，没有规则时删除配置，dry-run 模式下只输出修改后的规则
+func saveILMConfig(ctx context.Context, c *cli.Context, client *minio.Client, session *Session, config *lifecycle.Configuration) error {
+	if isDryRun(c) {
+		fmt.Printf("修改后的生命周期规则 (bucket: %s):\n", session.BucketName)
+		printILMRules(config.Rules)
+		fmt.Println("dry-run 模式，未执行任何写操作")
+		return nil
+	}
+
+	if err := client.SetBucketLifecycle(ctx, session.BucketName, config); err != nil {
+		return fmt.Errorf("无法写入生命周期配置: %w", err)
+	}
+	return nil
+}
+
+// 添加生命周期规则操作
+func ilmAddAction(c *cli.Context) error {
+	rule, err := ilmRuleFromFlags(c)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	client, session, config, err := currentILMConfig(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, existing := range config.Rules {
+		if existing.ID == rule.ID {
+			return fmt.Errorf("规则 ID '%s' 已存在", rule.ID)
+		}
+	}
+	config.Rules = append(config.Rules, rule)
+
+	if err := saveILMConfig(ctx, c, client, session, config); err != nil {
+		return err
+	}
+	if !isDryRun(c) {
+		fmt.Printf("已添加生命周期规则: %s\n", rule.ID)
+	}
+	return nil
+}
+
+// 列出生命周期规则操作
+func ilmLsAction(c *cli.Context) error {
+	_, _, config, err := currentILMConfig(context.Background())
+	if err != nil {
+		return err
+	}
+
+	if c.Bool("json") {
+		data, err := json.MarshalIndent(config, "", "  ")
+		if err != nil {
+			return fmt.Errorf("无法序列化生命周期配置: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	printILMRules(config.Rules)
+	return nil
+}
+
+// 删除生命周期规则操作
+func ilmRmAction(c *cli.Context) error {
+	all := c.Bool("all")
+	if c.NArg() < 1 && !all {
+		return fmt.Errorf("需要指定要删除规则的 ID，或使用 --all 删除全部规则")
+	}
+
+	ctx := context.Background()
+	client, session, config, err := currentILMConfig(ctx)
+	if err != nil {
+		return err
+	}
+
+	ids := make(map[string]bool)
+	for _, id := range c.Args().Slice() {
+		ids[id] = true
+	}
+
+	var kept []lifecycle.Rule
+	removed := 0
+	for _, rule := range config.Rules {
+		if all || ids[rule.ID] {
+			removed++
+			delete(ids, rule.ID)
+			continue
+		}
+		kept = append(kept, rule)
+	}
+
+	for _, id := range c.Args().Slice() {
+		if ids[id] {
+			return fmt.Errorf("没有 ID 为 '%s' 的生命周期规则", id)
+		}
+	}
+	if removed == 0 {
+		return fmt.Errorf("没有生命周期规则")
+	}
+
+	config.Rules = kept
+	if err := saveILMConfig(ctx, c, client, session, config); err != nil {
+		return err
+	}
+	if !isDryRun(c) {
+		fmt.Printf("已删除 %d 条生命周期规则\n", removed)
+	}
+	return nil
+}
+
+// 导出生命周期配置操作，格式与 mc ilm rule export 相同
+func ilmExportAction(c *cli.Context) error {
+	_, _, config, err := currentILMConfig(context.Background())
+	if err != nil {
+		return err
+	}
+	if config.Rules == nil {
+		config.Rules = []lifecycle.Rule{}
+	}
+
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return fmt.Errorf("无法序列化生命周期配置: %w", err)
+	}
+
+	if c.NArg() < 1 || c.Args().First() == "-" {
+		fmt.Println(string(data))
+		return nil
+	}
+
+	path := c.Args().First()
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("无法写入文件: %w", err)
+	}
+	fmt.Printf("生命周期配置已导出到: %s\n", path)
+	return nil
+}
+
+// 导入生命周期配置操作，默认替换存储桶的全部规则，--merge 时按 ID 合并到已有规则中
+func ilmImportAction(c *cli.Context) error {
+	if c.NArg() < 1 {
+		return fmt.Errorf("需要指定配置文件 (- 表示标准输入)")
+	}
+
+	var data []byte
+	var err error
+	if path := c.Args().First(); path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return fmt.Errorf("无法读取配置文件: %w", err)
+	}
+
+	var imported lifecycle.Configuration
+	if err := json.Unmarshal(data, &imported); err != nil {
+		return fmt.Errorf("无法解析配置文件: %w", err)
+	}
+	if imported.Rules == nil {
+		return errors.New("配置文件中缺少 Rules")
+	}
+	if err := validateILMRules(imported.Rules); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	client, session, config, err := currentILMConfig(ctx)
+	if err != nil {
+		return err
+	}
+
+	if c.Bool("merge") {
+		// 同 ID 的规则由导入的规则替换
+		replaced := make(map[string]bool)
+		for _, rule := range imported.Rules {
+			replaced[rule.ID] = true
+		}
+		var merged []lifecycle.Rule
+		for _, rule := range config.Rules {
+			if !replaced[rule.ID] {
+				merged = append(merged, rule)
+			}
+		}
+		config.Rules = append(merged, imported.Rules...)
+	} else {
+		config.Rules = imported.Rules
+	}
+
+	if err := saveILMConfig(ctx, c, client, session, config); err != nil {
+		return err
+	}
+	if !isDryRun(c) {
+		fmt.Printf("已导入 %d 条生命周期规则\n", len(imported.Rules))
+	}
+	return nil
+}
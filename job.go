@@ -0,0 +1,776 @@
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/urfave/cli/v2"
+)
+
+// 后台任务的状态
+const (
+	jobRunning     = "running"
+	jobPaused      = "paused"
+	jobInterrupted = "interrupted" // 任务进程意外退出 (例如系统重启)，可以从检查点恢复
+	jobDone        = "done"
+	jobFailed      = "failed"
+	jobCanceled    = "canceled"
+)
+
+// 任务状态的显示名称
+var jobStatusLabels = map[string]string{
+	jobRunning:     "运行中",
+	jobPaused:      "已暂停",
+	jobInterrupted: "已中断",
+	jobDone:        "已完成",
+	jobFailed:      "失败",
+	jobCanceled:    "已取消",
+}
+
+var (
+	errJobLocked      = errors.New("任务已在运行")
+	errJobUnsupported = errors.New("后台任务仅支持 Unix 平台")
+)
+
+// 不能在后台运行的命令: 需要交互，或者会修改会话配置
+var jobBlockedCommands = map[string]bool{
+	"job": true, "login": true, "logout": true, "switch": true, "cd": true, "sse": true, "help": true, "h": true,
+}
+
+// Job 是一个后台任务，状态、日志和检查点保存在 ~/.minx/jobs/<id>/ 下
+type Job struct {
+	ID        string    `json:"id"`
+	Global    []string  `json:"global,omitempty"` // 命令之前的全局选项
+	Args      []string  `json:"args"`             // 命令及其参数
+	Session   string    `json:"session"`
+	RemoteDir string    `json:"remote_dir"` // 启动时的远程当前目录，相对路径按它解析
+	LocalDir  string    `json:"local_dir"`  // 启动时的本地工作目录
+	Status    string    `json:"status"`
+	PID       int       `json:"pid,omitempty"`
+	Runs      int       `json:"runs"` // 已运行的次数，大于 1 时表示从检查点恢复
+	Error     string    `json:"error,omitempty"`
+	Created   time.Time `json:"created"`
+	Started   time.Time `json:"started"`
+	Finished  time.Time `json:"finished"`
+
+	dir        string
+	checkpoint *jobCheckpoint
+}
+
+// 当前进程中正在运行的任务，不在后台任务中时为 nil
+var runningJob *Job
+
+// 后台任务目录: ~/.minx/jobs
+func jobsDir() (string, error) {
+	manager, err := initSessionManager()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(manager.ConfigPath), "jobs"), nil
+}
+
+// 生成任务 ID: 创建时间加随机后缀，按字典序即为创建顺序
+func newJobID() (string, error) {
+	buf := make([]byte, 2)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("无法生成任务 ID: %w", err)
+	}
+	return time.Now().Format("20060102-150405") + "-" + hex.EncodeToString(buf), nil
+}
+
+// 读取任务状态
+func readJob(dir string) (*Job, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "job.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var job Job
+	if err := json.Unmarshal(data, &job); err != nil {
+		return nil, fmt.Errorf("无法解析任务状态: %w", err)
+	}
+	job.dir = dir
+	return &job, nil
+}
+
+// 按 ID 或唯一的 ID 前缀查找任务
+func findJob(id string) (*Job, error) {
+	if id == "" {
+		return nil, fmt.Errorf("需要指定任务 ID")
+	}
+
+	jobs, err := listJobs()
+	if err != nil {
+		return nil, err
+	}
+
+	var matched []*Job
+	for _, job := range jobs {
+		if job.ID == id {
+			return job, nil
+		}
+		if strings.HasPrefix(job.ID, id) {
+			matched = append(matched, job)
+		}
+	}
+
+	switch len(matched) {
+	case 0:
+		return nil, fmt.Errorf("任务 '%s' 不存在", id)
+	case 1:
+		return matched[0], nil
+	}
+	return nil, fmt.Errorf("任务 ID 前缀 '%s' 匹配多个任务", id)
+}
+
+// 列出所有任务，按创建时间排序
+func listJobs() ([]*Job, error) {
+	dir, err := jobsDir()
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("无法读取任务目录: %w", err)
+	}
+
+	var jobs []*Job
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		job, err := readJob(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			continue
+		}
+		jobs = append(jobs, job)
+	}
+
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].Created.Before(jobs[j].Created) })
+	return jobs, nil
+}
+
+// 保存任务状态，先写临时文件再替换，避免读到写了一半的文件
+// 任务的参数中可能包含 --auth，文件只允许当前用户读取
+func (j *Job) save() error {
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return fmt.Errorf("无法序列化任务状态: %w", err)
+	}
+
+	path := filepath.Join(j.dir, "job.json")
+	if err := os.WriteFile(path+".tmp", data, 0600); err != nil {
+		return fmt.Errorf("无法写入任务状态: %w", err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return fmt.Errorf("无法写入任务状态: %w", err)
+	}
+	return nil
+}
+
+// 重新读取任务状态，期间可能被其他 minx 进程修改
+func (j *Job) reload() error {
+	latest, err := readJob(j.dir)
+	if err != nil {
+		return err
+	}
+	*j = *latest
+	return nil
+}
+
+func (j *Job) logPath() string {
+	return filepath.Join(j.dir, "output.log")
+}
+
+// 任务进程是否存活: 任务进程运行期间持有 lock 文件的锁
+func (j *Job) alive() bool {
+	lock, err := lockJobFile(filepath.Join(j.dir, "lock"))
+	if err != nil {
+		return errors.Is(err, errJobLocked)
+	}
+	lock.Close()
+	return false
+}
+
+// 实际状态: 记录为运行中或已暂停但进程已经退出时为已中断
+func (j *Job) state() string {
+	if (j.Status == jobRunning || j.Status == jobPaused) && !j.alive() {
+		return jobInterrupted
+	}
+	return j.Status
+}
+
+func (j *Job) command() string {
+	return strings.Join(j.Args, " ")
+}
+
+// 本次运行的参数，恢复下载时加上 -c 断点续传，继续写入未下载完的文件
+func (j *Job) runArgs() []string {
+	args := append([]string{}, j.Global...)
+	if j.Runs > 1 && j.Args[0] == "get" && !containsAny(j.Args, "-c", "--c", "-continue", "--continue") {
+		return append(append(args, "get", "-c"), j.Args[1:]...)
+	}
+	return append(args, j.Args...)
+}
+
+func containsAny(list []string, values ...string) bool {
+	for _, s := range list {
+		for _, v := range values {
+			if s == v {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// 检查命令能否在后台运行
+func validateJobArgs(app *cli.App, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("需要指定要在后台运行的命令")
+	}
+
+	cmd := app.Command(args[0])
+	if cmd == nil {
+		return fmt.Errorf("未知命令 '%s'", args[0])
+	}
+	if jobBlockedCommands[cmd.Name] || (cmd.Name == "trash" && len(args) > 1 && (args[1] == "enable" || args[1] == "disable")) {
+		return fmt.Errorf("命令 '%s' 不能在后台运行", strings.Join(args, " "))
+	}
+	for _, arg := range args[1:] {
+		if arg == "-" {
+			return fmt.Errorf("后台任务不能读写标准输入或标准输出")
+		}
+	}
+	return nil
+}
+
+// 创建后台任务并启动任务进程
+func startJob(c *cli.Context, global, args []string) (*Job, error) {
+	if detachAttr() == nil {
+		return nil, errJobUnsupported
+	}
+	if err := validateJobArgs(c.App, args); err != nil {
+		return nil, err
+	}
+
+	manager, err := initSessionManager()
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := manager.CurrentSession()
+	if err != nil {
+		return nil, err
+	}
+
+	localDir, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("无法获取当前目录: %w", err)
+	}
+
+	root, err := jobsDir()
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := newJobID()
+	if err != nil {
+		return nil, err
+	}
+
+	job := &Job{
+		ID:        id,
+		Global:    global,
+		Args:      args,
+		Session:   manager.CurrentName,
+		RemoteDir: session.CurrentPath,
+		LocalDir:  localDir,
+		Status:    jobRunning,
+		Created:   time.Now(),
+		dir:       filepath.Join(root, id),
+	}
+
+	if err := os.MkdirAll(job.dir, 0700); err != nil {
+		return nil, fmt.Errorf("无法创建任务目录: %w", err)
+	}
+	if err := job.save(); err != nil {
+		return nil, err
+	}
+
+	if err := job.spawn(); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// 启动脱离终端的任务进程，等待它开始运行后返回
+func (j *Job) spawn() error {
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("无法获取 minx 可执行文件路径: %w", err)
+	}
+
+	logFile, err := os.OpenFile(j.logPath(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("无法打开任务日志: %w", err)
+	}
+	defer logFile.Close()
+
+	cmd := exec.Command(exe, "job", "run", j.ID)
+	cmd.Dir = j.LocalDir
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	cmd.SysProcAttr = detachAttr()
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("无法启动任务进程: %w", err)
+	}
+
+	exited := make(chan struct{})
+	go func() {
+		cmd.Wait()
+		close(exited)
+	}()
+
+	// 任务进程取得任务锁后会增加运行次数，这里不能去抢锁，否则任务进程可能取不到
+	runs := j.Runs
+	deadline := time.After(5 * time.Second)
+	for {
+		select {
+		case <-exited:
+			if err := j.reload(); err == nil && j.Runs > runs {
+				return nil
+			}
+			return fmt.Errorf("任务进程启动失败，查看日志 %s", j.logPath())
+		case <-deadline:
+			return fmt.Errorf("等待任务进程启动超时，查看日志 %s", j.logPath())
+		case <-time.After(50 * time.Millisecond):
+			if err := j.reload(); err == nil && j.Runs > runs {
+				return nil
+			}
+		}
+	}
+}
+
+// 任务进程: 取得任务锁，在当前进程中执行任务的命令，结束后记录结果
+func jobRunAction(c *cli.Context) error {
+	job, err := findJob(c.Args().First())
+	if err != nil {
+		return err
+	}
+
+	// 其他 minx 进程检查任务是否存活时会短暂持有锁，稍等后重试
+	var lock *os.File
+	for i := 0; i < 10; i++ {
+		if lock, err = lockJobFile(filepath.Join(job.dir, "lock")); !errors.Is(err, errJobLocked) {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if err != nil {
+		return err
+	}
+	defer lock.Close()
+
+	if err := job.reload(); err != nil {
+		return err
+	}
+	if job.Status == jobDone || job.Status == jobCanceled {
+		return fmt.Errorf("任务 %s %s", job.ID, jobStatusLabels[job.Status])
+	}
+
+	// 使用启动任务时的会话和远程目录，只修改内存中的配置
+	manager, err := initSessionManager()
+	if err != nil {
+		return err
+	}
+	session, ok := manager.Sessions[job.Session]
+	if !ok {
+		return job.finish(fmt.Errorf("会话 '%s' 不存在", job.Session))
+	}
+	session.CurrentPath = job.RemoteDir
+	manager.Sessions[job.Session] = session
+	manager.CurrentName = job.Session
+
+	job.Status = jobRunning
+	job.PID = os.Getpid()
+	job.Runs++
+	job.Error = ""
+	job.Started = time.Now()
+	if err := job.save(); err != nil {
+		return err
+	}
+
+	checkpoint, err := openJobCheckpoint(filepath.Join(job.dir, "checkpoint"))
+	if err != nil {
+		return job.finish(err)
+	}
+	defer checkpoint.Close()
+	job.checkpoint = checkpoint
+	runningJob = job
+
+	args := job.runArgs()
+	if job.Runs > 1 {
+		fmt.Printf("==> %s 从检查点恢复 (第 %d 次运行，已完成 %d 项): minx %s\n", job.Started.Format("2006-01-02 15:04:05"), job.Runs, checkpoint.Count(), strings.Join(args, " "))
+	} else {
+		fmt.Printf("==> %s 开始运行: minx %s\n", job.Started.Format("2006-01-02 15:04:05"), strings.Join(args, " "))
+	}
+
+	// 由任务进程记录退出状态，不让命令直接退出进程
+	c.App.ExitErrHandler = func(*cli.Context, error) {}
+	return job.finish(c.App.Run(append([]string{os.Args[0]}, args...)))
+}
+
+// 记录任务的运行结果，已被取消的任务保持取消状态
+func (j *Job) finish(runErr error) error {
+	if err := j.reload(); err != nil {
+		return err
+	}
+
+	j.PID = 0
+	j.Finished = time.Now()
+	if j.Status != jobCanceled {
+		j.Status = jobDone
+		j.Error = ""
+		if runErr != nil {
+			j.Status = jobFailed
+			j.Error = runErr.Error()
+		}
+	}
+
+	if runErr != nil {
+		fmt.Fprintf(os.Stderr, "错误: %v\n", runErr)
+	}
+	fmt.Printf("==> %s %s\n", j.Finished.Format("2006-01-02 15:04:05"), jobStatusLabels[j.Status])
+	return j.save()
+}
+
+// jobCheckpoint 记录任务中已经完成的项目，任务恢复时跳过
+type jobCheckpoint struct {
+	mu   sync.Mutex
+	file *os.File
+	done map[string]bool
+}
+
+func openJobCheckpoint(path string) (*jobCheckpoint, error) {
+	cp := &jobCheckpoint{done: make(map[string]bool)}
+
+	if data, err := os.ReadFile(path); err == nil {
+		scanner := bufio.NewScanner(strings.NewReader(string(data)))
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			if line := scanner.Text(); line != "" {
+				cp.done[line] = true
+			}
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("无法读取检查点: %w", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("无法打开检查点: %w", err)
+	}
+	cp.file = file
+	return cp, nil
+}
+
+// Count 返回已完成的项目数
+func (cp *jobCheckpoint) Count() int {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	return len(cp.done)
+}
+
+func (cp *jobCheckpoint) Close() error {
+	return cp.file.Close()
+}
+
+// 项目在之前的运行中是否已经完成，不在后台任务中时总是返回 false
+func checkpointDone(key string) bool {
+	if runningJob == nil || runningJob.checkpoint == nil {
+		return false
+	}
+	cp := runningJob.checkpoint
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	return cp.done[key]
+}
+
+// 记录已完成的项目，可在多个工作线程中并发调用
+func checkpointMark(key string) {
+	if runningJob == nil || runningJob.checkpoint == nil {
+		return
+	}
+	cp := runningJob.checkpoint
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	if !cp.done[key] {
+		cp.done[key] = true
+		fmt.Fprintln(cp.file, key)
+	}
+}
+
+// sync 的检查点: 对象名加上本地文件的大小和修改时间，文件在恢复之前被修改时仍会重新上传
+func syncCheckpointKey(objectName string, info os.FileInfo) string {
+	return fmt.Sprintf("%s\t%d\t%d", objectName, info.Size(), info.ModTime().UnixNano())
+}
+
+// 将 ctx 中显式设置的选项还原为命令行参数，跳过 skip 中的选项
+func flagArgs(ctx *cli.Context, flags []cli.Flag, skip ...string) []string {
+	var args []string
+	for _, flag := range flags {
+		name := flag.Names()[0]
+		if !ctx.IsSet(name) || containsAny(skip, name) {
+			continue
+		}
+		args = append(args, fmt.Sprintf("--%s=%v", name, ctx.Value(name)))
+	}
+	return args
+}
+
+// 命令之前显式设置的全局选项，按解析后的值还原为命令行参数
+// Lineage 的最后一项是 App.Run 创建的空上下文，全局选项在根命令的上下文中
+func globalFlagArgs(c *cli.Context) []string {
+	var root *cli.Context
+	for _, ctx := range c.Lineage() {
+		if ctx.Command != nil {
+			root = ctx
+		}
+	}
+	return flagArgs(root, c.App.Flags)
+}
+
+// 启动后台任务操作，命令之前的全局选项会传给任务
+func jobStartAction(c *cli.Context) error {
+	job, err := startJob(c, globalFlagArgs(c), c.Args().Slice())
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("已启动后台任务 %s: minx %s\n", job.ID, job.command())
+	fmt.Printf("使用 minx job status %s 查看状态，minx job logs -f %s 查看输出\n", job.ID, job.ID)
+	return nil
+}
+
+// 左对齐并按显示宽度补齐空格
+func padRight(s string, width int) string {
+	if w := displayWidth(s); w < width {
+		return s + strings.Repeat(" ", width-w)
+	}
+	return s
+}
+
+// 列出后台任务操作
+func jobLsAction(c *cli.Context) error {
+	jobs, err := listJobs()
+	if err != nil {
+		return err
+	}
+
+	if len(jobs) == 0 {
+		fmt.Println("没有后台任务")
+		return nil
+	}
+
+	for _, job := range jobs {
+		fmt.Printf("%s  %s  %s  %s\n", job.ID, padRight(jobStatusLabels[job.state()], 6), job.Created.Local().Format("2006-01-02 15:04:05"), job.command())
+	}
+	return nil
+}
+
+// 查看后台任务状态操作
+func jobStatusAction(c *cli.Context) error {
+	job, err := findJob(c.Args().First())
+	if err != nil {
+		return err
+	}
+
+	state := job.state()
+	fmt.Printf("任务:     %s\n", job.ID)
+	fmt.Printf("命令:     minx %s\n", strings.Join(append(append([]string{}, job.Global...), job.Args...), " "))
+	fmt.Printf("会话:     %s (远程目录 %s，本地目录 %s)\n", job.Session, job.RemoteDir, job.LocalDir)
+	fmt.Printf("状态:     %s\n", jobStatusLabels[state])
+	if state == jobRunning || state == jobPaused {
+		fmt.Printf("进程:     %d\n", job.PID)
+	}
+	fmt.Printf("创建时间: %s\n", job.Created.Local().Format("2006-01-02 15:04:05"))
+	if job.Runs > 0 {
+		fmt.Printf("运行:     %d 次，最近开始于 %s\n", job.Runs, job.Started.Local().Format("2006-01-02 15:04:05"))
+	}
+	if !job.Finished.IsZero() && state != jobRunning && state != jobPaused {
+		fmt.Printf("结束时间: %s\n", job.Finished.Local().Format("2006-01-02 15:04:05"))
+	}
+	if job.Error != "" {
+		fmt.Printf("错误:     %s\n", job.Error)
+	}
+	if cp, err := openJobCheckpoint(filepath.Join(job.dir, "checkpoint")); err == nil {
+		if n := cp.Count(); n > 0 {
+			fmt.Printf("检查点:   已完成 %d 项\n", n)
+		}
+		cp.Close()
+	}
+	fmt.Printf("日志:     %s\n", job.logPath())
+
+	switch state {
+	case jobInterrupted:
+		fmt.Printf("任务进程已退出，使用 minx job resume %s 从检查点恢复\n", job.ID)
+	case jobFailed:
+		fmt.Printf("使用 minx job resume %s 从检查点重新运行\n", job.ID)
+	}
+	return nil
+}
+
+// 查看后台任务输出操作，-f 时持续输出直到任务结束
+func jobLogsAction(c *cli.Context) error {
+	job, err := findJob(c.Args().First())
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(job.logPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("无法打开任务日志: %w", err)
+	}
+	defer file.Close()
+
+	for {
+		if _, err := io.Copy(os.Stdout, file); err != nil {
+			return err
+		}
+		if !c.Bool("f") || !job.alive() {
+			// 进程退出前写入的内容也要输出
+			_, err := io.Copy(os.Stdout, file)
+			return err
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+// 暂停后台任务操作
+func jobPauseAction(c *cli.Context) error {
+	job, err := findJob(c.Args().First())
+	if err != nil {
+		return err
+	}
+
+	if state := job.state(); state != jobRunning {
+		return fmt.Errorf("任务 %s %s，无法暂停", job.ID, jobStatusLabels[state])
+	}
+
+	if err := pauseProcess(job.PID); err != nil {
+		return fmt.Errorf("无法暂停任务进程: %w", err)
+	}
+	job.Status = jobPaused
+	if err := job.save(); err != nil {
+		return err
+	}
+
+	fmt.Printf("已暂停任务 %s\n", job.ID)
+	return nil
+}
+
+// 恢复后台任务操作: 已暂停的任务继续运行，已中断或失败的任务重新启动并从检查点继续
+func jobResumeAction(c *cli.Context) error {
+	var jobs []*Job
+	if c.Bool("all") {
+		all, err := listJobs()
+		if err != nil {
+			return err
+		}
+		for _, job := range all {
+			if state := job.state(); state == jobPaused || state == jobInterrupted {
+				jobs = append(jobs, job)
+			}
+		}
+		if len(jobs) == 0 {
+			fmt.Println("没有需要恢复的任务")
+			return nil
+		}
+	} else {
+		job, err := findJob(c.Args().First())
+		if err != nil {
+			return err
+		}
+		jobs = append(jobs, job)
+	}
+
+	var failed int
+	for _, job := range jobs {
+		if err := resumeJob(job); err != nil {
+			fmt.Fprintf(os.Stderr, "恢复失败 '%s': %v\n", job.ID, err)
+			failed++
+			continue
+		}
+		fmt.Printf("已恢复任务 %s: minx %s\n", job.ID, job.command())
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d 个任务恢复失败", failed)
+	}
+	return nil
+}
+
+func resumeJob(job *Job) error {
+	switch state := job.state(); state {
+	case jobPaused:
+		if err := continueProcess(job.PID); err != nil {
+			return fmt.Errorf("无法继续任务进程: %w", err)
+		}
+		job.Status = jobRunning
+		return job.save()
+
+	case jobInterrupted, jobFailed:
+		return job.spawn()
+
+	default:
+		return fmt.Errorf("任务%s", jobStatusLabels[state])
+	}
+}
+
+// 取消后台任务操作
+func jobCancelAction(c *cli.Context) error {
+	job, err := findJob(c.Args().First())
+	if err != nil {
+		return err
+	}
+
+	state := job.state()
+	if state == jobDone || state == jobCanceled {
+		return fmt.Errorf("任务 %s %s", job.ID, jobStatusLabels[state])
+	}
+
+	job.Status = jobCanceled
+	job.Finished = time.Now()
+	if err := job.save(); err != nil {
+		return err
+	}
+
+	if state == jobRunning || state == jobPaused {
+		if err := terminateProcess(job.PID); err != nil {
+			return fmt.Errorf("无法终止任务进程: %w", err)
+		}
+	}
+
+	fmt.Printf("已取消任务 %s\n", job.ID)
+	return nil
+}
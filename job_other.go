@@ -0,0 +1,34 @@
+//go:build !unix
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// 非 Unix 平台不支持后台任务
+func detachAttr() *syscall.SysProcAttr {
+	return nil
+}
+
+func lockJobFile(path string) (*os.File, error) {
+	return nil, errJobUnsupported
+}
+
+// 非 Unix 平台不加锁
+func lockFileWait(path string) (*os.File, error) {
+	return nil, nil
+}
+
+func pauseProcess(pid int) error {
+	return errJobUnsupported
+}
+
+func continueProcess(pid int) error {
+	return errJobUnsupported
+}
+
+func terminateProcess(pid int) error {
+	return errJobUnsupported
+}
@@ -0,0 +1,64 @@
+//go:build unix
+
+package main
+
+import (
+	"errors"
+	"os"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// 后台任务进程脱离终端所在的会话，关闭终端时不会收到 SIGHUP
+func detachAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Setsid: true}
+}
+
+// 锁定任务目录中的 lock 文件，任务进程退出 (包括重启) 后锁自动释放
+// 任务已在运行时返回 errJobLocked
+func lockJobFile(path string) (*os.File, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if err := unix.Flock(int(file.Fd()), unix.LOCK_EX|unix.LOCK_NB); err != nil {
+		file.Close()
+		if errors.Is(err, unix.EWOULDBLOCK) {
+			return nil, errJobLocked
+		}
+		return nil, err
+	}
+	return file, nil
+}
+
+// 锁定 lock 文件，其他进程持有锁时等待其释放
+func lockFileWait(path string) (*os.File, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if err := unix.Flock(int(file.Fd()), unix.LOCK_EX); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return file, nil
+}
+
+// 暂停任务进程
+func pauseProcess(pid int) error {
+	return unix.Kill(pid, unix.SIGSTOP)
+}
+
+// 继续已暂停的任务进程
+func continueProcess(pid int) error {
+	return unix.Kill(pid, unix.SIGCONT)
+}
+
+// 终止任务进程，已暂停的进程需要继续后才能处理 SIGTERM
+func terminateProcess(pid int) error {
+	if err := unix.Kill(pid, unix.SIGTERM); err != nil {
+		return err
+	}
+	return unix.Kill(pid, unix.SIGCONT)
+}
@@ -0,0 +1,438 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/minio/minio-go/v7"
+	"github.com/urfave/cli/v2"
+	"minx/wildcard"
+)
+
+// 按名称排序时每批输出的条目数，列宽按批计算，避免读取整个目录
+const lsBatchSize = 1000
+
+// lsOptions 描述 ls 的显示方式
+type lsOptions struct {
+	long      bool
+	human     bool
+	recursive bool
+	dirsOnly  bool
+	orig      bool
+	sortBy    string // name、size、time 或 ext
+	reverse   bool
+	pattern   string // 名称通配符，含 / 时匹配相对路径，否则匹配文件名
+	colors    lsColors
+}
+
+// lsEntry 表示要输出的一个文件或目录
+type lsEntry struct {
+	name        string // 相对于列出目录的路径，目录以 / 结尾
+	dir         bool
+	info        minio.ObjectInfo
+	contentType string
+	origSize    int64
+	lock        string // 对象锁定状态，见 objectLockState
+}
+
+// lsColors 保存 LS_COLORS 格式的颜色设置，键为 di、fi 或 *.扩展名
+type lsColors map[string]*color.Color
+
+// GNU dircolors 的部分默认值，未设置 LS_COLORS 时使用
+const defaultLSColors = "di=01;34:fi=0:" +
+	"*.tar=01;31:*.tgz=01;31:*.gz=01;31:*.zip=01;31:*.zst=01;31:*.bz2=01;31:*.xz=01;31:*.7z=01;31:*.rar=01;31:" +
+	"*.jpg=01;35:*.jpeg=01;35:*.png=01;35:*.gif=01;35:*.svg=01;35:*.webp=01;35:*.mp4=01;35:*.mkv=01;35:*.mov=01;35:" +
+	"*.mp3=00;36:*.flac=00;36:*.wav=00;36:*.ogg=00;36"
+
+// 解析 LS_COLORS，忽略无法识别的项
+func parseLSColors(spec string) lsColors {
+	colors := make(lsColors)
+	for _, item := range strings.Split(spec, ":") {
+		key, codes, ok := strings.Cut(item, "=")
+		if !ok || key == "" {
+			continue
+		}
+
+		var attrs []color.Attribute
+		for _, code := range strings.Split(codes, ";") {
+			n, err := strconv.Atoi(code)
+			if err != nil {
+				attrs = nil
+				break
+			}
+			attrs = append(attrs, color.Attribute(n))
+		}
+		if len(attrs) > 0 {
+			colors[strings.ToLower(key)] = color.New(attrs...)
+		}
+	}
+	return colors
+}
+
+// 加载颜色设置，优先使用 LS_COLORS 环境变量
+func loadLSColors() lsColors {
+	if spec := os.Getenv("LS_COLORS"); spec != "" {
+		return parseLSColors(spec)
+	}
+	return parseLSColors(defaultLSColors)
+}
+
+// 按类型为名称着色，未启用颜色时原样返回
+func (lc lsColors) paint(name string, dir bool) string {
+	if lc == nil {
+		return name
+	}
+
+	key := "fi"
+	if dir {
+		key = "di"
+	} else if ext := path.Ext(name); ext != "" {
+		if _, ok := lc["*"+strings.ToLower(ext)]; ok {
+			key = "*" + strings.ToLower(ext)
+		}
+	}
+
+	if c, ok := lc[key]; ok {
+		return c.Sprint(name)
+	}
+	return name
+}
+
+// 格式化大小: -h 时为易读格式，否则为字节数
+func (o *lsOptions) size(n int64) string {
+	if o.human {
+		return formatSize(n)
+	}
+	if n < 0 {
+		return "-"
+	}
+	return strconv.FormatInt(n, 10)
+}
+
+// 判断名称是否匹配通配符
+func (o *lsOptions) match(name string) bool {
+	if o.pattern == "" {
+		return true
+	}
+	name = strings.TrimSuffix(name, "/")
+	if strings.Contains(o.pattern, "/") {
+		return wildcard.Match(o.pattern, name)
+	}
+	return wildcard.Match(o.pattern, path.Base(name))
+}
+
+// 是否可以边列出边输出: 列表本身按名称顺序返回
+func (o *lsOptions) streaming() bool {
+	return o.sortBy == "name" && !o.reverse
+}
+
+// 按指定方式排序，按大小和时间排序时从大到小、从新到旧，目录始终在文件之前
+func sortLsEntries(entries []lsEntry, sortBy string, reverse bool) {
+	less := func(a, b lsEntry) bool {
+		switch sortBy {
+		case "size":
+			if a.info.Size != b.info.Size {
+				return a.info.Size > b.info.Size
+			}
+		case "time":
+			if !a.info.LastModified.Equal(b.info.LastModified) {
+				return a.info.LastModified.After(b.info.LastModified)
+			}
+		case "ext":
+			if ea, eb := path.Ext(a.name), path.Ext(b.name); ea != eb {
+				return ea < eb
+			}
+		}
+		return a.name < b.name
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].dir != entries[j].dir {
+			return entries[i].dir
+		}
+		if reverse {
+			return less(entries[j], entries[i])
+		}
+		return less(entries[i], entries[j])
+	})
+}
+
+// lsColumn 是输出中的一列，大小列右对齐
+type lsColumn struct {
+	value string
+	right bool
+}
+
+// 一个条目在名称之前的各列，短格式下目录只显示名称
+func (o *lsOptions) columns(e lsEntry) []lsColumn {
+	const timeLayout = "2006-01-02 15:04:05"
+
+	if e.dir && !o.long {
+		return nil
+	}
+
+	var cols []lsColumn
+	if o.long {
+		class := e.info.StorageClass
+		if class == "" {
+			class = "STANDARD"
+		}
+		lock := e.lock
+		if lock == "" {
+			lock = "-"
+		}
+		cols = append(cols, lsColumn{value: class}, lsColumn{value: lock})
+	}
+
+	cols = append(cols, lsColumn{value: o.size(e.info.Size), right: true})
+	if o.orig {
+		cols = append(cols, lsColumn{value: o.size(e.origSize), right: true})
+	}
+	cols = append(cols, lsColumn{value: e.info.LastModified.Local().Format(timeLayout)})
+
+	if o.long {
+		contentType := e.contentType
+		if contentType == "" {
+			contentType = "-"
+		}
+		cols = append(cols, lsColumn{value: e.info.ETag}, lsColumn{value: contentType})
+	}
+
+	// 目录没有大小、时间等信息
+	if e.dir {
+		for i := range cols {
+			cols[i].value = "-"
+		}
+	}
+	return cols
+}
+
+// 输出一批条目，列宽根据这批数据计算
+func printLsEntries(entries []lsEntry, o *lsOptions) {
+	rows := make([][]lsColumn, len(entries))
+	var widths []int
+	for i, e := range entries {
+		rows[i] = o.columns(e)
+		for j, col := range rows[i] {
+			if j >= len(widths) {
+				widths = append(widths, 0)
+			}
+			if n := len(col.value); n > widths[j] {
+				widths[j] = n
+			}
+		}
+	}
+
+	for i, e := range entries {
+		var b strings.Builder
+		for j, col := range rows[i] {
+			if col.right {
+				fmt.Fprintf(&b, "%*s  ", widths[j], col.value)
+			} else {
+				fmt.Fprintf(&b, "%-*s  ", widths[j], col.value)
+			}
+		}
+		b.WriteString(o.colors.paint(e.name, e.dir))
+		fmt.Println(b.String())
+	}
+}
+
+// 列出文件操作
+// 按名称排序时使用分隔符逐页列出，边读取边输出；其他排序方式需要读取全部条目
+func lsAction(c *cli.Context) error {
+	if c.Bool("help") {
+		return cli.ShowSubcommandHelp(c)
+	}
+
+	manager, err := initSessionManager()
+	if err != nil {
+		return err
+	}
+
+	client, err := manager.GetClient()
+	if err != nil {
+		return err
+	}
+
+	session, err := manager.CurrentSession()
+	if err != nil {
+		return err
+	}
+
+	o := &lsOptions{
+		long:      c.Bool("l"),
+		human:     c.Bool("h"),
+		recursive: c.Bool("R"),
+		dirsOnly:  c.Bool("d"),
+		orig:      c.Bool("orig"),
+		sortBy:    c.String("sort"),
+		reverse:   c.Bool("reverse"),
+	}
+	// 兼容原来的 -r: 按修改时间从新到旧排列
+	if c.Bool("r") {
+		if c.IsSet("sort") && o.sortBy != "time" {
+			return fmt.Errorf("-r 表示按修改时间排序，不能与 --sort %s 同时使用", o.sortBy)
+		}
+		o.sortBy = "time"
+	}
+	switch o.sortBy {
+	case "name", "size", "time", "ext":
+	default:
+		return fmt.Errorf("无效的排序方式 '%s'，应为 name、size、time 或 ext", o.sortBy)
+	}
+	if c.Bool("color") {
+		o.colors = loadLSColors()
+	}
+
+	// 参数中含通配符时，通配符之前的目录作为列出的目录
+	var remotePathArg string
+	if c.NArg() > 0 {
+		remotePathArg = c.Args().First()
+	}
+	if i := strings.IndexAny(remotePathArg, "*?"); i >= 0 {
+		j := strings.LastIndex(remotePathArg[:i], "/")
+		o.pattern = remotePathArg[j+1:]
+		remotePathArg = remotePathArg[:j+1]
+	}
+
+	remotePath, err := manager.FormatPath(remotePathArg)
+	if err != nil {
+		return err
+	}
+
+	// 确保路径以 / 结尾
+	prefix := strings.TrimPrefix(remotePath, "/")
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+
+	// 通配符之前的固定部分可以缩小列出范围，递归列出时通配符匹配各层的文件名，不能缩小
+	listPrefix := prefix
+	if o.pattern != "" && !strings.Contains(o.pattern, "/") && !o.recursive {
+		listPrefix += o.pattern[:strings.IndexAny(o.pattern, "*?")]
+	}
+
+	// 分页: --page 按 --limit 的大小跳过前面的条目，--start-after 直接从指定名称之后开始
+	limit := c.Int("limit")
+	page := c.Int("page")
+	if page < 1 {
+		return fmt.Errorf("--page 从 1 开始")
+	}
+	if page > 1 && limit <= 0 {
+		return fmt.Errorf("--page 需要与 --limit 一起使用")
+	}
+	skip := (page - 1) * limit
+
+	// 提前结束时取消列表，避免后台的列表协程阻塞
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// 长格式需要 Content-Type，--orig 需要元数据中记录的原始大小
+	opts := minio.ListObjectsOptions{
+		Prefix:       listPrefix,
+		Recursive:    o.recursive,
+		WithMetadata: o.long || o.orig,
+	}
+	if s := c.String("start-after"); s != "" {
+		opts.StartAfter = prefix + s
+	}
+
+	// 补充长格式和 --orig 需要的信息，列表结果不含元数据时单独获取
+	newEntry := func(object minio.ObjectInfo, name string) lsEntry {
+		e := lsEntry{name: name, dir: strings.HasSuffix(name, "/"), info: object}
+		if e.dir || (!o.long && !o.orig) {
+			return e
+		}
+
+		info := object
+		if info.UserMetadata == nil {
+			if stat, err := client.StatObject(ctx, session.BucketName, object.Key, minio.StatObjectOptions{}); err == nil {
+				info = stat
+			}
+		}
+		e.contentType = info.ContentType
+		if e.contentType == "" {
+			e.contentType = userMeta(info, "content-type")
+		}
+		e.origSize = newDecoder(nil).PlainSize(info)
+		e.lock = objectLockState(info)
+		return e
+	}
+
+	var entries []lsEntry
+	skipped, shown := 0, 0
+	last, more := "", false
+
+	for object := range client.ListObjects(ctx, session.BucketName, opts) {
+		if object.Err != nil {
+			return fmt.Errorf("列出对象时出错: %w", object.Err)
+		}
+
+		name := strings.TrimPrefix(object.Key, prefix)
+		if name == "" {
+			// 目录自身的标记对象
+			continue
+		}
+		if o.dirsOnly && !strings.HasSuffix(name, "/") {
+			continue
+		}
+		if !o.match(name) {
+			continue
+		}
+
+		if !o.streaming() {
+			entries = append(entries, lsEntry{name: name, dir: strings.HasSuffix(name, "/"), info: object})
+			continue
+		}
+
+		if skipped < skip {
+			skipped++
+			continue
+		}
+		if limit > 0 && shown >= limit {
+			more = true
+			break
+		}
+
+		entries = append(entries, newEntry(object, name))
+		shown++
+		last = name
+
+		if len(entries) >= lsBatchSize {
+			printLsEntries(entries, o)
+			entries = entries[:0]
+		}
+	}
+
+	if !o.streaming() {
+		sortLsEntries(entries, o.sortBy, o.reverse)
+
+		if skip > len(entries) {
+			skip = len(entries)
+		}
+		entries = entries[skip:]
+		if limit > 0 && len(entries) > limit {
+			entries, more = entries[:limit], true
+		}
+		for i := range entries {
+			entries[i] = newEntry(entries[i].info, entries[i].name)
+		}
+	}
+	printLsEntries(entries, o)
+
+	if more && !c.Bool("quiet") {
+		if o.streaming() {
+			fmt.Fprintf(os.Stderr, "还有更多条目，使用 --start-after '%s' 查看下一页\n", last)
+		} else {
+			fmt.Fprintf(os.Stderr, "还有更多条目，使用 --page %d 查看下一页\n", page+1)
+		}
+	}
+
+	return nil
+}
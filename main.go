@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/urfave/cli/v2"
 	"os"
+	"time"
 )
 
 func main() {
@@ -11,6 +12,8 @@ func main() {
 		Name:    "minx",
 		Usage:   "Minio Storage Command Tool",
 		Version: "0.1.0",
+		// 元数据和标签的值中可能包含逗号，不按逗号拆分多值参数
+		DisableSliceFlagSeparator: true,
 		Commands: []*cli.Command{
 			{
 				Name:   "login",
@@ -38,24 +41,66 @@ func main() {
 				Action: infoAction,
 			},
 			{
-				Name:  "ls",
-				Usage: "列出目录内容",
+				Name:      "ls",
+				Usage:     "列出目录内容",
+				ArgsUsage: "[路径或通配符，如 '*.csv']",
+				// -h 用于显示易读的大小，与 GNU ls 一致，帮助只保留 --help
+				HideHelp: true,
 				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "help",
+						Usage: "显示帮助",
+					},
+					&cli.BoolFlag{
+						Name:  "l",
+						Usage: "长格式: 存储类型、锁定状态、大小、修改时间、ETag 和 Content-Type",
+					},
+					&cli.BoolFlag{
+						Name:  "h",
+						Usage: "以易读格式显示大小 (默认显示字节数)",
+					},
+					&cli.BoolFlag{
+						Name:  "R",
+						Usage: "递归列出子目录",
+					},
 					&cli.BoolFlag{
 						Name:  "d",
 						Usage: "仅显示目录",
 					},
+					&cli.StringFlag{
+						Name:  "sort",
+						Usage: "排序方式: name、size (从大到小)、time (从新到旧) 或 ext，除 name 外需要读取整个目录",
+						Value: "name",
+					},
 					&cli.BoolFlag{
 						Name:  "r",
-						Usage: "按修改时间倒序排列",
+						Usage: "按修改时间从新到旧排列，等同于 --sort time",
+					},
+					&cli.BoolFlag{
+						Name:  "reverse",
+						Usage: "倒序排列",
 					},
 					&cli.BoolFlag{
 						Name:  "color",
-						Usage: "彩色输出",
+						Usage: "按类型彩色输出，颜色取自 LS_COLORS",
 					},
 					&cli.IntFlag{
-						Name:  "c",
-						Usage: "显示前 N 个文件或目录",
+						Name:    "limit",
+						Aliases: []string{"c"},
+						Usage:   "最多显示 N 个文件或目录 (每页的大小)",
+					},
+					&cli.IntFlag{
+						Name:  "page",
+						Usage: "显示第 N 页，需要与 --limit 一起使用",
+						Value: 1,
+					},
+					&cli.StringFlag{
+						Name:  "start-after",
+						Usage: "从指定名称之后开始列出，用于翻页",
+					},
+					&cli.BoolFlag{
+						Name:  "orig",
+						Usage: "同时显示压缩或加密前的原始大小",
 					},
 				},
 				Action: lsAction,
@@ -76,19 +121,122 @@ func main() {
 				Action: mkdirAction,
 			},
 			{
-				Name:   "tree",
-				Usage:  "显示目录结构",
+				Name:  "tree",
+				Usage: "显示目录结构",
+				Flags: []cli.Flag{
+					&cli.IntFlag{
+						Name:  "depth",
+						Usage: "最多显示 N 层 (默认不限)",
+					},
+				},
 				Action: treeAction,
 			},
 			{
-				Name:  "get",
-				Usage: "下载文件或目录",
+				Name:      "du",
+				Usage:     "统计目录的空间占用",
+				ArgsUsage: "[目录]...",
 				Flags: []cli.Flag{
 					&cli.IntFlag{
+						Name:  "depth",
+						Usage: "按子目录分组显示的最大层数",
+						Value: 1,
+					},
+					&cli.StringFlag{
+						Name:  "sort",
+						Usage: "排序方式: name、size 或 count",
+						Value: "name",
+					},
+					&cli.BoolFlag{
+						Name:    "summarize",
+						Aliases: []string{"s"},
+						Usage:   "只显示总量",
+					},
+					&cli.BoolFlag{
+						Name:  "versions",
+						Usage: "包含非当前版本和删除标记",
+					},
+					&cli.BoolFlag{
+						Name:  "uploads",
+						Usage: "包含未完成的分片上传",
+					},
+					&cli.BoolFlag{
+						Name:  "json",
+						Usage: "以 JSON 格式输出，每行一个目录",
+					},
+				},
+				Action: duAction,
+			},
+			{
+				Name:      "find",
+				Usage:     "按名称、大小、时间、元数据和标签查找文件，并对结果执行操作",
+				ArgsUsage: "[目录]...",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "name",
+						Usage: "名称通配符，含 / 时匹配完整路径，否则匹配文件名",
+					},
+					&cli.BoolFlag{
+						Name:  "regex",
+						Usage: "将 --name 作为正则表达式匹配完整路径",
+					},
+					&cli.StringSliceFlag{
+						Name:  "size",
+						Usage: "大小条件: +100M 大于、-1K 小于、10M 等于，可多次指定",
+					},
+					&cli.StringFlag{
+						Name:  "newer",
+						Usage: "修改时间晚于，例如 7d、12h 或 2024-01-01",
+					},
+					&cli.StringFlag{
+						Name:  "older",
+						Usage: "修改时间早于，例如 30d 或 2024-01-01",
+					},
+					&cli.StringFlag{
+						Name:  "type",
+						Usage: "f 查找文件 (默认)，d 查找目录",
+					},
+					&cli.StringSliceFlag{
+						Name:  "meta",
+						Usage: "用户元数据条件 key=value，值支持通配符，可多次指定",
+					},
+					&cli.StringSliceFlag{
+						Name:  "tag",
+						Usage: "标签条件 key=value，值支持通配符，可多次指定",
+					},
+					&cli.BoolFlag{
+						Name:  "print",
+						Usage: "输出匹配的路径 (未指定其他操作时默认)",
+					},
+					&cli.BoolFlag{
+						Name:  "print0",
+						Usage: "输出匹配的路径，以 NUL 分隔",
+					},
+					&cli.StringFlag{
+						Name:  "exec",
+						Usage: "对每个匹配执行命令，{} 替换为远程路径，参数按 shell 规则用空白分隔、支持引号，例如 --exec 'cp {} \"/tmp/my dir/\"'",
+					},
+					&cli.BoolFlag{
+						Name:  "delete",
+						Usage: "删除匹配的文件",
+					},
+					&cli.StringFlag{
+						Name:  "get",
+						Usage: "将匹配的文件下载到指定目录，保持相对路径",
+					},
+					sseFlag(),
+				},
+				Before: setupRateLimiter,
+				Action: findAction,
+			},
+			{
+				Name:  "get",
+				Usage: "下载文件或目录",
+				Flags: append([]cli.Flag{
+					&cli.StringFlag{
 						Name:    "w",
 						Aliases: []string{"workers"},
-						Usage:   "并发下载线程数 (1-10)",
-						Value:   5,
+						Usage:   "并发下载线程数 (1-64，或 auto 根据吞吐量自动调整)",
+						Value:   "5",
 					},
 					&cli.BoolFlag{
 						Name:    "c",
@@ -103,35 +251,180 @@ func main() {
 						Name:  "end",
 						Usage: "结束文件名（按字典序）",
 					},
-				},
+					&cli.StringFlag{
+						Name:  "range",
+						Usage: "输出到标准输出时只读取指定字节范围，例如 0-1023、1M-、-512",
+					},
+					&cli.StringFlag{
+						Name:  "offset",
+						Usage: "从指定偏移量开始读取",
+					},
+					&cli.StringFlag{
+						Name:  "length",
+						Usage: "读取的字节数",
+					},
+					sseFlag(),
+				}, decryptFlags()...),
+				Before: setupRateLimiter,
 				Action: getAction,
 			},
 			{
 				Name:  "put",
 				Usage: "上传文件或目录",
-				Flags: []cli.Flag{
-					&cli.IntFlag{
+				Flags: append([]cli.Flag{
+					&cli.StringFlag{
 						Name:    "w",
 						Aliases: []string{"workers"},
-						Usage:   "并发上传线程数 (1-10)",
-						Value:   5,
+						Usage:   "并发上传线程数 (1-64，或 auto 根据吞吐量自动调整)",
+						Value:   "5",
 					},
 					&cli.BoolFlag{
 						Name:  "all",
 						Usage: "包含隐藏文件和目录",
 					},
-				},
+					&cli.StringFlag{
+						Name:  "part-size",
+						Usage: "从标准输入上传时的分片大小 (至少 5MiB，最多 10000 个分片)",
+						Value: "16MiB",
+					},
+				}, uploadFlags()...),
+				Before: setupRateLimiter,
 				Action: putAction,
 			},
+			{
+				Name:  "cat",
+				Usage: "将文件内容输出到标准输出",
+				Flags: append([]cli.Flag{
+					&cli.StringFlag{
+						Name:  "range",
+						Usage: "只读取指定字节范围，例如 0-1023、1M-、-512",
+					},
+					&cli.StringFlag{
+						Name:  "offset",
+						Usage: "从指定偏移量开始读取",
+					},
+					&cli.StringFlag{
+						Name:  "length",
+						Usage: "读取的字节数",
+					},
+					sseFlag(),
+				}, decryptFlags()...),
+				Before: setupRateLimiter,
+				Action: catAction,
+			},
+			{
+				Name:  "stat",
+				Usage: "显示文件的完整元数据",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:    "r",
+						Aliases: []string{"recursive"},
+						Usage:   "显示目录下所有文件的元数据",
+					},
+					&cli.BoolFlag{
+						Name:  "json",
+						Usage: "以 JSON 格式输出 (每行一个对象)",
+					},
+					sseFlag(),
+				},
+				Action: statAction,
+			},
+			{
+				Name:      "setmeta",
+				Usage:     "修改文件的内容头、用户元数据和存储类型",
+				ArgsUsage: "<文件|目录|通配符>...",
+				Flags: append([]cli.Flag{
+					&cli.BoolFlag{
+						Name:    "r",
+						Aliases: []string{"recursive"},
+						Usage:   "修改目录下的所有文件",
+					},
+					&cli.BoolFlag{
+						Name:  "replace",
+						Usage: "用 --meta 指定的内容替换全部用户元数据 (默认合并)",
+					},
+					&cli.StringSliceFlag{
+						Name:  "rm-meta",
+						Usage: "删除指定的用户元数据，可多次指定",
+					},
+				}, transferFlags()...),
+				Action: setmetaAction,
+			},
+			{
+				Name:      "fix-content-types",
+				Usage:     "按文件名重新识别并修复已上传文件的 Content-Type",
+				ArgsUsage: "[目录]",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "force",
+						Usage: "同时修复已设置但与识别结果不同的类型 (默认只修复 application/octet-stream)",
+					},
+					&cli.BoolFlag{
+						Name:  "sniff",
+						Usage: "扩展名无法识别时读取文件开头内容进行识别",
+						Value: true,
+					},
+				},
+				Action: fixContentTypesAction,
+			},
+			{
+				Name:  "tag",
+				Usage: "管理文件标签",
+				Subcommands: []*cli.Command{
+					{
+						Name:      "set",
+						Usage:     "设置标签 (默认与已有标签合并)",
+						ArgsUsage: "<文件|目录|通配符> key=value...",
+						Flags: []cli.Flag{
+							&cli.BoolFlag{
+								Name:    "r",
+								Aliases: []string{"recursive"},
+								Usage:   "修改目录下的所有文件",
+							},
+							&cli.BoolFlag{
+								Name:  "replace",
+								Usage: "替换全部已有标签",
+							},
+						},
+						Action: tagSetAction,
+					},
+					{
+						Name:      "get",
+						Usage:     "显示标签",
+						ArgsUsage: "<文件|目录|通配符>...",
+						Flags: []cli.Flag{
+							&cli.BoolFlag{
+								Name:    "r",
+								Aliases: []string{"recursive"},
+								Usage:   "显示目录下所有文件的标签",
+							},
+						},
+						Action: tagGetAction,
+					},
+					{
+						Name:      "rm",
+						Usage:     "删除指定的标签，未指定键时删除全部标签",
+						ArgsUsage: "<文件|目录|通配符> [key...]",
+						Flags: []cli.Flag{
+							&cli.BoolFlag{
+								Name:    "r",
+								Aliases: []string{"recursive"},
+								Usage:   "修改目录下的所有文件",
+							},
+						},
+						Action: tagRmAction,
+					},
+				},
+			},
 			{
 				Name:  "upload",
 				Usage: "上传多个文件或目录",
-				Flags: []cli.Flag{
-					&cli.IntFlag{
+				Flags: append([]cli.Flag{
+					&cli.StringFlag{
 						Name:    "w",
 						Aliases: []string{"workers"},
-						Usage:   "并发上传线程数 (1-10)",
-						Value:   5,
+						Usage:   "并发上传线程数 (1-64，或 auto 根据吞吐量自动调整)",
+						Value:   "5",
 					},
 					&cli.BoolFlag{
 						Name:  "all",
@@ -145,7 +438,8 @@ func main() {
 						Name:  "err-log",
 						Usage: "错误日志文件",
 					},
-				},
+				}, uploadFlags()...),
+				Before: setupRateLimiter,
 				Action: uploadAction,
 			},
 			{
@@ -162,7 +456,11 @@ func main() {
 					},
 					&cli.BoolFlag{
 						Name:  "async",
-						Usage: "异步删除",
+						Usage: "作为后台任务删除，关闭终端后继续执行 (见 job 命令)",
+					},
+					&cli.BoolFlag{
+						Name:  "bypass-governance",
+						Usage: "删除处于治理模式保留期内的对象 (需要相应权限)",
 					},
 				},
 				Action: rmAction,
@@ -175,6 +473,11 @@ func main() {
 						Name:  "f",
 						Usage: "允许覆盖目标文件",
 					},
+					sseFlag(),
+					&cli.StringFlag{
+						Name:  "sse-src",
+						Usage: "读取 SSE-C 加密的源文件时使用的密钥 (c:<密钥文件>)，默认与 --sse 相同",
+					},
 				},
 				Action: mvAction,
 			},
@@ -186,26 +489,657 @@ func main() {
 						Name:  "f",
 						Usage: "允许覆盖目标文件",
 					},
+					sseFlag(),
+					&cli.StringFlag{
+						Name:  "sse-src",
+						Usage: "读取 SSE-C 加密的源文件时使用的密钥 (c:<密钥文件>)，默认与 --sse 相同",
+					},
 				},
 				Action: cpAction,
 			},
 			{
 				Name:  "sync",
 				Usage: "同步本地目录到远程",
-				Flags: []cli.Flag{
-					&cli.IntFlag{
+				Flags: append([]cli.Flag{
+					&cli.StringFlag{
 						Name:    "w",
 						Aliases: []string{"workers"},
-						Usage:   "并发线程数",
-						Value:   5,
+						Usage:   "并发线程数 (1-64，或 auto 根据吞吐量自动调整)",
+						Value:   "5",
 					},
 					&cli.BoolFlag{
 						Name:  "delete",
 						Usage: "删除本地不存在的远程文件",
 					},
-				},
+					&cli.BoolFlag{
+						Name:  "watch",
+						Usage: "完整同步后持续监视本地目录，上传新建和修改的文件，重命名在服务端移动 (需要 --delete，否则为复制)",
+					},
+					&cli.DurationFlag{
+						Name:  "settle",
+						Usage: "监视时文件在多长时间内没有写入才上传",
+						Value: 2 * time.Second,
+					},
+					&cli.DurationFlag{
+						Name:  "reconcile",
+						Usage: "监视时定期完整同步的间隔，0 表示不定期同步",
+						Value: 10 * time.Minute,
+					},
+				}, uploadFlags()...),
+				Before: setupRateLimiter,
 				Action: syncAction,
 			},
+			{
+				Name:      "diff",
+				Usage:     "比较本地目录与远程目录，或两个远程目录 (有差异时退出码为 1，出错时为 2)",
+				ArgsUsage: "<左侧路径> <右侧路径> (本地路径以 ./、../、~/ 开头或使用 file: 前缀)",
+				Flags: append([]cli.Flag{
+					&cli.BoolFlag{
+						Name:  "checksum",
+						Usage: "按内容比较而不是修改时间 (ETag 不是 MD5 时需要下载对象)",
+					},
+					&cli.StringFlag{
+						Name:    "w",
+						Aliases: []string{"workers"},
+						Usage:   "并发线程数 (1-64，或 auto 根据吞吐量自动调整)",
+						Value:   "5",
+					},
+					sseFlag(),
+				}, decryptFlags()...),
+				Before: setupRateLimiter,
+				Action: diffAction,
+			},
+			{
+				Name:      "watch",
+				Usage:     "监听远程目录的上传、删除等事件 (需要 MinIO 服务端)",
+				ArgsUsage: "[目录]",
+				Flags: append([]cli.Flag{
+					&cli.StringFlag{
+						Name:  "events",
+						Usage: "监听的事件，逗号分隔: put、delete、get，或完整的事件名如 s3:ObjectCreated:Copy",
+						Value: "put,delete",
+					},
+					&cli.StringFlag{
+						Name:  "suffix",
+						Usage: "只监听指定后缀的文件，例如 .csv",
+					},
+					&cli.StringFlag{
+						Name:  "exec",
+						Usage: "对每个事件执行命令，支持 {key}、{event}、{size}、{etag}、{local} 占位符，没有占位符时追加文件路径",
+					},
+					&cli.StringFlag{
+						Name:  "auto-get",
+						Usage: "将新建的文件下载到本地目录，保留相对于监听目录的路径",
+					},
+					&cli.BoolFlag{
+						Name:  "json",
+						Usage: "以 JSON 格式逐行输出事件",
+					},
+					sseFlag(),
+				}, decryptFlags()...),
+				Before: setupRateLimiter,
+				Action: watchAction,
+			},
+			{
+				Name:  "event",
+				Usage: "管理当前存储桶的事件通知规则 (webhook、AMQP、Kafka 等)",
+				Subcommands: []*cli.Command{
+					{
+						Name:      "add",
+						Usage:     "添加通知规则",
+						ArgsUsage: "<ARN，例如 arn:minio:sqs::primary:webhook>",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "events",
+								Usage: "触发通知的事件，逗号分隔: put、delete、get，或完整的事件名如 s3:ObjectCreated:Copy",
+								Value: "put,delete",
+							},
+							&cli.StringFlag{
+								Name:  "prefix",
+								Usage: "只通知指定前缀的对象 (从存储桶根目录开始)",
+							},
+							&cli.StringFlag{
+								Name:  "suffix",
+								Usage: "只通知指定后缀的对象，例如 .csv",
+							},
+							&cli.StringFlag{
+								Name:  "id",
+								Usage: "规则 ID，未指定时由服务端生成",
+							},
+						},
+						Action: eventAddAction,
+					},
+					{
+						Name:  "ls",
+						Usage: "列出通知规则",
+						Flags: []cli.Flag{
+							&cli.BoolFlag{
+								Name:  "json",
+								Usage: "以 JSON 格式输出",
+							},
+						},
+						Action: eventLsAction,
+					},
+					{
+						Name:      "rm",
+						Usage:     "删除指定 ARN 或 ID 的通知规则",
+						ArgsUsage: "<ARN|ID>",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "events",
+								Usage: "只删除事件完全相同的规则",
+							},
+							&cli.StringFlag{
+								Name:  "prefix",
+								Usage: "只删除前缀相同的规则",
+							},
+							&cli.StringFlag{
+								Name:  "suffix",
+								Usage: "只删除后缀相同的规则",
+							},
+							&cli.BoolFlag{
+								Name:  "all",
+								Usage: "删除全部通知规则",
+							},
+						},
+						Action: eventRmAction,
+					},
+					{
+						Name:      "export",
+						Usage:     "以 JSON 格式导出全部通知规则",
+						ArgsUsage: "[文件，默认输出到标准输出]",
+						Action:    eventExportAction,
+					},
+					{
+						Name:      "import",
+						Usage:     "从 JSON 文件导入通知规则，默认替换全部已有规则",
+						ArgsUsage: "<文件|->",
+						Flags: []cli.Flag{
+							&cli.BoolFlag{
+								Name:  "merge",
+								Usage: "添加到已有规则中，而不是替换",
+							},
+						},
+						Action: eventImportAction,
+					},
+				},
+			},
+			{
+				Name:  "ilm",
+				Usage: "管理当前存储桶的生命周期规则 (过期、转换到其他存储层级)",
+				Subcommands: []*cli.Command{
+					{
+						Name:  "add",
+						Usage: "添加生命周期规则",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "id",
+								Usage: "规则 ID，未指定时自动生成",
+							},
+							&cli.StringFlag{
+								Name:  "prefix",
+								Usage: "只作用于指定前缀的对象 (从存储桶根目录开始)",
+							},
+							&cli.StringSliceFlag{
+								Name:  "tag",
+								Usage: "只作用于带有指定标签的对象 key=value，可多次指定",
+							},
+							&cli.IntFlag{
+								Name:  "expire-days",
+								Usage: "对象创建指定天数后过期",
+							},
+							&cli.StringFlag{
+								Name:  "expire-date",
+								Usage: "对象在指定日期过期 (YYYY-MM-DD)",
+							},
+							&cli.BoolFlag{
+								Name:  "expire-delete-marker",
+								Usage: "删除没有旧版本的删除标记",
+							},
+							&cli.IntFlag{
+								Name:  "noncurrent-expire-days",
+								Usage: "旧版本在成为旧版本指定天数后过期",
+							},
+							&cli.IntFlag{
+								Name:  "newer-noncurrent-versions",
+								Usage: "旧版本过期时保留的最新旧版本数",
+							},
+							&cli.IntFlag{
+								Name:  "transition-days",
+								Usage: "对象创建指定天数后转换到 --storage-class",
+							},
+							&cli.StringFlag{
+								Name:  "transition-date",
+								Usage: "对象在指定日期转换到 --storage-class (YYYY-MM-DD)",
+							},
+							&cli.IntFlag{
+								Name:  "noncurrent-transition-days",
+								Usage: "旧版本在成为旧版本指定天数后转换到 --storage-class",
+							},
+							&cli.StringFlag{
+								Name:  "storage-class",
+								Usage: "转换的目标存储类别，MinIO 中为远程层级的名称",
+							},
+							&cli.IntFlag{
+								Name:  "abort-upload-days",
+								Usage: "清理发起超过指定天数仍未完成的分片上传",
+							},
+							&cli.BoolFlag{
+								Name:  "disable",
+								Usage: "添加为停用状态的规则",
+							},
+						},
+						Action: ilmAddAction,
+					},
+					{
+						Name:  "ls",
+						Usage: "以表格形式列出生命周期规则",
+						Flags: []cli.Flag{
+							&cli.BoolFlag{
+								Name:  "json",
+								Usage: "以 JSON 格式输出",
+							},
+						},
+						Action: ilmLsAction,
+					},
+					{
+						Name:      "rm",
+						Usage:     "删除指定 ID 的生命周期规则",
+						ArgsUsage: "<ID>...",
+						Flags: []cli.Flag{
+							&cli.BoolFlag{
+								Name:  "all",
+								Usage: "删除全部生命周期规则",
+							},
+						},
+						Action: ilmRmAction,
+					},
+					{
+						Name:      "export",
+						Usage:     "以 JSON 格式导出全部生命周期规则 (与 mc ilm rule export 格式相同)",
+						ArgsUsage: "[文件，默认输出到标准输出]",
+						Action:    ilmExportAction,
+					},
+					{
+						Name:      "import",
+						Usage:     "从 JSON 文件导入生命周期规则，默认替换全部已有规则",
+						ArgsUsage: "<文件|->",
+						Flags: []cli.Flag{
+							&cli.BoolFlag{
+								Name:  "merge",
+								Usage: "按 ID 合并到已有规则中，而不是替换",
+							},
+						},
+						Action: ilmImportAction,
+					},
+				},
+			},
+			{
+				Name:  "uploads",
+				Usage: "管理未完成的分片上传",
+				Subcommands: []*cli.Command{
+					{
+						Name:      "ls",
+						Usage:     "列出未完成的分片上传: 发起时间、分片数、已上传大小",
+						ArgsUsage: "[前缀，默认当前目录]",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "older-than",
+								Usage: "只列出发起时间早于指定时间的上传，例如 7d、12h 或 2024-01-01",
+							},
+							&cli.BoolFlag{
+								Name:  "id",
+								Usage: "显示上传 ID",
+							},
+						},
+						Action: uploadsLsAction,
+					},
+					{
+						Name:      "rm",
+						Usage:     "中止未完成的分片上传并释放已上传分片的空间",
+						ArgsUsage: "<前缀>",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "older-than",
+								Usage: "只中止发起时间早于指定时间的上传，例如 7d、12h 或 2024-01-01",
+							},
+						},
+						Action: uploadsRmAction,
+					},
+				},
+			},
+			{
+				Name:  "retention",
+				Usage: "管理对象的保留期 (对象锁定)",
+				Subcommands: []*cli.Command{
+					{
+						Name:      "set",
+						Usage:     "设置对象的保留期，--default 时设置存储桶的默认保留规则",
+						ArgsUsage: "<文件|目录|通配符>...",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "mode",
+								Usage: "保留模式: governance 或 compliance",
+							},
+							&cli.StringFlag{
+								Name:  "validity",
+								Usage: "保留时长，例如 30d、1y",
+							},
+							&cli.BoolFlag{
+								Name:    "r",
+								Aliases: []string{"recursive"},
+								Usage:   "处理目录下的所有文件",
+							},
+							&cli.BoolFlag{
+								Name:  "default",
+								Usage: "设置存储桶的默认保留规则",
+							},
+							&cli.BoolFlag{
+								Name:  "bypass-governance",
+								Usage: "允许缩短治理模式的保留期",
+							},
+						},
+						Action: retentionSetAction,
+					},
+					{
+						Name:      "get",
+						Usage:     "显示对象的保留期，--default 时显示存储桶的默认保留规则",
+						ArgsUsage: "<文件|目录|通配符>...",
+						Flags: []cli.Flag{
+							&cli.BoolFlag{
+								Name:    "r",
+								Aliases: []string{"recursive"},
+								Usage:   "处理目录下的所有文件",
+							},
+							&cli.BoolFlag{
+								Name:  "default",
+								Usage: "显示存储桶的默认保留规则",
+							},
+						},
+						Action: retentionGetAction,
+					},
+					{
+						Name:      "clear",
+						Usage:     "清除治理模式的保留期，--default 时清除存储桶的默认保留规则",
+						ArgsUsage: "<文件|目录|通配符>...",
+						Flags: []cli.Flag{
+							&cli.BoolFlag{
+								Name:    "r",
+								Aliases: []string{"recursive"},
+								Usage:   "处理目录下的所有文件",
+							},
+							&cli.BoolFlag{
+								Name:  "default",
+								Usage: "清除存储桶的默认保留规则",
+							},
+						},
+						Action: retentionClearAction,
+					},
+				},
+			},
+			{
+				Name:  "legalhold",
+				Usage: "管理对象的法律保留",
+				Subcommands: []*cli.Command{
+					{
+						Name:      "set",
+						Usage:     "设置法律保留，设置后对象无法删除或覆盖",
+						ArgsUsage: "<文件|目录|通配符>...",
+						Flags: []cli.Flag{
+							&cli.BoolFlag{
+								Name:    "r",
+								Aliases: []string{"recursive"},
+								Usage:   "处理目录下的所有文件",
+							},
+						},
+						Action: legalholdSetAction,
+					},
+					{
+						Name:      "clear",
+						Usage:     "清除法律保留",
+						ArgsUsage: "<文件|目录|通配符>...",
+						Flags: []cli.Flag{
+							&cli.BoolFlag{
+								Name:    "r",
+								Aliases: []string{"recursive"},
+								Usage:   "处理目录下的所有文件",
+							},
+						},
+						Action: legalholdClearAction,
+					},
+					{
+						Name:      "info",
+						Usage:     "显示对象的法律保留状态",
+						ArgsUsage: "<文件|目录|通配符>...",
+						Flags: []cli.Flag{
+							&cli.BoolFlag{
+								Name:    "r",
+								Aliases: []string{"recursive"},
+								Usage:   "处理目录下的所有文件",
+							},
+						},
+						Action: legalholdInfoAction,
+					},
+				},
+			},
+			{
+				Name:  "trash",
+				Usage: "管理回收站: 启用后 rm 和覆盖操作会先把对象移到回收站",
+				Subcommands: []*cli.Command{
+					{
+						Name:      "enable",
+						Usage:     "为当前会话启用回收站",
+						ArgsUsage: "[前缀，默认 " + defaultTrashPrefix + "]",
+						Action:    trashEnableAction,
+					},
+					{
+						Name:   "disable",
+						Usage:  "停用当前会话的回收站，已有的对象保留",
+						Action: trashDisableAction,
+					},
+					{
+						Name:  "ls",
+						Usage: "列出回收站中的对象: 删除时间、大小、原路径",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "older-than",
+								Usage: "只列出删除时间早于指定时间的对象，例如 7d、12h 或 2024-01-01",
+							},
+						},
+						Action: trashLsAction,
+					},
+					{
+						Name:      "restore",
+						Usage:     "将对象还原到原路径，同一路径删除过多次时还原最近的一次",
+						ArgsUsage: "<原路径|目录>...",
+						Flags: []cli.Flag{
+							&cli.BoolFlag{
+								Name:  "f",
+								Usage: "覆盖已存在的对象",
+							},
+						},
+						Action: trashRestoreAction,
+					},
+					{
+						Name:  "empty",
+						Usage: "永久删除回收站中的对象",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "older-than",
+								Usage: "只删除删除时间早于指定时间的对象，例如 30d 或 2024-01-01",
+							},
+						},
+						Action: trashEmptyAction,
+					},
+				},
+			},
+			{
+				Name:  "undo",
+				Usage: "撤销当前会话最近一次 mv 或 rm (rm 需要启用回收站)",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "list",
+						Usage: "列出可以撤销的操作",
+					},
+				},
+				Action: undoAction,
+			},
+			{
+				Name:  "job",
+				Usage: "在后台运行耗时的命令 (get、upload、sync、rm 等)，关闭终端后继续执行",
+				Subcommands: []*cli.Command{
+					{
+						Name:            "start",
+						Usage:           "启动后台任务，全局选项写在 job 之前",
+						ArgsUsage:       "<命令> [参数...]",
+						SkipFlagParsing: true,
+						Action:          jobStartAction,
+					},
+					{
+						Name:   "ls",
+						Usage:  "列出后台任务",
+						Action: jobLsAction,
+					},
+					{
+						Name:      "status",
+						Usage:     "查看任务的状态、运行次数和检查点",
+						ArgsUsage: "<任务 ID>",
+						Action:    jobStatusAction,
+					},
+					{
+						Name:      "logs",
+						Usage:     "查看任务的输出",
+						ArgsUsage: "<任务 ID>",
+						Flags: []cli.Flag{
+							&cli.BoolFlag{
+								Name:  "f",
+								Usage: "持续输出直到任务结束",
+							},
+						},
+						Action: jobLogsAction,
+					},
+					{
+						Name:      "pause",
+						Usage:     "暂停任务",
+						ArgsUsage: "<任务 ID>",
+						Action:    jobPauseAction,
+					},
+					{
+						Name:      "resume",
+						Usage:     "继续已暂停的任务，或从检查点重新运行已中断 (例如重启后) 或失败的任务",
+						ArgsUsage: "<任务 ID>",
+						Flags: []cli.Flag{
+							&cli.BoolFlag{
+								Name:  "all",
+								Usage: "恢复所有已暂停和已中断的任务，可以放在开机脚本中",
+							},
+						},
+						Action: jobResumeAction,
+					},
+					{
+						Name:      "cancel",
+						Usage:     "取消任务",
+						ArgsUsage: "<任务 ID>",
+						Action:    jobCancelAction,
+					},
+					{
+						Name:   "run",
+						Usage:  "运行任务 (由 job start 在后台调用)",
+						Hidden: true,
+						Action: jobRunAction,
+					},
+				},
+			},
+			{
+				Name:  "policy",
+				Usage: "管理当前存储桶的访问策略 (JSON)",
+				Subcommands: []*cli.Command{
+					{
+						Name:      "get",
+						Usage:     "输出存储桶策略",
+						ArgsUsage: "[文件，默认输出到标准输出]",
+						Action:    policyGetAction,
+					},
+					{
+						Name:      "set",
+						Usage:     "检查并设置存储桶策略，替换已有策略",
+						ArgsUsage: "<文件|->",
+						Action:    policySetAction,
+					},
+					{
+						Name:   "rm",
+						Usage:  "删除存储桶策略",
+						Action: policyRmAction,
+					},
+				},
+			},
+			{
+				Name:  "anonymous",
+				Usage: "管理前缀的匿名访问权限",
+				Subcommands: []*cli.Command{
+					{
+						Name:      "set",
+						Usage:     "设置前缀的匿名访问权限: download 只读、upload 只写、public 读写、none 禁止",
+						ArgsUsage: "<download|upload|public|none> [前缀，默认当前目录]",
+						Action:    anonymousSetAction,
+					},
+					{
+						Name:   "ls",
+						Usage:  "列出允许匿名访问的前缀",
+						Action: anonymousLsAction,
+					},
+				},
+			},
+			{
+				Name:  "sse",
+				Usage: "管理当前会话的服务端加密设置",
+				Subcommands: []*cli.Command{
+					{
+						Name:   "show",
+						Usage:  "显示默认加密方式和前缀规则",
+						Action: sseShowAction,
+					},
+					{
+						Name:      "default",
+						Usage:     "设置默认加密方式",
+						ArgsUsage: "<s3|kms:<密钥ID>|c:<密钥文件>|off>",
+						Action:    sseDefaultAction,
+					},
+					{
+						Name:      "rule",
+						Usage:     "要求指定前缀下的文件始终以指定方式加密",
+						ArgsUsage: "<前缀> <s3|kms:<密钥ID>|c:<密钥文件>|off>",
+						Action:    sseRuleAction,
+					},
+				},
+			},
+			{
+				Name:  "keygen",
+				Usage: "生成客户端加密使用的密钥对",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "o",
+						Usage: "私钥保存路径 (默认 ~/.minx/identity)",
+					},
+					&cli.BoolFlag{
+						Name:  "f",
+						Usage: "身份文件已存在时追加新的密钥",
+					},
+				},
+				Action: keygenAction,
+			},
+			{
+				Name:   "apply",
+				Usage:  "执行 --plan-out 保存的计划",
+				Before: setupRateLimiter,
+				Action: applyAction,
+			},
+			{
+				Name:   "retry",
+				Usage:  "重试失败记录中的操作",
+				Before: setupRateLimiter,
+				Action: retryAction,
+			},
 			{
 				Name:   "auth",
 				Usage:  "生成认证字符串",
@@ -222,6 +1156,37 @@ func main() {
 				Name:  "auth",
 				Usage: "认证字符串 (endpoint:accessKey:secretKey:bucketName)",
 			},
+			&cli.BoolFlag{
+				Name:  "dry-run",
+				Usage: "仅显示将要执行的操作，不做任何修改",
+			},
+			&cli.StringFlag{
+				Name:  "plan-out",
+				Usage: "将 dry-run 计划保存到文件 (隐含 --dry-run)",
+			},
+			&cli.IntFlag{
+				Name:  "retries",
+				Usage: "可重试错误 (超时、5xx、限流、连接中断) 的最大重试次数",
+				Value: 3,
+			},
+			&cli.DurationFlag{
+				Name:  "retry-delay",
+				Usage: "首次重试前的等待时间，之后按指数增长",
+				Value: time.Second,
+			},
+			&cli.DurationFlag{
+				Name:  "retry-max-delay",
+				Usage: "单次重试等待时间上限",
+				Value: 30 * time.Second,
+			},
+			&cli.StringFlag{
+				Name:  "limit-rate",
+				Usage: "限制所有传输的总带宽，例如 20MiB/s (未指定时使用配置中的 rate_schedules)",
+			},
+			&cli.StringFlag{
+				Name:  "failures-out",
+				Usage: "失败记录文件 (默认 ~/.minx/failures/<命令>-<时间>.json)",
+			},
 		},
 	}
 
@@ -0,0 +1,691 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/tags"
+	"github.com/urfave/cli/v2"
+	"minx/wildcard"
+)
+
+// 单次 CopyObject 能复制的最大对象大小
+const maxCopyObjectSize = 5 * 1024 * 1024 * 1024
+
+// TransferOptions 描述上传或修改元数据时要设置的内容头、用户元数据和标签，随计划和失败记录一起保存
+type TransferOptions struct {
+	ContentType        string            `json:"content_type,omitempty"`
+	CacheControl       string            `json:"cache_control,omitempty"`
+	ContentDisposition string            `json:"content_disposition,omitempty"`
+	ContentEncoding    string            `json:"content_encoding,omitempty"`
+	StorageClass       string            `json:"storage_class,omitempty"`
+	SSE                string            `json:"sse,omitempty"`        // 加密方式，SSE-C 只保存密钥文件路径
+	SSESource          string            `json:"sse_source,omitempty"` // 复制时读取源对象的 SSE-C 密钥
+	Metadata           map[string]string `json:"metadata,omitempty"`
+	Tags               map[string]string `json:"tags,omitempty"`
+	Encrypt            *EncryptOptions   `json:"encrypt,omitempty"`  // 客户端加密，下载时记录解密用的身份
+	Compress           string            `json:"compress,omitempty"` // 上传时压缩的算法
+
+	// 以下字段只用于修改已有对象
+	ReplaceMetadata bool     `json:"replace_metadata,omitempty"` // 用 Metadata 替换全部用户元数据，而不是合并
+	RemoveMetadata  []string `json:"remove_metadata,omitempty"`
+	ReplaceTags     bool     `json:"replace_tags,omitempty"` // 用 Tags 替换全部标签，而不是合并
+	RemoveTags      []string `json:"remove_tags,omitempty"`
+}
+
+// 上传相关命令共用的元数据参数
+func transferFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringSliceFlag{
+			Name:  "meta",
+			Usage: "设置用户元数据 key=value，可多次指定",
+		},
+		&cli.StringSliceFlag{
+			Name:  "tag",
+			Usage: "设置标签 key=value，可多次指定",
+		},
+		&cli.StringFlag{
+			Name:  "content-type",
+			Usage: "指定 Content-Type，默认根据文件扩展名判断",
+		},
+		&cli.StringFlag{
+			Name:  "cache-control",
+			Usage: "设置 Cache-Control",
+		},
+		&cli.StringFlag{
+			Name:  "content-disposition",
+			Usage: "设置 Content-Disposition",
+		},
+		&cli.StringFlag{
+			Name:  "content-encoding",
+			Usage: "设置 Content-Encoding",
+		},
+		&cli.StringFlag{
+			Name:  "storage-class",
+			Usage: "设置存储类型，例如 STANDARD、REDUCED_REDUNDANCY",
+		},
+		sseFlag(),
+	}
+}
+
+// put、upload、sync 的参数: 元数据参数加上压缩和客户端加密参数
+func uploadFlags() []cli.Flag {
+	return append(append(transferFlags(), compressFlag()), encryptFlags()...)
+}
+
+// 根据命令行参数构建元数据选项，未指定任何参数时返回 nil
+func transferOptionsFromContext(c *cli.Context) (*TransferOptions, error) {
+	meta, err := parseKeyValues(c.StringSlice("meta"), "元数据")
+	if err != nil {
+		return nil, err
+	}
+
+	tags, err := parseKeyValues(c.StringSlice("tag"), "标签")
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &TransferOptions{
+		ContentType:        c.String("content-type"),
+		CacheControl:       c.String("cache-control"),
+		ContentDisposition: c.String("content-disposition"),
+		ContentEncoding:    c.String("content-encoding"),
+		StorageClass:       c.String("storage-class"),
+		SSE:                c.String("sse"),
+		Metadata:           meta,
+		Tags:               tags,
+	}
+
+	if opts.SSE != "" {
+		if _, err := parseSSE(opts.SSE); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.Compress, err = parseCompression(c.String("compress")); err != nil {
+		return nil, err
+	}
+	if opts.Compress != "" && opts.ContentEncoding != "" {
+		return nil, fmt.Errorf("--compress 会设置 Content-Encoding，不能与 --content-encoding 同时使用")
+	}
+
+	if opts.Encrypt, err = encryptOptionsFromContext(c); err != nil {
+		return nil, err
+	}
+
+	// 上传时按覆盖规则确定 Content-Type，项目配置有误时直接报错
+	if _, err := loadContentTypeRules(); err != nil {
+		return nil, err
+	}
+
+	if opts.isEmpty() {
+		return nil, nil
+	}
+	return opts, nil
+}
+
+// 判断是否没有设置任何内容
+func (o *TransferOptions) isEmpty() bool {
+	return o == nil || (o.ContentType == "" && o.CacheControl == "" && o.ContentDisposition == "" &&
+		o.ContentEncoding == "" && o.StorageClass == "" && o.SSE == "" && len(o.Metadata) == 0 && len(o.Tags) == 0 && o.Encrypt == nil && o.Compress == "" &&
+		!o.ReplaceMetadata && len(o.RemoveMetadata) == 0 && !o.ReplaceTags && len(o.RemoveTags) == 0)
+}
+
+// 指定的加密方式，未指定时为空字符串
+func (o *TransferOptions) sseSpec() string {
+	if o == nil {
+		return ""
+	}
+	return o.SSE
+}
+
+// 复制时读取源对象使用的加密方式
+func (o *TransferOptions) sseSourceSpec() string {
+	if o == nil {
+		return ""
+	}
+	return o.SSESource
+}
+
+// 是否只修改标签，只修改标签时无需复制对象
+func (o *TransferOptions) tagsOnly() bool {
+	return o.ContentType == "" && o.CacheControl == "" && o.ContentDisposition == "" &&
+		o.ContentEncoding == "" && o.StorageClass == "" && o.SSE == "" && len(o.Metadata) == 0 &&
+		!o.ReplaceMetadata && len(o.RemoveMetadata) == 0
+}
+
+// 解析 key=value 列表
+func parseKeyValues(values []string, what string) (map[string]string, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	m := make(map[string]string, len(values))
+	for _, kv := range values {
+		key, value, ok := strings.Cut(kv, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("无效的%s '%s'，应为 key=value", what, kv)
+		}
+		m[key] = value
+	}
+
+	return m, nil
+}
+
+// 生成上传 objectName 的选项，contentType 为未指定 --content-type 时使用的类型
+// 即使未指定任何参数，也会应用会话的默认加密和前缀规则
+func (o *TransferOptions) putOptions(objectName, contentType string) (minio.PutObjectOptions, error) {
+	opts := minio.PutObjectOptions{ContentType: contentType}
+
+	sse, err := writeEncryption(o.sseSpec(), objectName)
+	if err != nil {
+		return opts, err
+	}
+	opts.ServerSideEncryption = sse
+
+	if o == nil {
+		return opts, nil
+	}
+
+	if o.ContentType != "" {
+		opts.ContentType = o.ContentType
+	}
+	opts.CacheControl = o.CacheControl
+	opts.ContentDisposition = o.ContentDisposition
+	opts.ContentEncoding = o.ContentEncoding
+	opts.StorageClass = o.StorageClass
+	opts.UserMetadata = o.Metadata
+	opts.UserTags = o.Tags
+
+	return opts, nil
+}
+
+// 生成上传 objectName 的数据流、长度和选项
+// 指定压缩时先压缩，压缩后的长度未知，使用分片上传；指定客户端加密时再加密，原始的 Content-Type 保存在元数据中
+// 进度统计应包在 r 上，这样显示的是原始字节数
+func (o *TransferOptions) prepareUpload(objectName, contentType string, r io.Reader, size int64) (io.Reader, int64, minio.PutObjectOptions, error) {
+	opts, err := o.putOptions(objectName, contentType)
+	if err != nil || o == nil || (o.Encrypt == nil && o.Compress == "") {
+		return r, size, opts, err
+	}
+
+	meta := make(map[string]string, len(opts.UserMetadata))
+	for k, v := range opts.UserMetadata {
+		meta[k] = v
+	}
+	opts.UserMetadata = meta
+
+	if o.Compress != "" {
+		reader, compressMeta, err := compressUpload(o.Compress, r, size)
+		if err != nil {
+			return nil, 0, opts, err
+		}
+		for k, v := range compressMeta {
+			meta[k] = v
+		}
+		r, size = reader, -1
+		opts.PartSize = defaultPartSize
+
+		// 加密后存储的是密文，不能声明 Content-Encoding，只通过元数据记录
+		if o.Encrypt == nil {
+			opts.ContentEncoding = o.Compress
+		}
+	}
+
+	if o.Encrypt != nil {
+		reader, cipherSize, cseMeta, err := encryptUpload(o.Encrypt, r, size, opts.ContentType)
+		if err != nil {
+			return nil, 0, opts, err
+		}
+		for k, v := range cseMeta {
+			meta[k] = v
+		}
+		r, size = reader, cipherSize
+		opts.ContentType = "application/octet-stream"
+	}
+
+	return r, size, opts, nil
+}
+
+// 修改已有对象的元数据和标签
+// 只修改标签时直接设置标签，否则通过复制到自身 (REPLACE 指令) 重写元数据，未修改的内容头会保留
+func updateObjectMetadata(ctx context.Context, client *minio.Client, bucket, objectName string, opts *TransferOptions) error {
+	return copyObjectWithMetadata(ctx, client, bucket, objectName, objectName, opts)
+}
+
+// 复制对象并按 opts 修改元数据和标签，source 与 target 相同时即为修改元数据
+func copyObjectWithMetadata(ctx context.Context, client *minio.Client, bucket, source, target string, opts *TransferOptions) error {
+	srcSSE, err := readEncryption(opts.SSE, source)
+	if err != nil {
+		return err
+	}
+
+	stat, err := statObject(ctx, client, bucket, source, srcSSE)
+	if err != nil {
+		return err
+	}
+
+	tagsChanged := opts.ReplaceTags || len(opts.Tags) > 0 || len(opts.RemoveTags) > 0
+	tagMap := mergeMap(stat.Tags, opts.Tags, opts.RemoveTags, opts.ReplaceTags)
+
+	if opts.tagsOnly() && source == target {
+		if len(tagMap) == 0 {
+			return client.RemoveObjectTagging(ctx, bucket, source, minio.RemoveObjectTaggingOptions{VersionID: stat.VersionID})
+		}
+
+		t, err := tags.MapToObjectTags(tagMap)
+		if err != nil {
+			return err
+		}
+		return client.PutObjectTagging(ctx, bucket, source, t, minio.PutObjectTaggingOptions{VersionID: stat.VersionID})
+	}
+
+	// REPLACE 指令会丢弃所有未提供的头，因此需要带上原有的内容头
+	meta := mergeMap(stat.UserMetadata, opts.Metadata, opts.RemoveMetadata, opts.ReplaceMetadata)
+	if meta == nil {
+		meta = make(map[string]string)
+	}
+	// 客户端加密的信封和压缩信息不能丢失，否则对象将无法还原
+	for k, v := range stat.UserMetadata {
+		if isContentMeta(k) {
+			meta[k] = v
+		}
+	}
+	for name, value := range stat.Headers {
+		meta[name] = value
+	}
+
+	contentType := stat.ContentType
+	if opts.ContentType != "" {
+		contentType = opts.ContentType
+	}
+	if contentType != "" {
+		meta["Content-Type"] = contentType
+	}
+	if opts.CacheControl != "" {
+		meta["Cache-Control"] = opts.CacheControl
+	}
+	if opts.ContentDisposition != "" {
+		meta["Content-Disposition"] = opts.ContentDisposition
+	}
+	if opts.ContentEncoding != "" {
+		meta["Content-Encoding"] = opts.ContentEncoding
+	}
+
+	storageClass := stat.StorageClass
+	if opts.StorageClass != "" {
+		storageClass = opts.StorageClass
+	}
+	if storageClass != "" {
+		meta["X-Amz-Storage-Class"] = storageClass
+	}
+
+	// 复制到自身时不带加密头会变成未加密对象，因此未指定新的加密方式时沿用原有的加密
+	dstSSE, err := writeEncryption(opts.SSE, target)
+	if err != nil {
+		return err
+	}
+	if dstSSE == nil {
+		dstSSE = stat.sse
+		if dstSSE == nil {
+			dstSSE = srcSSE
+		}
+	}
+
+	dst := minio.CopyDestOptions{
+		Bucket:          bucket,
+		Object:          target,
+		UserMetadata:    meta,
+		ReplaceMetadata: true,
+		Encryption:      dstSSE,
+	}
+	if tagsChanged {
+		dst.UserTags = tagMap
+		dst.ReplaceTags = true
+	}
+
+	src := minio.CopySrcOptions{
+		Bucket:     bucket,
+		Object:     source,
+		VersionID:  stat.VersionID,
+		Encryption: srcSSE,
+	}
+
+	// 单次 CopyObject 最多复制 5 GiB，更大的对象使用分段复制
+	// 分段复制不会沿用源对象的标签，需要显式带上
+	if stat.Size > maxCopyObjectSize {
+		dst.UserTags = tagMap
+		dst.ReplaceTags = true
+		_, err = client.ComposeObject(ctx, dst, src)
+		return err
+	}
+
+	_, err = client.CopyObject(ctx, dst, src)
+	return err
+}
+
+// 判断是否为 minx 写入的、还原对象内容所需的元数据
+func isContentMeta(key string) bool {
+	key = strings.ToLower(key)
+	return strings.HasPrefix(key, strings.ToLower(cseMetaVersion)) ||
+		key == strings.ToLower(compressMetaAlgorithm) || key == strings.ToLower(compressMetaOriginalSize)
+}
+
+// 合并键值对: replace 为 true 时丢弃原有内容，之后加入 set 并删除 remove 中的键
+func mergeMap(current, set map[string]string, remove []string, replace bool) map[string]string {
+	result := make(map[string]string)
+	if !replace {
+		for k, v := range current {
+			result[k] = v
+		}
+	}
+	for k, v := range set {
+		result[k] = v
+	}
+	for _, k := range remove {
+		delete(result, k)
+		// 用户元数据的键名大小写可能被服务端改写
+		for existing := range result {
+			if strings.EqualFold(existing, k) {
+				delete(result, existing)
+			}
+		}
+	}
+
+	if len(result) == 0 {
+		return nil
+	}
+	return result
+}
+
+// 获取路径匹配的所有对象: 含通配符时按模式匹配，recursive 时列出目录下所有文件，否则为单个文件
+func resolveObjects(ctx context.Context, client *minio.Client, bucket, objectName string, recursive bool) ([]minio.ObjectInfo, error) {
+	if strings.Contains(objectName, "*") {
+		prefix := objectName[:strings.Index(objectName, "*")]
+
+		var objects []minio.ObjectInfo
+		for object := range client.ListObjects(ctx, bucket, minio.ListObjectsOptions{
+			Prefix:    prefix,
+			Recursive: true,
+		}) {
+			if object.Err != nil {
+				return nil, fmt.Errorf("列出对象时出错: %w", object.Err)
+			}
+			if !strings.HasSuffix(object.Key, "/") && wildcard.Match(objectName, object.Key) {
+				objects = append(objects, object)
+			}
+		}
+
+		if len(objects) == 0 {
+			return nil, fmt.Errorf("没有匹配的文件: /%s", objectName)
+		}
+		return objects, nil
+	}
+
+	if recursive || strings.HasSuffix(objectName, "/") || objectName == "" {
+		if !recursive {
+			return nil, fmt.Errorf("'/%s' 是目录，需要使用 -r 参数", objectName)
+		}
+
+		prefix := objectName
+		if prefix != "" && !strings.HasSuffix(prefix, "/") {
+			prefix += "/"
+		}
+
+		var objects []minio.ObjectInfo
+		for object := range client.ListObjects(ctx, bucket, minio.ListObjectsOptions{
+			Prefix:    prefix,
+			Recursive: true,
+		}) {
+			if object.Err != nil {
+				return nil, fmt.Errorf("列出对象时出错: %w", object.Err)
+			}
+			if !strings.HasSuffix(object.Key, "/") {
+				objects = append(objects, object)
+			}
+		}
+
+		if len(objects) == 0 {
+			return nil, fmt.Errorf("目录 '/%s' 为空或不存在", prefix)
+		}
+		return objects, nil
+	}
+
+	info, err := client.StatObject(ctx, bucket, objectName, minio.StatObjectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("文件 '/%s' 不存在或无法访问: %w", objectName, err)
+	}
+	return []minio.ObjectInfo{info}, nil
+}
+
+// 对路径匹配的所有对象执行元数据修改，支持 dry-run
+func applyMetadataUpdate(c *cli.Context, command string, paths []string, opts *TransferOptions, reason string) error {
+	return applyObjectAction(c, command, paths, PlanAction{Op: opSetMeta, Reason: reason, Options: opts})
+}
+
+// 对路径匹配的所有对象执行 template 描述的操作，Target 和 Size 按对象填写，支持 dry-run
+func applyObjectAction(c *cli.Context, command string, paths []string, template PlanAction) error {
+	manager, err := initSessionManager()
+	if err != nil {
+		return err
+	}
+
+	client, err := manager.GetClient()
+	if err != nil {
+		return err
+	}
+
+	session, err := manager.CurrentSession()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	plan := newPlan(c, command, session)
+	policy := retryPolicyFromContext(c)
+	failures := newFailureReport(command, session)
+	var done int
+
+	for _, path := range paths {
+		formattedPath, err := manager.FormatPath(path)
+		if err != nil {
+			return err
+		}
+
+		objects, err := resolveObjects(ctx, client, session.BucketName, strings.TrimPrefix(formattedPath, "/"), c.Bool("r"))
+		if err != nil {
+			return err
+		}
+
+		for _, object := range objects {
+			action := template
+			action.Target = object.Key
+			action.Size = object.Size
+			if plan != nil {
+				plan.Add(action)
+				continue
+			}
+
+			attempts, err := runPlanAction(ctx, policy, client, session.BucketName, action)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "修改失败 '/%s': %v\n", object.Key, err)
+				failures.Add(action, attempts, err)
+				continue
+			}
+
+			if !c.Bool("quiet") {
+				fmt.Printf("已修改: /%s\n", object.Key)
+			}
+			done++
+		}
+	}
+
+	if plan != nil {
+		return plan.Finish(c)
+	}
+
+	fmt.Printf("修改完成: 成功 %d 个, 失败 %d 个\n", done, failures.Count())
+	return failures.Finish(c)
+}
+
+// 修改元数据操作
+func setmetaAction(c *cli.Context) error {
+	if c.NArg() < 1 {
+		return fmt.Errorf("需要指定远程文件、目录或通配符")
+	}
+
+	opts, err := transferOptionsFromContext(c)
+	if err != nil {
+		return err
+	}
+	if opts == nil {
+		opts = &TransferOptions{}
+	}
+	opts.ReplaceMetadata = c.Bool("replace")
+	opts.RemoveMetadata = c.StringSlice("rm-meta")
+
+	if opts.isEmpty() {
+		return fmt.Errorf("未指定要修改的元数据")
+	}
+
+	return applyMetadataUpdate(c, "setmeta", c.Args().Slice(), opts, describeOptions(opts))
+}
+
+// 设置标签操作: minx tag set <路径> key=value...
+func tagSetAction(c *cli.Context) error {
+	if c.NArg() < 2 {
+		return fmt.Errorf("需要指定远程路径和至少一个 key=value 标签")
+	}
+
+	tags, err := parseKeyValues(c.Args().Tail(), "标签")
+	if err != nil {
+		return err
+	}
+
+	opts := &TransferOptions{Tags: tags, ReplaceTags: c.Bool("replace")}
+	return applyMetadataUpdate(c, "tag", []string{c.Args().First()}, opts, describeOptions(opts))
+}
+
+// 删除标签操作: minx tag rm <路径> [key...]，未指定键时删除全部标签
+func tagRmAction(c *cli.Context) error {
+	if c.NArg() < 1 {
+		return fmt.Errorf("需要指定远程路径")
+	}
+
+	opts := &TransferOptions{RemoveTags: c.Args().Tail()}
+	if len(opts.RemoveTags) == 0 {
+		opts.ReplaceTags = true
+	}
+	return applyMetadataUpdate(c, "tag", []string{c.Args().First()}, opts, describeOptions(opts))
+}
+
+// 显示标签操作: minx tag get <路径>
+func tagGetAction(c *cli.Context) error {
+	if c.NArg() < 1 {
+		return fmt.Errorf("需要指定远程路径")
+	}
+
+	manager, err := initSessionManager()
+	if err != nil {
+		return err
+	}
+
+	client, err := manager.GetClient()
+	if err != nil {
+		return err
+	}
+
+	session, err := manager.CurrentSession()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+
+	for _, path := range c.Args().Slice() {
+		formattedPath, err := manager.FormatPath(path)
+		if err != nil {
+			return err
+		}
+
+		objects, err := resolveObjects(ctx, client, session.BucketName, strings.TrimPrefix(formattedPath, "/"), c.Bool("r"))
+		if err != nil {
+			return err
+		}
+
+		for _, object := range objects {
+			t, err := client.GetObjectTagging(ctx, session.BucketName, object.Key, minio.GetObjectTaggingOptions{})
+			if err != nil {
+				return fmt.Errorf("获取标签失败 '/%s': %w", object.Key, err)
+			}
+
+			tagMap := t.ToMap()
+			fmt.Printf("/%s:\n", object.Key)
+			if len(tagMap) == 0 {
+				fmt.Println("  (无标签)")
+			}
+			for _, k := range sortedKeys(tagMap) {
+				fmt.Printf("  %s = %s\n", k, tagMap[k])
+			}
+		}
+	}
+
+	return nil
+}
+
+// 生成修改内容的简短描述，用于计划输出
+func describeOptions(o *TransferOptions) string {
+	var parts []string
+
+	add := func(name, value string) {
+		if value != "" {
+			parts = append(parts, name+"="+value)
+		}
+	}
+	add("Content-Type", o.ContentType)
+	add("Cache-Control", o.CacheControl)
+	add("Content-Disposition", o.ContentDisposition)
+	add("Content-Encoding", o.ContentEncoding)
+	add("存储类型", o.StorageClass)
+	add("加密", o.SSE)
+
+	if o.ReplaceMetadata {
+		parts = append(parts, "替换元数据")
+	}
+	for _, k := range sortedKeys(o.Metadata) {
+		parts = append(parts, "meta:"+k+"="+o.Metadata[k])
+	}
+	for _, k := range o.RemoveMetadata {
+		parts = append(parts, "删除 meta:"+k)
+	}
+
+	if o.ReplaceTags && len(o.Tags) == 0 {
+		parts = append(parts, "删除全部标签")
+	} else if o.ReplaceTags {
+		parts = append(parts, "替换标签")
+	}
+	for _, k := range sortedKeys(o.Tags) {
+		parts = append(parts, "tag:"+k+"="+o.Tags[k])
+	}
+	for _, k := range o.RemoveTags {
+		parts = append(parts, "删除 tag:"+k)
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// 返回排序后的键列表
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
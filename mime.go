@@ -0,0 +1,437 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/urfave/cli/v2"
+	"minx/wildcard"
+)
+
+// 项目配置文件名，从当前目录向上查找
+const projectConfigName = ".minx.json"
+
+// ContentTypeRule 将匹配模式映射到 Content-Type
+// 模式不含 "/" 时匹配文件名，否则匹配完整路径，例如 "*.wasm"、"assets/fonts/*"
+type ContentTypeRule struct {
+	Pattern string `json:"pattern"`
+	Type    string `json:"type"`
+}
+
+// ProjectConfig 表示项目目录下的 .minx.json
+type ProjectConfig struct {
+	ContentTypes []ContentTypeRule `json:"content_types,omitempty"`
+}
+
+// 内置扩展名表，优先于系统 mime 数据库，保证不同机器上结果一致
+var builtinMimeTypes = map[string]string{
+	// 网页
+	".html":        "text/html; charset=utf-8",
+	".htm":         "text/html; charset=utf-8",
+	".xhtml":       "application/xhtml+xml",
+	".css":         "text/css; charset=utf-8",
+	".js":          "text/javascript; charset=utf-8",
+	".mjs":         "text/javascript; charset=utf-8",
+	".cjs":         "text/javascript; charset=utf-8",
+	".map":         "application/json",
+	".json":        "application/json",
+	".jsonld":      "application/ld+json",
+	".webmanifest": "application/manifest+json",
+	".wasm":        "application/wasm",
+	".xml":         "application/xml",
+	".rss":         "application/rss+xml",
+	".atom":        "application/atom+xml",
+
+	// 文本
+	".txt":      "text/plain; charset=utf-8",
+	".text":     "text/plain; charset=utf-8",
+	".log":      "text/plain; charset=utf-8",
+	".md":       "text/markdown; charset=utf-8",
+	".markdown": "text/markdown; charset=utf-8",
+	".csv":      "text/csv; charset=utf-8",
+	".tsv":      "text/tab-separated-values; charset=utf-8",
+	".ics":      "text/calendar; charset=utf-8",
+	".vtt":      "text/vtt; charset=utf-8",
+	".srt":      "application/x-subrip",
+	".yaml":     "application/yaml",
+	".yml":      "application/yaml",
+	".toml":     "application/toml",
+	".ini":      "text/plain; charset=utf-8",
+	".conf":     "text/plain; charset=utf-8",
+	".sh":       "application/x-sh",
+	".py":       "text/x-python; charset=utf-8",
+	".go":       "text/x-go; charset=utf-8",
+	".c":        "text/x-c; charset=utf-8",
+	".h":        "text/x-c; charset=utf-8",
+	".java":     "text/x-java-source; charset=utf-8",
+	".rtf":      "application/rtf",
+
+	// 图片
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".png":  "image/png",
+	".gif":  "image/gif",
+	".webp": "image/webp",
+	".avif": "image/avif",
+	".heic": "image/heic",
+	".heif": "image/heif",
+	".svg":  "image/svg+xml",
+	".svgz": "image/svg+xml",
+	".ico":  "image/vnd.microsoft.icon",
+	".bmp":  "image/bmp",
+	".tif":  "image/tiff",
+	".tiff": "image/tiff",
+	".psd":  "image/vnd.adobe.photoshop",
+	".jxl":  "image/jxl",
+
+	// 字体
+	".woff":  "font/woff",
+	".woff2": "font/woff2",
+	".ttf":   "font/ttf",
+	".otf":   "font/otf",
+	".eot":   "application/vnd.ms-fontobject",
+
+	// 音频
+	".mp3":  "audio/mpeg",
+	".wav":  "audio/wav",
+	".ogg":  "audio/ogg",
+	".oga":  "audio/ogg",
+	".opus": "audio/opus",
+	".flac": "audio/flac",
+	".aac":  "audio/aac",
+	".m4a":  "audio/mp4",
+	".mid":  "audio/midi",
+	".midi": "audio/midi",
+	".weba": "audio/webm",
+
+	// 视频
+	".mp4":  "video/mp4",
+	".m4v":  "video/mp4",
+	".webm": "video/webm",
+	".ogv":  "video/ogg",
+	".mov":  "video/quicktime",
+	".avi":  "video/x-msvideo",
+	".mkv":  "video/x-matroska",
+	".flv":  "video/x-flv",
+	".wmv":  "video/x-ms-wmv",
+	".mpeg": "video/mpeg",
+	".mpg":  "video/mpeg",
+	".ts":   "video/mp2t",
+	".m3u8": "application/vnd.apple.mpegurl",
+	".mpd":  "application/dash+xml",
+	".3gp":  "video/3gpp",
+
+	// 文档
+	".pdf":  "application/pdf",
+	".doc":  "application/msword",
+	".docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	".xls":  "application/vnd.ms-excel",
+	".xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	".ppt":  "application/vnd.ms-powerpoint",
+	".pptx": "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+	".odt":  "application/vnd.oasis.opendocument.text",
+	".ods":  "application/vnd.oasis.opendocument.spreadsheet",
+	".odp":  "application/vnd.oasis.opendocument.presentation",
+	".epub": "application/epub+zip",
+
+	// 压缩包
+	".zip": "application/zip",
+	".gz":  "application/gzip",
+	".tgz": "application/gzip",
+	".bz2": "application/x-bzip2",
+	".xz":  "application/x-xz",
+	".zst": "application/zstd",
+	".7z":  "application/x-7z-compressed",
+	".rar": "application/vnd.rar",
+	".tar": "application/x-tar",
+	".br":  "application/x-brotli",
+
+	// 其他二进制
+	".apk":     "application/vnd.android.package-archive",
+	".dmg":     "application/x-apple-diskimage",
+	".iso":     "application/x-iso9660-image",
+	".deb":     "application/vnd.debian.binary-package",
+	".rpm":     "application/x-rpm",
+	".jar":     "application/java-archive",
+	".exe":     "application/vnd.microsoft.portable-executable",
+	".msi":     "application/x-msdownload",
+	".bin":     "application/octet-stream",
+	".parquet": "application/vnd.apache.parquet",
+	".avro":    "application/avro",
+	".sqlite":  "application/vnd.sqlite3",
+	".db":      "application/vnd.sqlite3",
+	".pem":     "application/x-pem-file",
+	".crt":     "application/x-x509-ca-cert",
+}
+
+var (
+	contentTypeRulesOnce sync.Once
+	contentTypeRules     []ContentTypeRule
+	contentTypeRulesErr  error
+)
+
+// 加载 Content-Type 覆盖规则: 项目配置优先于会话配置
+// 项目配置 (.minx.json) 无法解析时返回错误，避免规则被静默忽略
+func loadContentTypeRules() ([]ContentTypeRule, error) {
+	contentTypeRulesOnce.Do(func() {
+		project, err := loadProjectConfig()
+		if err != nil {
+			contentTypeRulesErr = err
+			return
+		}
+		if project != nil {
+			contentTypeRules = append(contentTypeRules, project.ContentTypes...)
+		}
+
+		if manager != nil {
+			if session, err := manager.CurrentSession(); err == nil {
+				contentTypeRules = append(contentTypeRules, session.ContentTypes...)
+			}
+		}
+	})
+
+	return contentTypeRules, contentTypeRulesErr
+}
+
+// 从当前目录向上查找并加载项目配置，找不到时返回 nil
+func loadProjectConfig() (*ProjectConfig, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		configPath := filepath.Join(dir, projectConfigName)
+		data, err := os.ReadFile(configPath)
+		if err == nil {
+			config := &ProjectConfig{}
+			if err := json.Unmarshal(data, config); err != nil {
+				return nil, fmt.Errorf("无法解析项目配置 %s: %w", configPath, err)
+			}
+			return config, nil
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, nil
+		}
+		dir = parent
+	}
+}
+
+// 按覆盖规则匹配 Content-Type
+func matchContentTypeRule(name string) string {
+	name = filepath.ToSlash(name)
+	lower := strings.ToLower(name)
+	base := path.Base(lower)
+
+	// 配置错误已在命令开始时报告 (见 transferOptionsFromContext)，这里只使用已加载的规则
+	rules, _ := loadContentTypeRules()
+	for _, rule := range rules {
+		pattern := strings.ToLower(rule.Pattern)
+		if strings.Contains(pattern, "/") {
+			if wildcard.Match(strings.TrimPrefix(pattern, "/"), strings.TrimPrefix(lower, "/")) {
+				return rule.Type
+			}
+		} else if wildcard.Match(pattern, base) {
+			return rule.Type
+		}
+	}
+
+	return ""
+}
+
+// 根据文件名判断 MIME 类型，依次使用覆盖规则、内置表和系统 mime 数据库，无法判断时返回空字符串
+func lookupMimeType(name string) string {
+	if t := matchContentTypeRule(name); t != "" {
+		return t
+	}
+
+	ext := strings.ToLower(filepath.Ext(name))
+	if ext == "" {
+		return ""
+	}
+
+	if t, ok := builtinMimeTypes[ext]; ok {
+		return t
+	}
+
+	return mime.TypeByExtension(ext)
+}
+
+// 辅助函数：根据文件名获取 MIME 类型
+func getMimeType(name string) string {
+	if t := lookupMimeType(name); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
+
+// 获取本地文件的 MIME 类型，扩展名无法判断时读取文件开头的 512 字节进行识别
+func detectContentType(localPath string) string {
+	if t := lookupMimeType(localPath); t != "" {
+		return t
+	}
+
+	file, err := os.Open(localPath)
+	if err != nil {
+		return "application/octet-stream"
+	}
+	defer file.Close()
+
+	return sniffContentType(file)
+}
+
+// 根据内容识别 MIME 类型
+func sniffContentType(r io.Reader) string {
+	buf := make([]byte, 512)
+	n, _ := io.ReadFull(r, buf)
+	if n == 0 {
+		return "application/octet-stream"
+	}
+	return http.DetectContentType(buf[:n])
+}
+
+// 判断是否为未设置或泛化的类型
+func isGenericContentType(t string) bool {
+	t = strings.ToLower(strings.TrimSpace(t))
+	return t == "" || t == "application/octet-stream" || t == "binary/octet-stream"
+}
+
+// 修复已上传文件的 Content-Type
+func fixContentTypesAction(c *cli.Context) error {
+	manager, err := initSessionManager()
+	if err != nil {
+		return err
+	}
+
+	client, err := manager.GetClient()
+	if err != nil {
+		return err
+	}
+
+	session, err := manager.CurrentSession()
+	if err != nil {
+		return err
+	}
+
+	formattedPath, err := manager.FormatPath(c.Args().First())
+	if err != nil {
+		return err
+	}
+
+	prefix := strings.TrimPrefix(formattedPath, "/")
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+
+	if _, err := loadContentTypeRules(); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	trash := sessionTrashPrefix()
+	plan := newPlan(c, "fix-content-types", session)
+	policy := retryPolicyFromContext(c)
+	failures := newFailureReport("fix-content-types", session)
+	force := c.Bool("force")
+	var checked, fixed int
+
+	for object := range client.ListObjects(ctx, session.BucketName, minio.ListObjectsOptions{
+		Prefix:       prefix,
+		Recursive:    true,
+		WithMetadata: true,
+	}) {
+		if object.Err != nil {
+			return fmt.Errorf("列出对象时出错: %w", object.Err)
+		}
+		// 跳过目录和回收站中的对象，除非指定的路径本身在回收站内
+		if strings.HasSuffix(object.Key, "/") || inTrash(trash, prefix, object.Key) {
+			continue
+		}
+		checked++
+
+		// 并非所有服务都在列表中返回元数据，缺失时单独获取
+		current := object.ContentType
+		if current == "" {
+			if v, ok := object.UserMetadata["content-type"]; ok {
+				current = v
+			} else if info, err := client.StatObject(ctx, session.BucketName, object.Key, minio.StatObjectOptions{}); err == nil {
+				current = info.ContentType
+				object.UserMetadata = info.UserMetadata
+			}
+		}
+
+		// 客户端加密或压缩的文件存储的不是原始内容，无法按内容识别
+		if isClientEncrypted(object) || compressionOf(object) != "" {
+			continue
+		}
+
+		// 默认只修复未设置或泛化的类型，避免覆盖手动设置的类型
+		if !force && !isGenericContentType(current) {
+			continue
+		}
+
+		expected := lookupMimeType(object.Key)
+		if expected == "" && c.Bool("sniff") {
+			expected = sniffObjectContentType(ctx, client, session.BucketName, object.Key)
+		}
+		if expected == "" || isGenericContentType(expected) || strings.EqualFold(expected, current) {
+			continue
+		}
+
+		opts := &TransferOptions{ContentType: expected}
+		action := PlanAction{Op: opSetMeta, Target: object.Key, Size: object.Size, Reason: fmt.Sprintf("%s -> %s", current, expected), Options: opts}
+		if plan != nil {
+			plan.Add(action)
+			continue
+		}
+
+		attempts, err := policy.Do(ctx, func() error {
+			return updateObjectMetadata(ctx, client, session.BucketName, object.Key, opts)
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "修复失败 '/%s': %v\n", object.Key, err)
+			failures.Add(action, attempts, err)
+			continue
+		}
+
+		if !c.Bool("quiet") {
+			fmt.Printf("/%s: %s -> %s\n", object.Key, current, expected)
+		}
+		fixed++
+	}
+
+	if plan != nil {
+		return plan.Finish(c)
+	}
+
+	fmt.Printf("检查 %d 个文件, 修复 %d 个, 失败 %d 个\n", checked, fixed, failures.Count())
+	return failures.Finish(c)
+}
+
+// 读取对象开头的 512 字节识别类型，失败时返回空字符串
+func sniffObjectContentType(ctx context.Context, client *minio.Client, bucket, objectName string) string {
+	opts := minio.GetObjectOptions{}
+	if err := opts.SetRange(0, 511); err != nil {
+		return ""
+	}
+
+	obj, err := client.GetObject(ctx, bucket, objectName, opts)
+	if err != nil {
+		return ""
+	}
+	defer obj.Close()
+
+	return sniffContentType(obj)
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/notification"
+	"github.com/urfave/cli/v2"
+)
+
+// 事件简称对应的 S3 事件类型
+var eventAliases = map[string]notification.EventType{
+	"put":    notification.ObjectCreatedAll,
+	"delete": notification.ObjectRemovedAll,
+	"get":    notification.ObjectAccessedAll,
+}
+
+// 解析逗号分隔的事件列表: put、delete、get，或完整的 S3 事件名 (如 s3:ObjectCreated:Copy)
+func parseEventTypes(spec string) ([]notification.EventType, error) {
+	var events []notification.EventType
+	seen := make(map[notification.EventType]bool)
+
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		event, ok := eventAliases[strings.ToLower(name)]
+		if !ok {
+			if !strings.HasPrefix(name, "s3:") {
+				return nil, fmt.Errorf("无效的事件 '%s'，应为 put、delete、get 或 s3:ObjectCreated:* 形式的事件名", name)
+			}
+			event = notification.EventType(name)
+		}
+
+		if !seen[event] {
+			seen[event] = true
+			events = append(events, event)
+		}
+	}
+
+	if len(events) == 0 {
+		return nil, fmt.Errorf("需要指定至少一个事件")
+	}
+	return events, nil
+}
+
+// 事件名的简称，无法简化时去掉 s3: 前缀
+func eventShortName(name string) string {
+	switch {
+	case strings.HasPrefix(name, "s3:ObjectCreated:"):
+		return "put"
+	case strings.HasPrefix(name, "s3:ObjectRemoved:"):
+		return "delete"
+	case strings.HasPrefix(name, "s3:ObjectAccessed:"):
+		return "get"
+	}
+	return strings.TrimPrefix(name, "s3:")
+}
+
+// BucketEvent 是一条存储桶事件，同时用于文本和 JSON 输出
+type BucketEvent struct {
+	Time        time.Time `json:"time"`
+	Event       string    `json:"event"`
+	Bucket      string    `json:"bucket"`
+	Key         string    `json:"key"`
+	Size        int64     `json:"size"`
+	ETag        string    `json:"etag,omitempty"`
+	ContentType string    `json:"content_type,omitempty"`
+	VersionID   string    `json:"version_id,omitempty"`
+	Source      string    `json:"source,omitempty"` // 触发事件的客户端地址
+	Local       string    `json:"local,omitempty"`  // --auto-get 下载到的本地路径
+}
+
+// 转换通知记录，对象名在通知中经过 URL 编码
+func newBucketEvent(record notification.Event) BucketEvent {
+	key, err := url.QueryUnescape(record.S3.Object.Key)
+	if err != nil {
+		key = record.S3.Object.Key
+	}
+
+	event := BucketEvent{
+		Event:       record.EventName,
+		Bucket:      record.S3.Bucket.Name,
+		Key:         key,
+		Size:        record.S3.Object.Size,
+		ETag:        record.S3.Object.ETag,
+		ContentType: record.S3.Object.ContentType,
+		VersionID:   record.S3.Object.VersionID,
+		Source:      record.Source.Host,
+	}
+	if t, err := time.Parse(time.RFC3339Nano, record.EventTime); err == nil {
+		event.Time = t
+	} else {
+		event.Time = time.Now()
+	}
+	return event
+}
+
+// 以文本形式输出一条事件
+func printBucketEvent(e BucketEvent) {
+	size := "-"
+	if strings.HasPrefix(e.Event, "s3:ObjectCreated:") {
+		size = formatSize(e.Size)
+	}
+	line := fmt.Sprintf("%s  %-6s  %10s  /%s", e.Time.Local().Format("2006-01-02 15:04:05"), eventShortName(e.Event), size, e.Key)
+	if e.Local != "" {
+		line += "  -> " + e.Local
+	}
+	fmt.Println(line)
+}
+
+// 监听中断后是否应该重新连接: 服务端不支持或请求被拒绝时不再重试
+func isListenFatal(err error) bool {
+	var resp minio.ErrorResponse
+	if !errors.As(err, &resp) {
+		return false
+	}
+	if resp.Code == "APINotSupported" || resp.Code == "NotImplemented" {
+		return true
+	}
+	return resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != 408 && resp.StatusCode != 429
+}
+
+// 监听存储桶事件操作
+// 使用 MinIO 的 ListenBucketNotification 接口，连接中断后自动重连，重连期间的事件会丢失
+func watchAction(c *cli.Context) error {
+	events, err := parseEventTypes(c.String("events"))
+	if err != nil {
+		return err
+	}
+
+	execCmd, err := splitCommand(c.String("exec"))
+	if err != nil {
+		return fmt.Errorf("无效的 --exec: %w", err)
+	}
+	getDir := c.String("auto-get")
+	jsonOut := c.Bool("json")
+
+	manager, err := initSessionManager()
+	if err != nil {
+		return err
+	}
+
+	client, err := manager.GetClient()
+	if err != nil {
+		return err
+	}
+
+	session, err := manager.CurrentSession()
+	if err != nil {
+		return err
+	}
+
+	var remotePath string
+	if c.NArg() > 0 {
+		remotePath = c.Args().First()
+	}
+	formattedPath, err := manager.FormatPath(remotePath)
+	if err != nil {
+		return err
+	}
+
+	prefix := strings.TrimPrefix(formattedPath, "/")
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+
+	if getDir != "" {
+		if err := os.MkdirAll(getDir, 0755); err != nil {
+			return fmt.Errorf("无法创建下载目录: %w", err)
+		}
+	}
+
+	var options *TransferOptions
+	if dec := newDecoder(c); c.String("sse") != "" || dec.options() != nil {
+		options = &TransferOptions{SSE: c.String("sse"), Encrypt: dec.options()}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	policy := retryPolicyFromContext(c)
+	failures := newFailureReport("watch", session)
+
+	// 处理一条事件: 下载新建的对象，执行命令，然后输出
+	handle := func(e BucketEvent) {
+		display := "/" + e.Key
+
+		if getDir != "" && strings.HasPrefix(e.Event, "s3:ObjectCreated:") && !strings.HasSuffix(e.Key, "/") {
+			// 对象名中的 .. 不能让文件写到下载目录之外
+			rel := filepath.FromSlash(strings.TrimPrefix(e.Key, prefix))
+			if !filepath.IsLocal(rel) {
+				fmt.Fprintf(os.Stderr, "跳过下载 '%s': 对象名不能作为本地路径\n", display)
+			} else {
+				action := PlanAction{Op: opDownload, Source: e.Key, Target: planLocalPath(filepath.Join(getDir, rel)), Size: e.Size, Reason: "watch 新建", Options: options}
+				attempts, err := runPlanAction(ctx, policy, client, session.BucketName, action)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "%s失败 '%s': %v\n", opLabels[action.Op], display, err)
+					failures.Add(action, attempts, err)
+				} else {
+					e.Local = action.Target
+				}
+			}
+		}
+
+		if jsonOut {
+			data, err := json.Marshal(e)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "无法序列化事件: %v\n", err)
+			} else {
+				fmt.Println(string(data))
+			}
+		} else {
+			printBucketEvent(e)
+		}
+
+		if len(execCmd) > 0 {
+			values := map[string]string{
+				"{}":      display,
+				"{key}":   display,
+				"{event}": eventShortName(e.Event),
+				"{size}":  strconv.FormatInt(e.Size, 10),
+				"{etag}":  e.ETag,
+				"{local}": e.Local,
+			}
+			args := expandCommand(execCmd, values, display)
+
+			cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+			cmd.Stdin = os.Stdin
+			cmd.Stdout = os.Stdout
+			cmd.Stderr = os.Stderr
+			cmd.Env = append(os.Environ(),
+				"MINX_EVENT="+e.Event,
+				"MINX_BUCKET="+e.Bucket,
+				"MINX_KEY="+e.Key,
+				"MINX_SIZE="+strconv.FormatInt(e.Size, 10),
+				"MINX_LOCAL="+e.Local,
+			)
+			if err := cmd.Run(); err != nil && ctx.Err() == nil {
+				fmt.Fprintf(os.Stderr, "执行失败 '%s': %v\n", display, err)
+				failures.AddError(fmt.Errorf("执行 '%s' 失败: %w", strings.Join(args, " "), err))
+			}
+		}
+	}
+
+	eventNames := make([]string, len(events))
+	for i, event := range events {
+		eventNames[i] = string(event)
+	}
+
+	if !c.Bool("quiet") && !jsonOut {
+		fmt.Fprintf(os.Stderr, "正在监听 /%s 的事件 (%s，按 Ctrl+C 停止)\n", prefix, strings.Join(eventNames, ", "))
+	}
+
+	attempt := 0
+	for ctx.Err() == nil {
+		listenCtx, cancel := context.WithCancel(ctx)
+		var listenErr error
+
+		for info := range client.ListenBucketNotification(listenCtx, session.BucketName, prefix, c.String("suffix"), eventNames) {
+			if info.Err != nil {
+				listenErr = info.Err
+				break
+			}
+			attempt = 0
+			for _, record := range info.Records {
+				handle(newBucketEvent(record))
+			}
+		}
+		cancel()
+
+		if ctx.Err() != nil {
+			break
+		}
+		if listenErr != nil && isListenFatal(listenErr) {
+			return fmt.Errorf("无法监听事件: %w", listenErr)
+		}
+		if listenErr == nil {
+			listenErr = errors.New("连接已关闭")
+		}
+
+		attempt++
+		delay := policy.backoff(attempt)
+		fmt.Fprintf(os.Stderr, "监听中断: %v，%s 后重新连接\n", listenErr, delay.Round(time.Second))
+		select {
+		case <-ctx.Done():
+		case <-time.After(delay):
+		}
+	}
+
+	return failures.Finish(c)
+}
@@ -0,0 +1,473 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/urfave/cli/v2"
+)
+
+// 计划中的操作类型
+const (
+	opUpload    = "upload"
+	opOverwrite = "overwrite"
+	opSkip      = "skip"
+	opDelete    = "delete"
+	opCopy      = "copy"
+	opMove      = "move"
+	opMkdir     = "mkdir"
+	opDownload  = "download"
+	opSetMeta   = "setmeta"
+	opRetention = "retention"
+	opLegalHold = "legalhold"
+	opAbort     = "abort"
+	opTrash     = "trash"
+	opRestore   = "restore"
+)
+
+// 操作类型的显示名称
+var opLabels = map[string]string{
+	opUpload:    "上传",
+	opOverwrite: "覆盖",
+	opSkip:      "跳过",
+	opDelete:    "删除",
+	opCopy:      "复制",
+	opMove:      "移动",
+	opMkdir:     "建目录",
+	opDownload:  "下载",
+	opSetMeta:   "改元数据",
+	opRetention: "改保留期",
+	opLegalHold: "法律保留",
+	opAbort:     "中止上传",
+	opTrash:     "移到回收站",
+	opRestore:   "还原",
+}
+
+// 汇总时的显示顺序
+var opOrder = []string{opUpload, opOverwrite, opSkip, opDelete, opCopy, opMove, opMkdir, opDownload, opSetMeta, opRetention, opLegalHold, opAbort, opTrash, opRestore}
+
+// PlanAction 表示计划中的一个操作
+type PlanAction struct {
+	Op     string `json:"op"`
+	Source string `json:"source,omitempty"` // 本地路径、URL、源对象名，中止上传时为上传 ID
+	Target string `json:"target,omitempty"` // 目标对象名，下载时为本地路径
+	Size   int64  `json:"size"`
+	Reason string `json:"reason,omitempty"`
+
+	Options *TransferOptions `json:"options,omitempty"` // 上传或修改元数据时设置的内容头、元数据和标签
+	Lock    *ObjectLock      `json:"lock,omitempty"`    // 修改保留期、法律保留，或删除时绕过治理模式
+}
+
+// Plan 表示一次 dry-run 生成的执行计划
+type Plan struct {
+	Command   string       `json:"command"`
+	Session   string       `json:"session"`
+	Endpoint  string       `json:"endpoint"`
+	Bucket    string       `json:"bucket"`
+	CreatedAt time.Time    `json:"created_at"`
+	Actions   []PlanAction `json:"actions"`
+
+	mu sync.Mutex
+}
+
+// 判断是否处于 dry-run 模式 (--plan-out 隐含 --dry-run)
+func isDryRun(c *cli.Context) bool {
+	return c.Bool("dry-run") || c.String("plan-out") != ""
+}
+
+// 创建计划，非 dry-run 模式下返回 nil
+func newPlan(c *cli.Context, command string, session *Session) *Plan {
+	if !isDryRun(c) {
+		return nil
+	}
+
+	name := ""
+	if manager != nil {
+		name = manager.CurrentName
+	}
+
+	return &Plan{
+		Command:   command,
+		Session:   name,
+		Endpoint:  session.Endpoint,
+		Bucket:    session.BucketName,
+		CreatedAt: time.Now(),
+	}
+}
+
+// Add 向计划中添加一个操作，可在多个工作线程中并发调用
+func (p *Plan) Add(action PlanAction) {
+	p.mu.Lock()
+	p.Actions = append(p.Actions, action)
+	p.mu.Unlock()
+}
+
+// Print 打印计划内容及汇总
+func (p *Plan) Print() {
+	fmt.Printf("执行计划 (%s, bucket: %s):\n", p.Command, p.Bucket)
+
+	counts := make(map[string]int)
+	sizes := make(map[string]int64)
+
+	for _, action := range p.Actions {
+		counts[action.Op]++
+		if action.Size > 0 {
+			sizes[action.Op] += action.Size
+		}
+
+		// 中止上传时 Source 为上传 ID，只显示对象名
+		target := action.Target
+		if action.Source != "" && action.Target != "" && action.Op != opAbort {
+			target = action.Source + " -> " + action.Target
+		} else if action.Source != "" && action.Op != opAbort {
+			target = action.Source
+		}
+
+		sizeStr := "-"
+		if action.Size >= 0 && action.Op != opMkdir {
+			sizeStr = formatSize(action.Size)
+		}
+
+		fmt.Printf("  %-6s %10s  %s", opLabels[action.Op], sizeStr, target)
+		if action.Reason != "" {
+			fmt.Printf("  (%s)", action.Reason)
+		}
+		fmt.Println()
+	}
+
+	fmt.Println("汇总:")
+	for _, op := range opOrder {
+		if counts[op] == 0 {
+			continue
+		}
+		fmt.Printf("  %-6s %d 个, %s\n", opLabels[op], counts[op], formatSize(sizes[op]))
+	}
+	if len(p.Actions) == 0 {
+		fmt.Println("  无任何操作")
+	}
+}
+
+// Save 将计划写入 JSON 文件
+func (p *Plan) Save(path string) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return fmt.Errorf("无法序列化计划: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("无法写入计划文件: %w", err)
+	}
+
+	return nil
+}
+
+// Finish 输出计划，并在指定 --plan-out 时保存
+func (p *Plan) Finish(c *cli.Context) error {
+	// 并发生成的操作顺序不确定，按目标排序以便审阅
+	sort.SliceStable(p.Actions, func(i, j int) bool {
+		return p.Actions[i].Target < p.Actions[j].Target
+	})
+
+	p.Print()
+
+	if out := c.String("plan-out"); out != "" {
+		if err := p.Save(out); err != nil {
+			return err
+		}
+		fmt.Printf("计划已保存到: %s (使用 minx apply %s 执行)\n", out, out)
+	} else {
+		fmt.Println("dry-run 模式，未执行任何写操作")
+	}
+
+	return nil
+}
+
+// 加载计划文件
+func loadPlan(path string) (*Plan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("无法读取计划文件: %w", err)
+	}
+
+	plan := &Plan{}
+	if err := json.Unmarshal(data, plan); err != nil {
+		return nil, fmt.Errorf("无法解析计划文件: %w", err)
+	}
+
+	return plan, nil
+}
+
+// 判断上传目标是新建还是覆盖 (只读操作)
+func planUploadOp(ctx context.Context, client *minio.Client, bucket, objectName string) (string, string) {
+	info, err := client.StatObject(ctx, bucket, objectName, minio.StatObjectOptions{})
+	if err != nil {
+		return opUpload, "远程不存在"
+	}
+	return opOverwrite, fmt.Sprintf("远程已存在 (%s)", formatSize(info.Size))
+}
+
+// 获取 URL 内容大小 (只读操作)，未知时返回 -1
+func headContentLength(url string) int64 {
+	resp, err := http.Head(url)
+	if err != nil {
+		return -1
+	}
+	resp.Body.Close()
+	return resp.ContentLength
+}
+
+// 按重试策略执行计划中的单个操作
+// 启用回收站时，被覆盖的对象在第一次尝试之前复制到回收站一次
+func runPlanAction(ctx context.Context, policy RetryPolicy, client *minio.Client, bucket string, action PlanAction) (int, error) {
+	switch action.Op {
+	case opUpload, opOverwrite, opCopy, opMove, opRestore:
+		if _, err := trashOverwritten(ctx, client, bucket, action.Target, action.Options.sseSpec()); err != nil {
+			return 1, err
+		}
+	}
+
+	return policy.Do(ctx, func() error {
+		return executePlanAction(ctx, client, bucket, action)
+	})
+}
+
+// 执行计划中的单个操作
+func executePlanAction(ctx context.Context, client *minio.Client, bucket string, action PlanAction) error {
+	switch action.Op {
+	case opSkip:
+		return nil
+
+	case opMkdir:
+		_, err := client.PutObject(ctx, bucket, action.Target, strings.NewReader(""), 0, minio.PutObjectOptions{})
+		return err
+
+	case opUpload, opOverwrite:
+		if action.Source == "-" {
+			// 标准输入只能读取一次，失败后不再重试
+			reader, size, opts, err := action.Options.prepareUpload(action.Target, getMimeType(action.Target), newTransferReader(os.Stdin), -1)
+			if err != nil {
+				return permanentError{err}
+			}
+			opts.PartSize = defaultPartSize
+			_, err = client.PutObject(ctx, bucket, action.Target, reader, size, opts)
+			if err != nil {
+				return permanentError{err}
+			}
+			return nil
+		}
+
+		if strings.HasPrefix(action.Source, "http://") || strings.HasPrefix(action.Source, "https://") {
+			resp, err := http.Get(action.Source)
+			if err != nil {
+				return err
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != http.StatusOK {
+				return fmt.Errorf("HTTP 请求失败: %s", resp.Status)
+			}
+
+			reader, size, opts, err := action.Options.prepareUpload(action.Target, resp.Header.Get("Content-Type"), newTransferReader(resp.Body), resp.ContentLength)
+			if err != nil {
+				return permanentError{err}
+			}
+			_, err = client.PutObject(ctx, bucket, action.Target, reader, size, opts)
+			return err
+		}
+
+		info, err := os.Stat(action.Source)
+		if err != nil {
+			return err
+		}
+		if info.Size() != action.Size {
+			return fmt.Errorf("本地文件已变化 (计划 %s, 当前 %s)", formatSize(action.Size), formatSize(info.Size()))
+		}
+
+		file, err := os.Open(action.Source)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		reader, size, opts, err := action.Options.prepareUpload(action.Target, detectContentType(action.Source), newTransferReader(file), info.Size())
+		if err != nil {
+			return permanentError{err}
+		}
+		_, err = client.PutObject(ctx, bucket, action.Target, reader, size, opts)
+		return err
+
+	case opDelete:
+		// 启用回收站时移到回收站，回收站中的对象直接删除
+		if trash := sessionTrashPrefix(); trash != "" && !strings.HasPrefix(action.Target, trash) {
+			return moveToTrash(ctx, client, bucket, action.Target, trashKey(trash, time.Now(), action.Target), action)
+		}
+		return client.RemoveObject(ctx, bucket, action.Target, action.Lock.removeOptions())
+
+	case opCopy, opMove:
+		srcSSE, dstSSE, err := copyEncryption(action.Options.sseSourceSpec(), action.Options.sseSpec(), action.Source, action.Target)
+		if err != nil {
+			return permanentError{err}
+		}
+
+		_, err = client.CopyObject(ctx, minio.CopyDestOptions{
+			Bucket:     bucket,
+			Object:     action.Target,
+			Encryption: dstSSE,
+		}, minio.CopySrcOptions{
+			Bucket:     bucket,
+			Object:     action.Source,
+			Encryption: srcSSE,
+		})
+		if err != nil {
+			return err
+		}
+		if action.Op == opMove {
+			return client.RemoveObject(ctx, bucket, action.Source, minio.RemoveObjectOptions{})
+		}
+		return nil
+
+	case opDownload:
+		if err := os.MkdirAll(filepath.Dir(action.Target), 0755); err != nil {
+			return err
+		}
+
+		sse, err := readEncryption(action.Options.sseSpec(), action.Source)
+		if err != nil {
+			return permanentError{err}
+		}
+
+		info, err := client.StatObject(ctx, bucket, action.Source, minio.StatObjectOptions{ServerSideEncryption: sse})
+		if err != nil {
+			return err
+		}
+
+		obj, err := decoderFromOptions(action.Options).Open(ctx, client, bucket, info, sse, 0, -1)
+		if err != nil {
+			return err
+		}
+		defer obj.Close()
+
+		file, err := os.Create(action.Target)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		_, err = io.Copy(file, newTransferReader(obj))
+		return err
+
+	case opSetMeta:
+		if action.Options.isEmpty() {
+			return fmt.Errorf("计划中缺少要修改的元数据")
+		}
+		return updateObjectMetadata(ctx, client, bucket, action.Target, action.Options)
+
+	case opRetention:
+		if action.Lock == nil {
+			return fmt.Errorf("计划中缺少保留期设置")
+		}
+		return action.Lock.putRetention(ctx, client, bucket, action.Target)
+
+	case opLegalHold:
+		if action.Lock == nil || action.Lock.LegalHold == "" {
+			return fmt.Errorf("计划中缺少法律保留设置")
+		}
+		status := minio.LegalHoldStatus(action.Lock.LegalHold)
+		return client.PutObjectLegalHold(ctx, bucket, action.Target, minio.PutObjectLegalHoldOptions{Status: &status})
+
+	case opTrash:
+		return moveToTrash(ctx, client, bucket, action.Source, action.Target, action)
+
+	case opRestore:
+		return restoreFromTrash(ctx, client, bucket, action.Source, action.Target, action)
+
+	case opAbort:
+		core := minio.Core{Client: client}
+		return core.AbortMultipartUpload(ctx, bucket, action.Target, action.Source)
+
+	default:
+		return fmt.Errorf("未知的操作类型: %s", action.Op)
+	}
+}
+
+// 执行计划文件操作
+func applyAction(c *cli.Context) error {
+	if c.NArg() < 1 {
+		return fmt.Errorf("需要指定计划文件")
+	}
+
+	manager, err := initSessionManager()
+	if err != nil {
+		return err
+	}
+
+	client, err := manager.GetClient()
+	if err != nil {
+		return err
+	}
+
+	session, err := manager.CurrentSession()
+	if err != nil {
+		return err
+	}
+
+	plan, err := loadPlan(c.Args().First())
+	if err != nil {
+		return err
+	}
+
+	// 计划必须在生成它的 bucket 上执行
+	if plan.Bucket != session.BucketName || plan.Endpoint != session.Endpoint {
+		return fmt.Errorf("计划属于 %s/%s，与当前会话 %s/%s 不一致",
+			plan.Bucket, plan.Endpoint, session.BucketName, session.Endpoint)
+	}
+
+	fmt.Printf("执行计划: %s (生成于 %s，共 %d 个操作)\n",
+		plan.Command, plan.CreatedAt.Format("2006-01-02 15:04:05"), len(plan.Actions))
+
+	ctx := context.Background()
+	policy := retryPolicyFromContext(c)
+	failures := newFailureReport("apply", session)
+	var done int
+
+	for _, action := range plan.Actions {
+		if action.Op == opSkip {
+			continue
+		}
+
+		target := action.Target
+		if action.Source != "" && action.Op != opAbort {
+			target = action.Source + " -> " + action.Target
+		}
+		fmt.Printf("%s: %s\n", opLabels[action.Op], target)
+
+		attempts, err := runPlanAction(ctx, policy, client, session.BucketName, action)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s失败 '%s': %v\n", opLabels[action.Op], target, err)
+			failures.Add(action, attempts, err)
+			continue
+		}
+		done++
+	}
+
+	fmt.Printf("计划执行完成: 成功 %d 个, 失败 %d 个\n", done, failures.Count())
+	return failures.Finish(c)
+}
+
+// 计划中记录本地文件的绝对路径，以便在其他目录下执行
+func planLocalPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
@@ -0,0 +1,578 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/urfave/cli/v2"
+)
+
+// 匿名访问权限
+const (
+	anonymousNone     = "none"     // 不允许匿名访问
+	anonymousDownload = "download" // 只读: 下载和列出
+	anonymousUpload   = "upload"   // 只写: 上传和删除
+	anonymousPublic   = "public"   // 读写
+)
+
+// 匿名访问权限对应的对象操作
+var (
+	anonymousReadActions  = []string{"s3:GetObject"}
+	anonymousWriteActions = []string{"s3:AbortMultipartUpload", "s3:DeleteObject", "s3:ListMultipartUploadParts", "s3:PutObject"}
+)
+
+// 策略中的字符串或字符串数组，统一按数组处理
+type policyValues []string
+
+func (v *policyValues) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*v = policyValues{s}
+		return nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return errors.New("应为字符串或字符串数组")
+	}
+	*v = list
+	return nil
+}
+
+// 是否包含指定的值
+func (v policyValues) has(value string) bool {
+	return slices.Contains(v, value)
+}
+
+// 存储桶策略中的一条语句，Principal 保留原样以免丢失其他类型的主体
+type policyStatement struct {
+	Sid          string                             `json:"Sid,omitempty"`
+	Effect       string                             `json:"Effect"`
+	Principal    json.RawMessage                    `json:"Principal,omitempty"`
+	NotPrincipal json.RawMessage                    `json:"NotPrincipal,omitempty"`
+	Action       policyValues                       `json:"Action,omitempty"`
+	NotAction    policyValues                       `json:"NotAction,omitempty"`
+	Resource     policyValues                       `json:"Resource,omitempty"`
+	NotResource  policyValues                       `json:"NotResource,omitempty"`
+	Condition    map[string]map[string]policyValues `json:"Condition,omitempty"`
+}
+
+// 存储桶策略
+type bucketPolicy struct {
+	Version   string            `json:"Version"`
+	ID        string            `json:"Id,omitempty"`
+	Statement []policyStatement `json:"Statement"`
+}
+
+// 存储桶及其对象的资源名
+func bucketARN(bucket string) string {
+	return "arn:aws:s3:::" + bucket
+}
+
+func objectARN(bucket, prefix string) string {
+	return bucketARN(bucket) + "/" + prefix + "*"
+}
+
+// 主体是否为匿名用户: "*" 或 {"AWS": "*"}
+func isAnonymousPrincipal(raw json.RawMessage) bool {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s == "*"
+	}
+
+	var m map[string]policyValues
+	if json.Unmarshal(raw, &m) != nil || len(m) != 1 {
+		return false
+	}
+	aws, ok := m["AWS"]
+	return ok && len(aws) == 1 && aws[0] == "*"
+}
+
+// 语句是否为不带条件限制以外特殊字段的匿名允许语句
+func (s policyStatement) isAnonymousAllow() bool {
+	return s.Effect == "Allow" && isAnonymousPrincipal(s.Principal) &&
+		len(s.NotPrincipal) == 0 && len(s.NotAction) == 0 && len(s.NotResource) == 0
+}
+
+// 语句是否允许指定操作，考虑 s3:* 和 * 通配
+func (s policyStatement) allows(action string) bool {
+	return s.Action.has(action) || s.Action.has("s3:*") || s.Action.has("*")
+}
+
+// 解析并检查策略，资源必须属于指定的存储桶
+func parseBucketPolicy(data []byte, bucket string) (*bucketPolicy, error) {
+	var policy bucketPolicy
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&policy); err != nil {
+		return nil, fmt.Errorf("无法解析策略: %w", err)
+	}
+	if err := validateBucketPolicy(&policy, bucket); err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// 检查策略的结构，避免把明显错误的策略提交到服务端
+func validateBucketPolicy(policy *bucketPolicy, bucket string) error {
+	if policy.Version != "2012-10-17" && policy.Version != "2008-10-17" {
+		return fmt.Errorf("策略版本 '%s' 无效，应为 2012-10-17", policy.Version)
+	}
+	if len(policy.Statement) == 0 {
+		return errors.New("策略中没有任何语句")
+	}
+
+	arn := bucketARN(bucket)
+	for i, s := range policy.Statement {
+		where := fmt.Sprintf("第 %d 条语句", i+1)
+		if s.Sid != "" {
+			where += fmt.Sprintf(" (%s)", s.Sid)
+		}
+
+		if s.Effect != "Allow" && s.Effect != "Deny" {
+			return fmt.Errorf("%s: Effect '%s' 无效，应为 Allow 或 Deny", where, s.Effect)
+		}
+		if len(s.Principal) == 0 && len(s.NotPrincipal) == 0 {
+			return fmt.Errorf("%s: 缺少 Principal", where)
+		}
+
+		actions := append(slices.Clone(s.Action), s.NotAction...)
+		if len(actions) == 0 {
+			return fmt.Errorf("%s: 缺少 Action", where)
+		}
+		for _, action := range actions {
+			if action != "*" && !strings.HasPrefix(action, "s3:") {
+				return fmt.Errorf("%s: 操作 '%s' 无效，应以 s3: 开头", where, action)
+			}
+		}
+
+		resources := append(slices.Clone(s.Resource), s.NotResource...)
+		if len(resources) == 0 {
+			return fmt.Errorf("%s: 缺少 Resource", where)
+		}
+		for _, resource := range resources {
+			if resource != arn && !strings.HasPrefix(resource, arn+"/") {
+				return fmt.Errorf("%s: 资源 '%s' 不属于存储桶 %s", where, resource, bucket)
+			}
+		}
+	}
+	return nil
+}
+
+// 获取当前会话存储桶的策略，没有策略时返回空字符串
+func currentBucketPolicy(ctx context.Context) (*minio.Client, *Session, string, error) {
+	manager, err := initSessionManager()
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	client, err := manager.GetClient()
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	session, err := manager.CurrentSession()
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	policy, err := client.GetBucketPolicy(ctx, session.BucketName)
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("无法获取存储桶策略: %w", err)
+	}
+
+	return client, session, policy, nil
+}
+
+// 写入存储桶策略，空字符串表示删除策略，dry-run 模式下只输出修改后的策略
+func saveBucketPolicy(ctx context.Context, c *cli.Context, client *minio.Client, session *Session, policy string) error {
+	if isDryRun(c) {
+		if policy == "" {
+			fmt.Printf("将删除存储桶 %s 的策略\n", session.BucketName)
+		} else {
+			fmt.Printf("修改后的存储桶策略 (bucket: %s):\n", session.BucketName)
+			printBucketPolicy(policy)
+		}
+		fmt.Println("dry-run 模式，未执行任何写操作")
+		return nil
+	}
+
+	if err := client.SetBucketPolicy(ctx, session.BucketName, policy); err != nil {
+		return fmt.Errorf("无法写入存储桶策略: %w", err)
+	}
+	return nil
+}
+
+// 格式化输出策略 JSON
+func printBucketPolicy(policy string) {
+	var out bytes.Buffer
+	if err := json.Indent(&out, []byte(policy), "", "  "); err != nil {
+		fmt.Println(policy)
+		return
+	}
+	fmt.Println(out.String())
+}
+
+// 查看存储桶策略操作
+func policyGetAction(c *cli.Context) error {
+	_, session, policy, err := currentBucketPolicy(context.Background())
+	if err != nil {
+		return err
+	}
+
+	if policy == "" {
+		return fmt.Errorf("存储桶 %s 没有设置策略", session.BucketName)
+	}
+
+	if c.NArg() < 1 || c.Args().First() == "-" {
+		printBucketPolicy(policy)
+		return nil
+	}
+
+	var out bytes.Buffer
+	if err := json.Indent(&out, []byte(policy), "", "  "); err != nil {
+		out.Reset()
+		out.WriteString(policy)
+	}
+	out.WriteByte('\n')
+
+	file := c.Args().First()
+	if err := os.WriteFile(file, out.Bytes(), 0644); err != nil {
+		return fmt.Errorf("无法写入文件: %w", err)
+	}
+	fmt.Printf("存储桶策略已导出到: %s\n", file)
+	return nil
+}
+
+// 设置存储桶策略操作，提交前检查策略的结构
+func policySetAction(c *cli.Context) error {
+	if c.NArg() < 1 {
+		return fmt.Errorf("需要指定策略文件 (- 表示标准输入)")
+	}
+
+	var data []byte
+	var err error
+	if file := c.Args().First(); file == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(file)
+	}
+	if err != nil {
+		return fmt.Errorf("无法读取策略文件: %w", err)
+	}
+
+	ctx := context.Background()
+	client, session, _, err := currentBucketPolicy(ctx)
+	if err != nil {
+		return err
+	}
+
+	if _, err := parseBucketPolicy(data, session.BucketName); err != nil {
+		return err
+	}
+
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, data); err != nil {
+		return fmt.Errorf("无法解析策略: %w", err)
+	}
+
+	if err := saveBucketPolicy(ctx, c, client, session, compact.String()); err != nil {
+		return err
+	}
+	if !isDryRun(c) {
+		fmt.Printf("已设置存储桶 %s 的策略\n", session.BucketName)
+	}
+	return nil
+}
+
+// 删除存储桶策略操作
+func policyRmAction(c *cli.Context) error {
+	ctx := context.Background()
+	client, session, policy, err := currentBucketPolicy(ctx)
+	if err != nil {
+		return err
+	}
+
+	if policy == "" {
+		return fmt.Errorf("存储桶 %s 没有设置策略", session.BucketName)
+	}
+
+	if err := saveBucketPolicy(ctx, c, client, session, ""); err != nil {
+		return err
+	}
+	if !isDryRun(c) {
+		fmt.Printf("已删除存储桶 %s 的策略\n", session.BucketName)
+	}
+	return nil
+}
+
+// 匿名访问前缀的读写权限
+type anonymousGrant struct {
+	read  bool
+	write bool
+}
+
+// 权限名称
+func (g anonymousGrant) String() string {
+	switch {
+	case g.read && g.write:
+		return anonymousPublic
+	case g.read:
+		return anonymousDownload
+	case g.write:
+		return anonymousUpload
+	}
+	return anonymousNone
+}
+
+// 统计策略中各前缀的匿名访问权限，other 为无法按前缀归类的匿名语句数
+func anonymousGrants(policy *bucketPolicy, bucket string) (grants map[string]anonymousGrant, other int) {
+	grants = make(map[string]anonymousGrant)
+	objectPrefix := bucketARN(bucket) + "/"
+
+	for _, s := range policy.Statement {
+		if !isAnonymousPrincipal(s.Principal) {
+			continue
+		}
+		if !s.isAnonymousAllow() || len(s.Condition) > 0 && !isListCondition(s) {
+			other++
+			continue
+		}
+
+		classified := true
+		for _, resource := range s.Resource {
+			if resource == bucketARN(bucket) {
+				// 存储桶级别的操作只是配合对象权限使用
+				continue
+			}
+			prefix, ok := strings.CutPrefix(resource, objectPrefix)
+			if !ok || !strings.HasSuffix(prefix, "*") || strings.ContainsAny(strings.TrimSuffix(prefix, "*"), "*?") {
+				classified = false
+				continue
+			}
+			prefix = strings.TrimSuffix(prefix, "*")
+
+			grant := grants[prefix]
+			grant.read = grant.read || s.allows("s3:GetObject")
+			grant.write = grant.write || s.allows("s3:PutObject")
+			if grant.read || grant.write {
+				grants[prefix] = grant
+			} else {
+				classified = false
+			}
+		}
+		if !classified {
+			other++
+		}
+	}
+	return grants, other
+}
+
+// 语句是否为只允许列出指定前缀的 s3:ListBucket 语句
+func isListCondition(s policyStatement) bool {
+	if len(s.Action) != 1 || s.Action[0] != "s3:ListBucket" || len(s.Condition) != 1 {
+		return false
+	}
+	values, ok := s.Condition["StringEquals"]
+	return ok && len(values) == 1 && len(values["s3:prefix"]) == 1
+}
+
+// 生成前缀的匿名访问语句
+func anonymousStatements(bucket, prefix, permission string) []policyStatement {
+	principal := json.RawMessage(`{"AWS":["*"]}`)
+	var statements []policyStatement
+
+	if permission == anonymousDownload || permission == anonymousPublic {
+		list := policyStatement{Effect: "Allow", Principal: principal, Action: policyValues{"s3:ListBucket"}, Resource: policyValues{bucketARN(bucket)}}
+		if prefix != "" {
+			list.Condition = map[string]map[string]policyValues{"StringEquals": {"s3:prefix": {prefix}}}
+		}
+		statements = append(statements, list,
+			policyStatement{Effect: "Allow", Principal: principal, Action: anonymousReadActions, Resource: policyValues{objectARN(bucket, prefix)}})
+	}
+	if permission == anonymousUpload || permission == anonymousPublic {
+		statements = append(statements,
+			policyStatement{Effect: "Allow", Principal: principal, Action: anonymousWriteActions, Resource: policyValues{objectARN(bucket, prefix)}})
+	}
+	return statements
+}
+
+// 语句是否为生成的存储桶级别辅助语句
+func isAnonymousBucketStatement(s policyStatement, bucket string) bool {
+	if !s.isAnonymousAllow() || len(s.Condition) > 0 || len(s.Resource) != 1 || s.Resource[0] != bucketARN(bucket) {
+		return false
+	}
+	for _, action := range s.Action {
+		if action != "s3:GetBucketLocation" && action != "s3:ListBucketMultipartUploads" {
+			return false
+		}
+	}
+	return true
+}
+
+// 修改前缀的匿名访问权限，替换该前缀已有的匿名访问语句
+func setAnonymousAccess(policy *bucketPolicy, bucket, prefix, permission string) {
+	var kept []policyStatement
+	for _, s := range policy.Statement {
+		if isAnonymousBucketStatement(s, bucket) {
+			continue
+		}
+		if s.isAnonymousAllow() && s.Action.has("s3:ListBucket") && len(s.Action) == 1 && len(s.Resource) == 1 && s.Resource[0] == bucketARN(bucket) {
+			if prefix == "" && len(s.Condition) == 0 || isListCondition(s) && s.Condition["StringEquals"]["s3:prefix"][0] == prefix {
+				continue
+			}
+		}
+		if s.isAnonymousAllow() && len(s.Condition) == 0 && s.Resource.has(objectARN(bucket, prefix)) {
+			s.Resource = slices.DeleteFunc(slices.Clone(s.Resource), func(r string) bool { return r == objectARN(bucket, prefix) })
+			if len(s.Resource) == 0 {
+				continue
+			}
+		}
+		kept = append(kept, s)
+	}
+	kept = append(kept, anonymousStatements(bucket, prefix, permission)...)
+
+	// 按剩余的匿名权限重新生成存储桶级别的语句
+	policy.Statement = kept
+	grants, _ := anonymousGrants(policy, bucket)
+	var read, write bool
+	for _, grant := range grants {
+		read = read || grant.read
+		write = write || grant.write
+	}
+
+	var actions policyValues
+	if read || write {
+		actions = append(actions, "s3:GetBucketLocation")
+	}
+	if write {
+		actions = append(actions, "s3:ListBucketMultipartUploads")
+	}
+	if len(actions) > 0 {
+		bucketStatement := policyStatement{Effect: "Allow", Principal: json.RawMessage(`{"AWS":["*"]}`), Action: actions, Resource: policyValues{bucketARN(bucket)}}
+		policy.Statement = append([]policyStatement{bucketStatement}, kept...)
+	}
+}
+
+// 解析匿名访问命令中的前缀，前缀按目录处理
+func anonymousPrefix(manager *SessionManager, arg string) (string, error) {
+	formatted, err := manager.FormatPath(arg)
+	if err != nil {
+		return "", err
+	}
+
+	prefix := strings.TrimPrefix(path.Clean("/"+formatted), "/")
+	if strings.ContainsAny(prefix, "*?") {
+		return "", fmt.Errorf("前缀 '%s' 不能包含通配符", prefix)
+	}
+	if prefix != "" {
+		prefix += "/"
+	}
+	return prefix, nil
+}
+
+// 设置匿名访问权限操作
+func anonymousSetAction(c *cli.Context) error {
+	permission := c.Args().First()
+	switch permission {
+	case anonymousNone, anonymousDownload, anonymousUpload, anonymousPublic:
+	default:
+		return fmt.Errorf("需要指定权限: download、upload、public 或 none")
+	}
+
+	manager, err := initSessionManager()
+	if err != nil {
+		return err
+	}
+	prefix, err := anonymousPrefix(manager, c.Args().Get(1))
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	client, session, current, err := currentBucketPolicy(ctx)
+	if err != nil {
+		return err
+	}
+
+	policy := &bucketPolicy{Version: "2012-10-17"}
+	if current != "" {
+		if policy, err = parseBucketPolicy([]byte(current), session.BucketName); err != nil {
+			return fmt.Errorf("存储桶现有的策略无效: %w", err)
+		}
+	}
+
+	setAnonymousAccess(policy, session.BucketName, prefix, permission)
+
+	var updated string
+	if len(policy.Statement) > 0 {
+		if err := validateBucketPolicy(policy, session.BucketName); err != nil {
+			return err
+		}
+		data, err := json.Marshal(policy)
+		if err != nil {
+			return fmt.Errorf("无法序列化存储桶策略: %w", err)
+		}
+		updated = string(data)
+	}
+
+	if updated == current {
+		fmt.Printf("/%s 的匿名访问权限已是 %s\n", prefix, permission)
+		return nil
+	}
+
+	if err := saveBucketPolicy(ctx, c, client, session, updated); err != nil {
+		return err
+	}
+	if !isDryRun(c) {
+		fmt.Printf("已将 /%s 的匿名访问权限设为 %s\n", prefix, permission)
+	}
+	return nil
+}
+
+// 列出允许匿名访问的前缀操作
+func anonymousLsAction(c *cli.Context) error {
+	_, session, current, err := currentBucketPolicy(context.Background())
+	if err != nil {
+		return err
+	}
+
+	if current == "" {
+		fmt.Println("没有允许匿名访问的前缀")
+		return nil
+	}
+
+	var policy bucketPolicy
+	if err := json.Unmarshal([]byte(current), &policy); err != nil {
+		return fmt.Errorf("无法解析存储桶策略: %w", err)
+	}
+
+	grants, other := anonymousGrants(&policy, session.BucketName)
+	if len(grants) == 0 {
+		fmt.Println("没有允许匿名访问的前缀")
+	}
+
+	prefixes := make([]string, 0, len(grants))
+	for prefix := range grants {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+
+	for _, prefix := range prefixes {
+		fmt.Printf("%-8s  /%s\n", grants[prefix], prefix)
+	}
+	if other > 0 {
+		fmt.Printf("另有 %d 条匿名访问语句无法按前缀归类，使用 policy get 查看完整策略\n", other)
+	}
+	return nil
+}
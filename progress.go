@@ -3,84 +3,374 @@ package main
 import (
 	"fmt"
 	"io"
+	"os"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
+
+	"github.com/mattn/go-isatty"
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	progressBarWidth    = 20                     // 进度条宽度
+	progressNameWidth   = 24                     // 文件名显示宽度
+	progressMaxBars     = 8                      // 同时显示的文件进度条数量
+	progressTTYInterval = 100 * time.Millisecond // 终端重绘间隔
+	progressLogInterval = 5 * time.Second        // 非终端输出进度日志的间隔
 )
 
-// ProgressBar 表示进度条
+// Progress 汇总多个并发传输的进度，统一负责输出，避免各工作线程的输出相互穿插
+type Progress struct {
+	mu        sync.Mutex
+	out       io.Writer
+	tty       bool
+	quiet     bool
+	bars      []*ProgressBar
+	started   int
+	files     int
+	failures  int
+	total     int64 // 预期总字节数，未知时为 0
+	done      int64 // 已传输字节数，原子操作
+	startTime time.Time
+	lines     int // 上次绘制占用的行数
+	stop      chan struct{}
+	stopped   chan struct{}
+}
+
+// ProgressBar 表示单个文件的传输进度，Total 小于 0 表示大小未知
 type ProgressBar struct {
 	Total     int64
 	Current   int64
-	Width     int
 	FileName  string
 	StartTime time.Time
+
+	base     int64 // 断点续传的起始位置
+	progress *Progress
 }
 
-// Update 更新进度条
-func (p *ProgressBar) Update(n int64) {
-	atomic.AddInt64(&p.Current, n)
-	p.Draw()
+// 创建进度显示，标准输出不是终端时退化为定期输出日志行，--quiet 或 dry-run 时不输出进度
+func newProgress(c *cli.Context) *Progress {
+	p := &Progress{
+		out:       os.Stdout,
+		tty:       isatty.IsTerminal(os.Stdout.Fd()) || isatty.IsCygwinTerminal(os.Stdout.Fd()),
+		quiet:     c.Bool("quiet") || isDryRun(c),
+		startTime: time.Now(),
+		stop:      make(chan struct{}),
+		stopped:   make(chan struct{}),
+	}
+
+	go p.run()
+	return p
+}
+
+// AddTotal 增加预期的总字节数，用于计算整体百分比
+func (p *Progress) AddTotal(n int64) {
+	atomic.AddInt64(&p.total, n)
 }
 
-// Draw 绘制进度条
-func (p *ProgressBar) Draw() {
-	current := atomic.LoadInt64(&p.Current)
+// Start 开始跟踪一个文件的传输
+func (p *Progress) Start(name string, total int64) *ProgressBar {
+	bar := &ProgressBar{
+		Total:     total,
+		FileName:  name,
+		StartTime: time.Now(),
+		progress:  p,
+	}
+
+	p.mu.Lock()
+	p.bars = append(p.bars, bar)
+	p.started++
+	p.mu.Unlock()
 
-	percent := float64(current) / float64(p.Total) * 100
-	if percent > 100 {
-		percent = 100
+	return bar
+}
+
+// Update 更新进度
+func (b *ProgressBar) Update(n int64) {
+	atomic.AddInt64(&b.Current, n)
+	if b.progress != nil {
+		atomic.AddInt64(&b.progress.done, n)
 	}
+}
 
-	// 计算速度
-	elapsed := time.Since(p.StartTime).Seconds()
-	var speed float64
-	if elapsed > 0 {
-		speed = float64(current) / elapsed
+// SetOffset 设置断点续传的起始位置，这部分不计入本次传输量和速度
+func (b *ProgressBar) SetOffset(n int64) {
+	atomic.StoreInt64(&b.base, n)
+	atomic.StoreInt64(&b.Current, n)
+}
+
+// Reset 将进度清零，用于重试时重新开始传输
+func (b *ProgressBar) Reset() {
+	n := atomic.SwapInt64(&b.Current, 0) - atomic.SwapInt64(&b.base, 0)
+	if b.progress == nil {
+		b.StartTime = time.Now()
+		return
 	}
 
-	// 格式化速度
-	var speedStr string
-	if speed < 1024 {
-		speedStr = fmt.Sprintf("%.2f B/s", speed)
-	} else if speed < 1024*1024 {
-		speedStr = fmt.Sprintf("%.2f KB/s", speed/1024)
-	} else if speed < 1024*1024*1024 {
-		speedStr = fmt.Sprintf("%.2f MB/s", speed/(1024*1024))
-	} else {
-		speedStr = fmt.Sprintf("%.2f GB/s", speed/(1024*1024*1024))
+	atomic.AddInt64(&b.progress.done, -n)
+	b.progress.mu.Lock()
+	b.StartTime = time.Now()
+	b.progress.mu.Unlock()
+}
+
+// Done 结束一个文件的传输
+func (b *ProgressBar) Done(err error) {
+	p := b.progress
+	if p == nil {
+		return
 	}
 
-	// 计算剩余时间
-	var etaStr string
-	if speed > 0 {
-		etaSec := float64(p.Total-current) / speed
-		if etaSec < 60 {
-			etaStr = fmt.Sprintf("%.0fs", etaSec)
-		} else if etaSec < 3600 {
-			etaStr = fmt.Sprintf("%dm%ds", int(etaSec)/60, int(etaSec)%60)
-		} else {
-			etaStr = fmt.Sprintf("%dh%dm%ds", int(etaSec)/3600, (int(etaSec)%3600)/60, int(etaSec)%60)
+	p.mu.Lock()
+	for i, bar := range p.bars {
+		if bar == b {
+			p.bars = append(p.bars[:i], p.bars[i+1:]...)
+			break
 		}
+	}
+	if err != nil {
+		p.failures++
 	} else {
-		etaStr = "计算中..."
+		p.files++
+	}
+	p.mu.Unlock()
+}
+
+// Logf 在进度条上方输出一行信息，--quiet 时不输出
+func (p *Progress) Logf(format string, args ...interface{}) {
+	if p.quiet {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.clear()
+	fmt.Fprintf(p.out, format+"\n", args...)
+	p.draw()
+}
+
+// Errorf 在进度条上方向标准错误输出一行错误信息
+func (p *Progress) Errorf(format string, args ...interface{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.clear()
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	p.draw()
+}
+
+// Finish 停止绘制并输出汇总信息
+func (p *Progress) Finish() {
+	close(p.stop)
+	<-p.stopped
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.clear()
+	if p.quiet {
+		return
+	}
+
+	elapsed := time.Since(p.startTime)
+	done := atomic.LoadInt64(&p.done)
+	fmt.Fprintf(p.out, "传输汇总: %d 个文件, %s, 用时 %s, 平均 %s",
+		p.files, formatSize(done), formatDuration(elapsed.Seconds()), formatSpeed(done, elapsed))
+	if p.failures > 0 {
+		fmt.Fprintf(p.out, ", 失败 %d 个", p.failures)
+	}
+	fmt.Fprintln(p.out)
+}
+
+// 后台定期重绘
+func (p *Progress) run() {
+	defer close(p.stopped)
+	if p.quiet {
+		<-p.stop
+		return
+	}
+
+	interval := progressTTYInterval
+	if !p.tty {
+		interval = progressLogInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+		}
+
+		p.mu.Lock()
+		if p.tty {
+			p.clear()
+			p.draw()
+		} else if len(p.bars) > 0 {
+			fmt.Fprintln(p.out, p.summaryLine())
+		}
+		p.mu.Unlock()
+	}
+}
+
+// 清除上次绘制的进度条，调用方需持有锁
+func (p *Progress) clear() {
+	if !p.tty || p.lines == 0 {
+		return
+	}
+	fmt.Fprintf(p.out, "\r\x1b[%dA\x1b[J", p.lines)
+	p.lines = 0
+}
+
+// 绘制总进度和活动文件的进度条，调用方需持有锁
+func (p *Progress) draw() {
+	if !p.tty || p.quiet || len(p.bars) == 0 {
+		return
+	}
+
+	// 超出终端宽度的行会折行，导致无法正确清除，因此截断到终端宽度
+	width := terminalWidth() - 1
+
+	var sb strings.Builder
+	lines := 0
+
+	if p.started > 1 {
+		sb.WriteString(truncateWidth(p.summaryLine(), width))
+		sb.WriteString("\n")
+		lines++
+	}
+
+	for i, bar := range p.bars {
+		if i >= progressMaxBars {
+			fmt.Fprintf(&sb, "  ... 另有 %d 个传输\n", len(p.bars)-i)
+			lines++
+			break
+		}
+		sb.WriteString(truncateWidth(bar.line(), width))
+		sb.WriteString("\n")
+		lines++
+	}
+
+	fmt.Fprint(p.out, sb.String())
+	p.lines = lines
+}
+
+// 总进度行，调用方需持有锁
+func (p *Progress) summaryLine() string {
+	done := atomic.LoadInt64(&p.done)
+	total := atomic.LoadInt64(&p.total)
+	elapsed := time.Since(p.startTime)
+
+	line := fmt.Sprintf("总计: %s %s", renderBar(done, total), formatSize(done))
+	if total > 0 {
+		line += "/" + formatSize(total)
+	}
+	line += fmt.Sprintf(" %s, 已完成 %d 个, 传输中 %d 个", formatSpeed(done, elapsed), p.files, len(p.bars))
+	if p.failures > 0 {
+		line += fmt.Sprintf(", 失败 %d 个", p.failures)
 	}
+	return line
+}
 
-	// 绘制进度条
-	fmt.Printf("\r%s: [", p.FileName)
+// 单个文件的进度行
+func (b *ProgressBar) line() string {
+	current := atomic.LoadInt64(&b.Current)
+	transferred := current - atomic.LoadInt64(&b.base)
+	elapsed := time.Since(b.StartTime)
 
-	completed := int(float64(p.Width) * (float64(current) / float64(p.Total)))
-	for i := 0; i < p.Width; i++ {
-		if i < completed {
-			fmt.Print("=")
-		} else if i == completed {
-			fmt.Print(">")
+	name := []rune(b.FileName)
+	if len(name) > progressNameWidth {
+		name = append([]rune("..."), name[len(name)-progressNameWidth+3:]...)
+	}
+
+	line := fmt.Sprintf("  %-*s %s %s", progressNameWidth, string(name), renderBar(current, b.Total), formatSize(current))
+	if b.Total >= 0 {
+		line += "/" + formatSize(b.Total)
+	}
+	line += " " + formatSpeed(transferred, elapsed)
+
+	// 大小未知时无法估算剩余时间
+	if b.Total > 0 && transferred > 0 && current < b.Total {
+		speed := float64(transferred) / elapsed.Seconds()
+		line += " ETA " + formatDuration(float64(b.Total-current)/speed)
+	}
+	return line
+}
+
+// 绘制进度条和百分比，total 小于等于 0 时只显示占位
+func renderBar(current, total int64) string {
+	if total <= 0 {
+		if total == 0 && current == 0 {
+			return "[" + strings.Repeat("=", progressBarWidth) + "] 100.0%"
+		}
+		return "[" + strings.Repeat("?", progressBarWidth) + "]    ?  "
+	}
+
+	ratio := float64(current) / float64(total)
+	if ratio > 1 {
+		ratio = 1
+	}
+
+	completed := int(ratio * progressBarWidth)
+	bar := strings.Repeat("=", completed)
+	if completed < progressBarWidth {
+		bar += ">" + strings.Repeat(" ", progressBarWidth-completed-1)
+	}
+
+	return fmt.Sprintf("[%s] %5.1f%%", bar, ratio*100)
+}
+
+// 按显示宽度截断字符串，中日韩字符按两列计算
+func truncateWidth(s string, width int) string {
+	w := 0
+	for i, r := range s {
+		rw := 1
+		if r >= 0x1100 {
+			rw = 2
+		}
+		if w+rw > width {
+			return s[:i]
+		}
+		w += rw
+	}
+	return s
+}
+
+// 字符串的显示宽度，中日韩字符按两列计算
+func displayWidth(s string) int {
+	w := 0
+	for _, r := range s {
+		if r >= 0x1100 {
+			w += 2
 		} else {
-			fmt.Print(" ")
+			w++
 		}
 	}
+	return w
+}
 
-	fmt.Printf("] %.2f%% %s %s/s ETA: %s",
-		percent, formatSize(current), speedStr, etaStr)
+// 格式化传输速度
+func formatSpeed(bytes int64, elapsed time.Duration) string {
+	if elapsed <= 0 {
+		return "0 B/s"
+	}
+	return formatSize(int64(float64(bytes)/elapsed.Seconds())) + "/s"
+}
+
+// 格式化秒数，例如 45s、3m20s、1h2m3s
+func formatDuration(sec float64) string {
+	s := int(sec)
+	if s < 60 {
+		return fmt.Sprintf("%ds", s)
+	} else if s < 3600 {
+		return fmt.Sprintf("%dm%ds", s/60, s%60)
+	}
+	return fmt.Sprintf("%dh%dm%ds", s/3600, (s%3600)/60, s%60)
 }
 
 // ProgressReader 是一个带有进度跟踪的包装读取器
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package main
+
+// 获取终端宽度，非 Unix 平台固定为 80
+func terminalWidth() int {
+	return 80
+}
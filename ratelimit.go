@@ -0,0 +1,180 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/urfave/cli/v2"
+)
+
+// RateSchedule 表示配置文件中按时间段生效的限速规则
+type RateSchedule struct {
+	Start string `json:"start"` // 开始时间，例如 "09:00"
+	End   string `json:"end"`   // 结束时间，例如 "18:00"，早于开始时间表示跨越午夜
+	Rate  string `json:"rate"`  // 限速，例如 "5MiB/s"
+}
+
+// 判断时间段是否包含给定时刻
+func (s RateSchedule) contains(now time.Time) (bool, error) {
+	start, err := time.Parse("15:04", s.Start)
+	if err != nil {
+		return false, fmt.Errorf("无效的开始时间 '%s'", s.Start)
+	}
+	end, err := time.Parse("15:04", s.End)
+	if err != nil {
+		return false, fmt.Errorf("无效的结束时间 '%s'", s.End)
+	}
+
+	minute := now.Hour()*60 + now.Minute()
+	startMinute := start.Hour()*60 + start.Minute()
+	endMinute := end.Hour()*60 + end.Minute()
+
+	if startMinute <= endMinute {
+		return minute >= startMinute && minute < endMinute, nil
+	}
+	return minute >= startMinute || minute < endMinute, nil
+}
+
+// 解析限速字符串，例如 "20MiB/s"、"500K"
+func parseRate(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	s = strings.TrimSuffix(strings.TrimSuffix(s, "/s"), "ps")
+	size, err := parseSize(s)
+	if err != nil {
+		return 0, fmt.Errorf("无效的限速 '%s': %w", s, err)
+	}
+	return float64(size), nil
+}
+
+// RateLimiter 是在所有工作线程及上传、下载两个方向间共享的令牌桶
+type RateLimiter struct {
+	mu        sync.Mutex
+	fixed     float64 // --limit-rate 指定的速率，优先于时间段规则
+	schedules []RateSchedule
+	tokens    float64
+	last      time.Time
+}
+
+// 全局限速器，未限速时为 nil
+var rateLimiter *RateLimiter
+
+// 全局已传输字节数，用于自适应并发的吞吐统计
+var transferredBytes int64
+
+// 根据 --limit-rate 和配置中的时间段规则初始化全局限速器，在会传输数据的命令开始前调用
+func setupRateLimiter(c *cli.Context) error {
+	limiter := &RateLimiter{last: time.Now()}
+
+	if s := c.String("limit-rate"); s != "" {
+		rate, err := parseRate(s)
+		if err != nil {
+			return err
+		}
+		limiter.fixed = rate
+	}
+
+	if limiter.fixed == 0 {
+		manager, err := initSessionManager()
+		if err != nil {
+			return err
+		}
+
+		// 提前校验规则，避免传输中途出错
+		for _, schedule := range manager.RateSchedules {
+			if _, err := schedule.contains(time.Now()); err != nil {
+				return fmt.Errorf("配置中的限速规则无效: %w", err)
+			}
+			if _, err := parseRate(schedule.Rate); err != nil {
+				return fmt.Errorf("配置中的限速规则无效: %w", err)
+			}
+		}
+		limiter.schedules = manager.RateSchedules
+	}
+
+	if limiter.fixed > 0 || len(limiter.schedules) > 0 {
+		rateLimiter = limiter
+	}
+
+	return nil
+}
+
+// 当前时刻的限速，0 表示不限速
+func (l *RateLimiter) currentRate(now time.Time) float64 {
+	if l.fixed > 0 {
+		return l.fixed
+	}
+
+	for _, schedule := range l.schedules {
+		if ok, _ := schedule.contains(now); ok {
+			rate, _ := parseRate(schedule.Rate)
+			return rate
+		}
+	}
+
+	return 0
+}
+
+// WaitN 等待直到可以传输 n 个字节
+func (l *RateLimiter) WaitN(n int) {
+	for n > 0 {
+		l.mu.Lock()
+		now := time.Now()
+		rate := l.currentRate(now)
+		if rate <= 0 {
+			l.tokens = 0
+			l.last = now
+			l.mu.Unlock()
+			return
+		}
+
+		// 桶容量为一秒的流量，至少 32 KiB
+		burst := math.Max(rate, 32*1024)
+		l.tokens += now.Sub(l.last).Seconds() * rate
+		if l.tokens > burst {
+			l.tokens = burst
+		}
+		l.last = now
+
+		// 令牌可以预支为负数，后来者需要等待更久
+		take := math.Min(float64(n), burst)
+		l.tokens -= take
+
+		var wait time.Duration
+		if l.tokens < 0 {
+			wait = time.Duration(-l.tokens / rate * float64(time.Second))
+		}
+		l.mu.Unlock()
+
+		n -= int(take)
+		if wait > 0 {
+			time.Sleep(wait)
+		}
+	}
+}
+
+// transferReader 对读取进行限速并统计传输字节数
+type transferReader struct {
+	reader io.Reader
+}
+
+// 包装传输数据流，所有上传和下载都应经过此函数
+func newTransferReader(reader io.Reader) io.Reader {
+	return &transferReader{reader: reader}
+}
+
+// Read 实现 io.Reader 接口
+func (tr *transferReader) Read(p []byte) (int, error) {
+	n, err := tr.reader.Read(p)
+	if n > 0 {
+		atomic.AddInt64(&transferredBytes, int64(n))
+		if rateLimiter != nil {
+			rateLimiter.WaitN(n)
+		}
+	}
+	return n, err
+}
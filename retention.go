@@ -0,0 +1,342 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/urfave/cli/v2"
+)
+
+// ObjectLock 描述对象锁定相关的修改，随计划和失败记录一起保存
+type ObjectLock struct {
+	Mode             string     `json:"mode,omitempty"` // GOVERNANCE 或 COMPLIANCE，为空时清除保留期
+	RetainUntil      *time.Time `json:"retain_until,omitempty"`
+	LegalHold        string     `json:"legal_hold,omitempty"` // ON 或 OFF
+	BypassGovernance bool       `json:"bypass_governance,omitempty"`
+}
+
+// 删除对象的选项，l 为 nil 时不绕过治理模式
+func (l *ObjectLock) removeOptions() minio.RemoveObjectOptions {
+	if l == nil {
+		return minio.RemoveObjectOptions{}
+	}
+	return minio.RemoveObjectOptions{GovernanceBypass: l.BypassGovernance}
+}
+
+// 设置或清除对象的保留期，清除治理模式的保留期需要绕过治理模式
+func (l *ObjectLock) putRetention(ctx context.Context, client *minio.Client, bucket, objectName string) error {
+	opts := minio.PutObjectRetentionOptions{GovernanceBypass: l.BypassGovernance}
+	if l.Mode != "" {
+		mode := minio.RetentionMode(l.Mode)
+		opts.Mode = &mode
+		opts.RetainUntilDate = l.RetainUntil
+	}
+	return client.PutObjectRetention(ctx, bucket, objectName, opts)
+}
+
+// 解析保留模式: governance 或 compliance
+func parseRetentionMode(value string) (minio.RetentionMode, error) {
+	mode := minio.RetentionMode(strings.ToUpper(value))
+	if !mode.IsValid() {
+		return "", fmt.Errorf("无效的保留模式 '%s'，应为 governance 或 compliance", value)
+	}
+	return mode, nil
+}
+
+// 解析保留时长，例如 30d、1y
+func parseValidity(value string) (uint, minio.ValidityUnit, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if len(value) < 2 {
+		return 0, "", fmt.Errorf("无效的保留时长 '%s'，应为天数或年数，例如 30d、1y", value)
+	}
+
+	n, err := strconv.ParseUint(value[:len(value)-1], 10, 32)
+	if err != nil || n == 0 {
+		return 0, "", fmt.Errorf("无效的保留时长 '%s'，应为天数或年数，例如 30d、1y", value)
+	}
+
+	switch value[len(value)-1] {
+	case 'd':
+		return uint(n), minio.Days, nil
+	case 'y':
+		return uint(n), minio.Years, nil
+	}
+	return 0, "", fmt.Errorf("无效的保留时长 '%s'，单位应为 d (天) 或 y (年)", value)
+}
+
+// 保留时长的显示形式
+func formatValidity(validity uint, unit minio.ValidityUnit) string {
+	if unit == minio.Years {
+		return fmt.Sprintf("%d 年", validity)
+	}
+	return fmt.Sprintf("%d 天", validity)
+}
+
+// 从对象信息中读取锁定状态，用于 ls -l 显示: G/C 加保留截止日期，法律保留时加 H，未锁定时返回空字符串
+func objectLockState(info minio.ObjectInfo) string {
+	lockMeta := func(key string) string {
+		if v := userMeta(info, key); v != "" {
+			return v
+		}
+		return info.Metadata.Get(key)
+	}
+
+	var parts []string
+	if until, err := time.Parse(time.RFC3339, lockMeta("X-Amz-Object-Lock-Retain-Until-Date")); err == nil && until.After(time.Now()) {
+		switch minio.RetentionMode(strings.ToUpper(lockMeta("X-Amz-Object-Lock-Mode"))) {
+		case minio.Governance:
+			parts = append(parts, "G:"+until.Local().Format("2006-01-02"))
+		case minio.Compliance:
+			parts = append(parts, "C:"+until.Local().Format("2006-01-02"))
+		}
+	}
+	if strings.EqualFold(lockMeta("X-Amz-Object-Lock-Legal-Hold"), string(minio.LegalHoldEnabled)) {
+		parts = append(parts, "H")
+	}
+	return strings.Join(parts, "+")
+}
+
+// 是否为对象或存储桶没有锁定配置的错误
+// MinIO 对未开启对象锁定的存储桶查询对象的锁定状态时返回 InvalidRequest
+func isNoLockConfig(err error) bool {
+	resp := minio.ToErrorResponse(err)
+	switch resp.Code {
+	case "NoSuchObjectLockConfiguration", "ObjectLockConfigurationNotFoundError":
+		return true
+	case "InvalidRequest":
+		return strings.Contains(resp.Message, "ObjectLockConfiguration")
+	}
+	return false
+}
+
+// 设置保留期操作，--default 时设置存储桶的默认保留规则
+func retentionSetAction(c *cli.Context) error {
+	mode, err := parseRetentionMode(c.String("mode"))
+	if err != nil {
+		return err
+	}
+	if !c.IsSet("validity") {
+		return fmt.Errorf("需要使用 --validity 指定保留时长，例如 30d、1y")
+	}
+	validity, unit, err := parseValidity(c.String("validity"))
+	if err != nil {
+		return err
+	}
+
+	if c.Bool("default") {
+		return setDefaultRetention(c, &mode, &validity, &unit)
+	}
+
+	if c.NArg() < 1 {
+		return fmt.Errorf("需要指定远程文件、目录或通配符，或使用 --default 设置存储桶的默认保留规则")
+	}
+
+	until := time.Now().UTC()
+	if unit == minio.Years {
+		until = until.AddDate(int(validity), 0, 0)
+	} else {
+		until = until.AddDate(0, 0, int(validity))
+	}
+
+	lock := &ObjectLock{Mode: string(mode), RetainUntil: &until, BypassGovernance: c.Bool("bypass-governance")}
+	reason := fmt.Sprintf("%s 至 %s", mode, until.Local().Format("2006-01-02 15:04:05"))
+	return applyObjectAction(c, "retention", c.Args().Slice(), PlanAction{Op: opRetention, Reason: reason, Lock: lock})
+}
+
+// 清除保留期操作，只能清除治理模式的保留期
+func retentionClearAction(c *cli.Context) error {
+	if c.Bool("default") {
+		return setDefaultRetention(c, nil, nil, nil)
+	}
+
+	if c.NArg() < 1 {
+		return fmt.Errorf("需要指定远程文件、目录或通配符，或使用 --default 清除存储桶的默认保留规则")
+	}
+
+	lock := &ObjectLock{BypassGovernance: true}
+	return applyObjectAction(c, "retention", c.Args().Slice(), PlanAction{Op: opRetention, Reason: "清除保留期", Lock: lock})
+}
+
+// 设置存储桶的默认保留规则，参数均为 nil 时清除
+func setDefaultRetention(c *cli.Context, mode *minio.RetentionMode, validity *uint, unit *minio.ValidityUnit) error {
+	manager, err := initSessionManager()
+	if err != nil {
+		return err
+	}
+
+	client, err := manager.GetClient()
+	if err != nil {
+		return err
+	}
+
+	session, err := manager.CurrentSession()
+	if err != nil {
+		return err
+	}
+
+	desc := "清除默认保留规则"
+	if mode != nil {
+		desc = fmt.Sprintf("设置默认保留规则为 %s %s", *mode, formatValidity(*validity, *unit))
+	}
+
+	if isDryRun(c) {
+		fmt.Printf("将%s (bucket: %s)\n", desc, session.BucketName)
+		fmt.Println("dry-run 模式，未执行任何写操作")
+		return nil
+	}
+
+	if err := client.SetObjectLockConfig(context.Background(), session.BucketName, mode, validity, unit); err != nil {
+		return fmt.Errorf("无法设置默认保留规则: %w", err)
+	}
+	fmt.Printf("已%s\n", desc)
+	return nil
+}
+
+// 查看保留期操作，--default 时显示存储桶的对象锁定配置
+func retentionGetAction(c *cli.Context) error {
+	manager, err := initSessionManager()
+	if err != nil {
+		return err
+	}
+
+	client, err := manager.GetClient()
+	if err != nil {
+		return err
+	}
+
+	session, err := manager.CurrentSession()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+
+	if c.Bool("default") {
+		enabled, mode, validity, unit, err := client.GetObjectLockConfig(ctx, session.BucketName)
+		if err != nil {
+			if isNoLockConfig(err) {
+				fmt.Printf("存储桶 %s 未启用对象锁定\n", session.BucketName)
+				return nil
+			}
+			return fmt.Errorf("无法获取对象锁定配置: %w", err)
+		}
+
+		fmt.Printf("对象锁定: %s\n", enabled)
+		if mode != nil && validity != nil && unit != nil {
+			fmt.Printf("默认保留: %s %s\n", *mode, formatValidity(*validity, *unit))
+		} else {
+			fmt.Println("默认保留: 未设置")
+		}
+		return nil
+	}
+
+	if c.NArg() < 1 {
+		return fmt.Errorf("需要指定远程路径，或使用 --default 查看存储桶的默认保留规则")
+	}
+
+	for _, path := range c.Args().Slice() {
+		formattedPath, err := manager.FormatPath(path)
+		if err != nil {
+			return err
+		}
+
+		objects, err := resolveObjects(ctx, client, session.BucketName, strings.TrimPrefix(formattedPath, "/"), c.Bool("r"))
+		if err != nil {
+			return err
+		}
+
+		for _, object := range objects {
+			mode, until, err := client.GetObjectRetention(ctx, session.BucketName, object.Key, "")
+			if err != nil && !isNoLockConfig(err) {
+				return fmt.Errorf("获取保留期失败 '/%s': %w", object.Key, err)
+			}
+
+			if mode == nil || until == nil {
+				fmt.Printf("/%s: 未设置\n", object.Key)
+				continue
+			}
+
+			state := fmt.Sprintf("剩余 %d 天", int(time.Until(*until).Hours()/24))
+			if !until.After(time.Now()) {
+				state = "已过期"
+			}
+			fmt.Printf("/%s: %s 至 %s (%s)\n", object.Key, *mode, until.Local().Format("2006-01-02 15:04:05"), state)
+		}
+	}
+
+	return nil
+}
+
+// 设置法律保留操作
+func legalholdSetAction(c *cli.Context) error {
+	return legalholdUpdate(c, minio.LegalHoldEnabled, "设置法律保留")
+}
+
+// 清除法律保留操作
+func legalholdClearAction(c *cli.Context) error {
+	return legalholdUpdate(c, minio.LegalHoldDisabled, "清除法律保留")
+}
+
+func legalholdUpdate(c *cli.Context, status minio.LegalHoldStatus, reason string) error {
+	if c.NArg() < 1 {
+		return fmt.Errorf("需要指定远程文件、目录或通配符")
+	}
+
+	lock := &ObjectLock{LegalHold: string(status)}
+	return applyObjectAction(c, "legalhold", c.Args().Slice(), PlanAction{Op: opLegalHold, Reason: reason, Lock: lock})
+}
+
+// 查看法律保留操作
+func legalholdInfoAction(c *cli.Context) error {
+	if c.NArg() < 1 {
+		return fmt.Errorf("需要指定远程路径")
+	}
+
+	manager, err := initSessionManager()
+	if err != nil {
+		return err
+	}
+
+	client, err := manager.GetClient()
+	if err != nil {
+		return err
+	}
+
+	session, err := manager.CurrentSession()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+
+	for _, path := range c.Args().Slice() {
+		formattedPath, err := manager.FormatPath(path)
+		if err != nil {
+			return err
+		}
+
+		objects, err := resolveObjects(ctx, client, session.BucketName, strings.TrimPrefix(formattedPath, "/"), c.Bool("r"))
+		if err != nil {
+			return err
+		}
+
+		for _, object := range objects {
+			status, err := client.GetObjectLegalHold(ctx, session.BucketName, object.Key, minio.GetObjectLegalHoldOptions{})
+			if err != nil && !isNoLockConfig(err) {
+				return fmt.Errorf("获取法律保留失败 '/%s': %w", object.Key, err)
+			}
+
+			state := string(minio.LegalHoldDisabled)
+			if status != nil && *status != "" {
+				state = string(*status)
+			}
+			fmt.Printf("/%s: %s\n", object.Key, state)
+		}
+	}
+
+	return nil
+}
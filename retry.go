@@ -0,0 +1,322 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/urfave/cli/v2"
+)
+
+// RetryPolicy 描述失败后的重试策略
+type RetryPolicy struct {
+	Attempts  int           // 最大尝试次数 (含第一次)
+	BaseDelay time.Duration // 第一次重试前的等待时间
+	MaxDelay  time.Duration // 单次等待的上限
+}
+
+// 从命令行参数构建重试策略
+func retryPolicyFromContext(c *cli.Context) RetryPolicy {
+	policy := RetryPolicy{
+		Attempts:  c.Int("retries") + 1,
+		BaseDelay: c.Duration("retry-delay"),
+		MaxDelay:  c.Duration("retry-max-delay"),
+	}
+
+	if policy.Attempts < 1 {
+		policy.Attempts = 1
+	}
+	if policy.BaseDelay <= 0 {
+		policy.BaseDelay = time.Second
+	}
+	if policy.MaxDelay < policy.BaseDelay {
+		policy.MaxDelay = policy.BaseDelay
+	}
+
+	return policy
+}
+
+// Do 执行操作，遇到可重试的错误时按带抖动的指数退避重试，返回实际尝试次数
+func (p RetryPolicy) Do(ctx context.Context, fn func() error) (int, error) {
+	var err error
+	attempt := 0
+
+	for attempt < p.Attempts {
+		attempt++
+		err = fn()
+		if err == nil || !isRetryable(err) || attempt >= p.Attempts {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return attempt, ctx.Err()
+		case <-time.After(p.backoff(attempt)):
+		}
+	}
+
+	return attempt, err
+}
+
+// 计算第 n 次重试前的等待时间 (等待时间的一半固定，另一半随机)
+func (p RetryPolicy) backoff(n int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < n && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// permanentError 标记不应重试的错误，例如数据源只能读取一次
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// 判断错误是否值得重试：超时、5xx、限流和连接中断
+func isRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var permanent permanentError
+	if errors.As(err, &permanent) {
+		return false
+	}
+
+	if errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNABORTED) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, syscall.ETIMEDOUT) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	var resp minio.ErrorResponse
+	if errors.As(err, &resp) {
+		switch resp.Code {
+		case "SlowDown", "SlowDownRead", "SlowDownWrite", "RequestTimeout",
+			"InternalError", "ServiceUnavailable", "XMinioServerNotInitialized":
+			return true
+		}
+		if resp.StatusCode >= 500 || resp.StatusCode == 429 || resp.StatusCode == 408 {
+			return true
+		}
+		return false
+	}
+
+	msg := err.Error()
+	return strings.Contains(msg, "connection reset") ||
+		strings.Contains(msg, "broken pipe") ||
+		strings.Contains(msg, "timeout")
+}
+
+// Failure 记录一个重试后仍然失败的操作
+type Failure struct {
+	PlanAction
+	Error    string    `json:"error"`
+	Attempts int       `json:"attempts"`
+	Time     time.Time `json:"time"`
+}
+
+// FailureReport 收集一次命令执行中的失败操作，可由 minx retry 重放
+type FailureReport struct {
+	Command   string    `json:"command"`
+	Endpoint  string    `json:"endpoint"`
+	Bucket    string    `json:"bucket"`
+	CreatedAt time.Time `json:"created_at"`
+	Failures  []Failure `json:"failures"`
+	Errors    []string  `json:"errors,omitempty"` // 无法重放的错误，例如列出对象失败
+
+	mu sync.Mutex
+}
+
+// 创建失败记录
+func newFailureReport(command string, session *Session) *FailureReport {
+	return &FailureReport{
+		Command:   command,
+		Endpoint:  session.Endpoint,
+		Bucket:    session.BucketName,
+		CreatedAt: time.Now(),
+	}
+}
+
+// Add 记录一个失败的操作，可在多个工作线程中并发调用
+func (r *FailureReport) Add(action PlanAction, attempts int, err error) {
+	r.mu.Lock()
+	r.Failures = append(r.Failures, Failure{
+		PlanAction: action,
+		Error:      err.Error(),
+		Attempts:   attempts,
+		Time:       time.Now(),
+	})
+	r.mu.Unlock()
+}
+
+// AddError 记录一个无法重放的错误
+func (r *FailureReport) AddError(err error) {
+	r.mu.Lock()
+	r.Errors = append(r.Errors, err.Error())
+	r.mu.Unlock()
+}
+
+// Count 返回失败总数
+func (r *FailureReport) Count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.Failures) + len(r.Errors)
+}
+
+// Save 将失败记录写入 JSON 文件
+func (r *FailureReport) Save(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("无法序列化失败记录: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("无法写入失败记录: %w", err)
+	}
+
+	return nil
+}
+
+// 默认的失败记录文件路径: ~/.minx/failures/<command>-<time>.json
+func defaultFailuresPath(command string) (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("无法获取用户主目录: %w", err)
+	}
+
+	dir := filepath.Join(homeDir, ".minx", "failures")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("无法创建失败记录目录: %w", err)
+	}
+
+	name := fmt.Sprintf("%s-%s.json", command, time.Now().Format("20060102-150405"))
+	return filepath.Join(dir, name), nil
+}
+
+// Finish 在存在失败时保存失败记录并返回错误，使命令以非零状态退出
+func (r *FailureReport) Finish(c *cli.Context) error {
+	count := r.Count()
+	if count == 0 {
+		return nil
+	}
+
+	path := c.String("failures-out")
+	if path == "" {
+		var err error
+		path, err = defaultFailuresPath(r.Command)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := r.Save(path); err != nil {
+		return err
+	}
+
+	if len(r.Failures) > 0 {
+		return fmt.Errorf("%d 个操作失败，失败记录已保存到 %s (使用 minx retry %s 重试)", count, path, path)
+	}
+	return fmt.Errorf("%d 个操作失败，失败记录已保存到 %s", count, path)
+}
+
+// 加载失败记录
+func loadFailureReport(path string) (*FailureReport, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("无法读取失败记录: %w", err)
+	}
+
+	report := &FailureReport{}
+	if err := json.Unmarshal(data, report); err != nil {
+		return nil, fmt.Errorf("无法解析失败记录: %w", err)
+	}
+
+	return report, nil
+}
+
+// 重放失败记录操作
+func retryAction(c *cli.Context) error {
+	if c.NArg() < 1 {
+		return fmt.Errorf("需要指定失败记录文件")
+	}
+
+	manager, err := initSessionManager()
+	if err != nil {
+		return err
+	}
+
+	client, err := manager.GetClient()
+	if err != nil {
+		return err
+	}
+
+	session, err := manager.CurrentSession()
+	if err != nil {
+		return err
+	}
+
+	report, err := loadFailureReport(c.Args().First())
+	if err != nil {
+		return err
+	}
+
+	if report.Bucket != session.BucketName || report.Endpoint != session.Endpoint {
+		return fmt.Errorf("失败记录属于 %s/%s，与当前会话 %s/%s 不一致",
+			report.Bucket, report.Endpoint, session.BucketName, session.Endpoint)
+	}
+
+	fmt.Printf("重试 %s 的失败操作，共 %d 个\n", report.Command, len(report.Failures))
+
+	ctx := context.Background()
+	policy := retryPolicyFromContext(c)
+	failures := newFailureReport(report.Command, session)
+	var done int
+
+	for _, failure := range report.Failures {
+		action := failure.PlanAction
+
+		target := action.Target
+		if action.Source != "" {
+			target = action.Source + " -> " + action.Target
+		}
+		fmt.Printf("%s: %s\n", opLabels[action.Op], target)
+
+		attempts, err := runPlanAction(ctx, policy, client, session.BucketName, action)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s失败 '%s': %v\n", opLabels[action.Op], target, err)
+			failures.Add(action, attempts, err)
+			continue
+		}
+		done++
+	}
+
+	fmt.Printf("重试完成: 成功 %d 个, 失败 %d 个\n", done, failures.Count())
+	return failures.Finish(c)
+}
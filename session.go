@@ -19,12 +19,18 @@ type Session struct {
 	SecretKey   string `json:"secret_key"`
 	BucketName  string `json:"bucket_name"`
 	CurrentPath string `json:"current_path"`
+
+	ContentTypes []ContentTypeRule `json:"content_types,omitempty"` // Content-Type 覆盖规则，项目配置 .minx.json 优先
+	SSE          string            `json:"sse,omitempty"`           // 默认加密方式，格式同 --sse
+	SSERules     []SSERule         `json:"sse_rules,omitempty"`     // 强制加密的前缀规则
+	Trash        string            `json:"trash,omitempty"`         // 回收站前缀，为空时删除和覆盖立即生效
 }
 
 // SessionManager 管理所有会话
 type SessionManager struct {
 	Sessions      map[string]Session `json:"sessions"`
 	CurrentName   string             `json:"current_name"`
+	RateSchedules []RateSchedule     `json:"rate_schedules,omitempty"`
 	ConfigPath    string             `json:"-"`
 	currentClient *minio.Client      `json:"-"`
 }
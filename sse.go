@@ -0,0 +1,280 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/minio/minio-go/v7/pkg/encrypt"
+	"github.com/urfave/cli/v2"
+)
+
+// SSERule 要求指定前缀下的对象始终以指定方式加密写入
+type SSERule struct {
+	Prefix string `json:"prefix"` // 对象名前缀，不含开头的 "/"
+	SSE    string `json:"sse"`    // 加密方式，格式同 --sse
+}
+
+// 加密参数，读取时只有 SSE-C 需要提供密钥
+func sseFlag() cli.Flag {
+	return &cli.StringFlag{
+		Name:  "sse",
+		Usage: "服务端加密: s3、kms:<密钥ID> 或 c:<密钥文件> (SSE-C 读取和复制时也需要提供)",
+	}
+}
+
+// 解析加密方式: s3、kms:<密钥ID>、c:<密钥文件>，off 或空字符串表示不加密
+func parseSSE(spec string) (encrypt.ServerSide, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" || spec == "off" {
+		return nil, nil
+	}
+
+	kind, arg, _ := strings.Cut(spec, ":")
+	switch strings.ToLower(kind) {
+	case "s3":
+		return encrypt.NewSSE(), nil
+
+	case "kms":
+		if arg == "" {
+			return nil, fmt.Errorf("SSE-KMS 需要指定密钥 ID，例如 kms:my-key")
+		}
+		sse, err := encrypt.NewSSEKMS(arg, nil)
+		if err != nil {
+			return nil, fmt.Errorf("无效的 KMS 密钥: %w", err)
+		}
+		return sse, nil
+
+	case "c":
+		if arg == "" {
+			return nil, fmt.Errorf("SSE-C 需要指定密钥文件，例如 c:~/.minx/key")
+		}
+		key, err := loadSSECKey(arg)
+		if err != nil {
+			return nil, err
+		}
+		return encrypt.NewSSEC(key)
+
+	default:
+		return nil, fmt.Errorf("无效的加密方式 '%s'，应为 s3、kms:<密钥ID> 或 c:<密钥文件>", spec)
+	}
+}
+
+// 读取 SSE-C 密钥文件，内容可以是 32 字节原始密钥、64 位十六进制或 Base64
+func loadSSECKey(path string) ([]byte, error) {
+	if strings.HasPrefix(path, "~/") {
+		if homeDir, err := os.UserHomeDir(); err == nil {
+			path = homeDir + path[1:]
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("无法读取密钥文件: %w", err)
+	}
+
+	if len(data) == 32 {
+		return data, nil
+	}
+
+	text := strings.TrimSpace(string(data))
+	if key, err := hex.DecodeString(text); err == nil && len(key) == 32 {
+		return key, nil
+	}
+	if key, err := base64.StdEncoding.DecodeString(text); err == nil && len(key) == 32 {
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("密钥文件 '%s' 应包含 32 字节密钥 (原始、十六进制或 Base64)", path)
+}
+
+// 查找对象适用的前缀规则，多条规则匹配时使用最长的前缀
+func matchSSERule(session *Session, objectName string) *SSERule {
+	var best *SSERule
+	for i, rule := range session.SSERules {
+		if strings.HasPrefix(objectName, rule.Prefix) && (best == nil || len(rule.Prefix) > len(best.Prefix)) {
+			best = &session.SSERules[i]
+		}
+	}
+	return best
+}
+
+// 确定写入对象时使用的加密方式: 前缀规则 > --sse > 会话默认值
+// 前缀规则是强制的，--sse 与之冲突时报错
+func writeSSESpec(flagSpec, objectName string) (string, error) {
+	var session *Session
+	if manager != nil {
+		session, _ = manager.CurrentSession()
+	}
+	if session == nil {
+		return flagSpec, nil
+	}
+
+	if rule := matchSSERule(session, objectName); rule != nil {
+		if flagSpec != "" && flagSpec != rule.SSE {
+			return "", fmt.Errorf("前缀 '/%s' 要求使用 %s 加密，与 --sse %s 冲突", rule.Prefix, rule.SSE, flagSpec)
+		}
+		return rule.SSE, nil
+	}
+
+	if flagSpec != "" {
+		return flagSpec, nil
+	}
+	return session.SSE, nil
+}
+
+// 写入对象时使用的加密选项，不加密时返回 nil
+func writeEncryption(flagSpec, objectName string) (encrypt.ServerSide, error) {
+	spec, err := writeSSESpec(flagSpec, objectName)
+	if err != nil {
+		return nil, err
+	}
+	return parseSSE(spec)
+}
+
+// 读取对象时使用的加密选项，只有 SSE-C 需要在读取时提供密钥，其他情况返回 nil
+func readEncryption(flagSpec, objectName string) (encrypt.ServerSide, error) {
+	spec := flagSpec
+	if spec == "" {
+		var err error
+		if spec, err = writeSSESpec("", objectName); err != nil {
+			return nil, err
+		}
+	}
+
+	if !strings.HasPrefix(strings.ToLower(spec), "c:") {
+		return nil, nil
+	}
+	return parseSSE(spec)
+}
+
+// 复制对象时使用的加密选项: srcSpec 用于读取 SSE-C 加密的源对象，未指定时与 dstSpec 相同
+func copyEncryption(srcSpec, dstSpec, src, dst string) (encrypt.ServerSide, encrypt.ServerSide, error) {
+	if srcSpec == "" {
+		srcSpec = dstSpec
+	}
+
+	srcSSE, err := readEncryption(srcSpec, src)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	dstSSE, err := writeEncryption(dstSpec, dst)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return srcSSE, dstSSE, nil
+}
+
+// 显示当前会话的加密设置
+func sseShowAction(c *cli.Context) error {
+	manager, err := initSessionManager()
+	if err != nil {
+		return err
+	}
+
+	session, err := manager.CurrentSession()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("会话 %s 的加密设置:\n", manager.CurrentName)
+	if session.SSE == "" {
+		fmt.Println("  默认: 不加密")
+	} else {
+		fmt.Printf("  默认: %s\n", session.SSE)
+	}
+
+	if len(session.SSERules) == 0 {
+		fmt.Println("  前缀规则: 无")
+		return nil
+	}
+
+	fmt.Println("  前缀规则:")
+	for _, rule := range session.SSERules {
+		fmt.Printf("    /%s -> %s\n", rule.Prefix, rule.SSE)
+	}
+	return nil
+}
+
+// 设置当前会话的默认加密方式: minx sse default <方式|off>
+func sseDefaultAction(c *cli.Context) error {
+	if c.NArg() < 1 {
+		return fmt.Errorf("需要指定加密方式 (s3、kms:<密钥ID>、c:<密钥文件> 或 off)")
+	}
+
+	spec := c.Args().First()
+	if _, err := parseSSE(spec); err != nil {
+		return err
+	}
+	if spec == "off" {
+		spec = ""
+	}
+
+	return updateCurrentSession(func(session *Session) {
+		session.SSE = spec
+	})
+}
+
+// 设置或删除前缀规则: minx sse rule <前缀> <方式|off>
+func sseRuleAction(c *cli.Context) error {
+	if c.NArg() < 2 {
+		return fmt.Errorf("需要指定前缀和加密方式 (s3、kms:<密钥ID>、c:<密钥文件> 或 off)")
+	}
+
+	manager, err := initSessionManager()
+	if err != nil {
+		return err
+	}
+
+	formattedPath, err := manager.FormatPath(c.Args().First())
+	if err != nil {
+		return err
+	}
+	prefix := strings.TrimPrefix(formattedPath, "/")
+
+	spec := c.Args().Get(1)
+	if _, err := parseSSE(spec); err != nil {
+		return err
+	}
+
+	return updateCurrentSession(func(session *Session) {
+		rules := session.SSERules[:0:0]
+		for _, rule := range session.SSERules {
+			if rule.Prefix != prefix {
+				rules = append(rules, rule)
+			}
+		}
+		if spec != "off" {
+			rules = append(rules, SSERule{Prefix: prefix, SSE: spec})
+		}
+		sort.Slice(rules, func(i, j int) bool { return rules[i].Prefix < rules[j].Prefix })
+		session.SSERules = rules
+	})
+}
+
+// 修改当前会话并保存配置
+func updateCurrentSession(update func(session *Session)) error {
+	manager, err := initSessionManager()
+	if err != nil {
+		return err
+	}
+
+	session, err := manager.CurrentSession()
+	if err != nil {
+		return err
+	}
+
+	update(session)
+	manager.Sessions[manager.CurrentName] = *session
+	if err := manager.Save(); err != nil {
+		return err
+	}
+
+	fmt.Println("加密设置已更新")
+	return nil
+}
@@ -0,0 +1,359 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/encrypt"
+	"github.com/urfave/cli/v2"
+)
+
+// ObjectStat 汇总一个对象的元数据，同时用于文本和 JSON 输出
+type ObjectStat struct {
+	Key                string            `json:"key"`
+	Size               int64             `json:"size"`
+	OriginalSize       *int64            `json:"original_size,omitempty"` // 压缩或加密前的大小，与存储大小不同时才有
+	Compression        string            `json:"compression,omitempty"`
+	LastModified       time.Time         `json:"last_modified"`
+	ETag               string            `json:"etag"`
+	ContentType        string            `json:"content_type,omitempty"`
+	StorageClass       string            `json:"storage_class,omitempty"`
+	VersionID          string            `json:"version_id,omitempty"`
+	Headers            map[string]string `json:"headers,omitempty"` // Cache-Control、Content-Encoding 等标准头
+	UserMetadata       map[string]string `json:"user_metadata,omitempty"`
+	Tags               map[string]string `json:"tags,omitempty"`
+	Encryption         string            `json:"encryption,omitempty"`
+	ClientEncryption   string            `json:"client_encryption,omitempty"` // 客户端加密的信封摘要
+	Checksums          map[string]string `json:"checksums,omitempty"`
+	ReplicationStatus  string            `json:"replication_status,omitempty"`
+	RetentionMode      string            `json:"retention_mode,omitempty"`
+	RetainUntil        *time.Time        `json:"retain_until,omitempty"`
+	LegalHold          string            `json:"legal_hold,omitempty"`
+	Expiration         *time.Time        `json:"expiration,omitempty"`
+	ExpirationRuleID   string            `json:"expiration_rule_id,omitempty"`
+	Expires            *time.Time        `json:"expires,omitempty"`
+	WebsiteRedirection string            `json:"website_redirect,omitempty"`
+
+	sse encrypt.ServerSide // 可在重写对象时沿用的加密方式 (SSE-S3 或 SSE-KMS)
+}
+
+// 以文本形式输出的标准头，按此顺序显示
+var statHeaders = []string{"Cache-Control", "Content-Encoding", "Content-Language", "Content-Disposition"}
+
+// 获取对象的完整元数据
+// 读取 SSE-C 加密的对象时需要提供 sse
+func statObject(ctx context.Context, client *minio.Client, bucket, objectName string, sse encrypt.ServerSide) (*ObjectStat, error) {
+	info, err := client.StatObject(ctx, bucket, objectName, minio.StatObjectOptions{ServerSideEncryption: sse, Checksum: true})
+	if err != nil {
+		return nil, err
+	}
+
+	stat := &ObjectStat{
+		Key:                info.Key,
+		Size:               info.Size,
+		LastModified:       info.LastModified,
+		ETag:               info.ETag,
+		ContentType:        info.ContentType,
+		StorageClass:       info.StorageClass,
+		VersionID:          info.VersionID,
+		ReplicationStatus:  info.ReplicationStatus,
+		ExpirationRuleID:   info.ExpirationRuleID,
+		WebsiteRedirection: info.Metadata.Get("X-Amz-Website-Redirect-Location"),
+	}
+	if stat.Key == "" {
+		stat.Key = objectName
+	}
+	if !info.Expiration.IsZero() {
+		stat.Expiration = &info.Expiration
+	}
+	if !info.Expires.IsZero() {
+		stat.Expires = &info.Expires
+	}
+
+	for _, name := range statHeaders {
+		if v := info.Metadata.Get(name); v != "" {
+			if stat.Headers == nil {
+				stat.Headers = make(map[string]string)
+			}
+			stat.Headers[name] = v
+		}
+	}
+
+	if len(info.UserMetadata) > 0 {
+		stat.UserMetadata = make(map[string]string, len(info.UserMetadata))
+		for k, v := range info.UserMetadata {
+			stat.UserMetadata[k] = v
+		}
+	}
+
+	stat.ClientEncryption = describeEnvelope(info)
+	stat.Compression = compressionOf(info)
+	if stat.Compression != "" || stat.ClientEncryption != "" {
+		size := newDecoder(nil).PlainSize(info)
+		stat.OriginalSize = &size
+	}
+
+	// 加密状态: SSE-S3 为 AES256，SSE-KMS 附带密钥 ID，SSE-C 只返回算法
+	switch {
+	case info.Metadata.Get("X-Amz-Server-Side-Encryption-Customer-Algorithm") != "":
+		stat.Encryption = "SSE-C (" + info.Metadata.Get("X-Amz-Server-Side-Encryption-Customer-Algorithm") + ")"
+	case info.Metadata.Get("X-Amz-Server-Side-Encryption") == "aws:kms":
+		stat.Encryption = "SSE-KMS"
+		if keyID := info.Metadata.Get("X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id"); keyID != "" {
+			stat.Encryption += " (" + keyID + ")"
+			// MinIO 返回 arn:aws:kms:<密钥名>，AWS 返回完整 ARN，可直接使用
+			if name := strings.TrimPrefix(keyID, "arn:aws:kms:"); !strings.Contains(name, ":") {
+				keyID = name
+			}
+			stat.sse, _ = encrypt.NewSSEKMS(keyID, nil)
+		}
+	case info.Metadata.Get("X-Amz-Server-Side-Encryption") != "":
+		stat.Encryption = "SSE-S3 (" + info.Metadata.Get("X-Amz-Server-Side-Encryption") + ")"
+		stat.sse = encrypt.NewSSE()
+	}
+
+	checksums := map[string]string{
+		"CRC32":     info.ChecksumCRC32,
+		"CRC32C":    info.ChecksumCRC32C,
+		"CRC64NVME": info.ChecksumCRC64NVME,
+		"SHA1":      info.ChecksumSHA1,
+		"SHA256":    info.ChecksumSHA256,
+	}
+	for name, v := range checksums {
+		if v == "" {
+			continue
+		}
+		if stat.Checksums == nil {
+			stat.Checksums = make(map[string]string)
+		}
+		stat.Checksums[name] = v
+	}
+
+	// 标签: MinIO 在 StatObject 中直接返回，其他服务只返回数量，需要单独获取
+	if len(info.UserTags) > 0 {
+		stat.Tags = make(map[string]string, len(info.UserTags))
+		for k, v := range info.UserTags {
+			stat.Tags[k] = v
+		}
+	} else if info.UserTagCount > 0 {
+		t, err := client.GetObjectTagging(ctx, bucket, objectName, minio.GetObjectTaggingOptions{VersionID: info.VersionID})
+		if err != nil {
+			return nil, fmt.Errorf("获取标签失败: %w", err)
+		}
+		stat.Tags = t.ToMap()
+	}
+
+	// 对象锁定: 优先使用响应头，未返回时再单独查询，存储桶未开启对象锁定时忽略错误
+	stat.RetentionMode = info.Metadata.Get("X-Amz-Object-Lock-Mode")
+	if until := info.Metadata.Get("X-Amz-Object-Lock-Retain-Until-Date"); until != "" {
+		if t, err := time.Parse(time.RFC3339, until); err == nil {
+			stat.RetainUntil = &t
+		}
+	}
+	if stat.RetentionMode == "" {
+		if mode, until, err := client.GetObjectRetention(ctx, bucket, objectName, info.VersionID); err == nil && mode != nil {
+			stat.RetentionMode = mode.String()
+			stat.RetainUntil = until
+		}
+	}
+
+	// 法律保留与保留期相互独立，没有保留期的对象也可能处于法律保留
+	// 与保留期相同，查询失败 (未开启对象锁定、没有权限等) 时不显示，不影响其他操作
+	stat.LegalHold = info.Metadata.Get("X-Amz-Object-Lock-Legal-Hold")
+	if stat.LegalHold == "" {
+		if status, err := client.GetObjectLegalHold(ctx, bucket, objectName, minio.GetObjectLegalHoldOptions{VersionID: info.VersionID}); err == nil && status != nil {
+			stat.LegalHold = string(*status)
+		}
+	}
+
+	return stat, nil
+}
+
+// 以文本形式输出对象元数据
+func printObjectStat(stat *ObjectStat) {
+	fmt.Printf("名称:         %s\n", stat.Key)
+	fmt.Printf("  大小:       %s (%d 字节)\n", formatSize(stat.Size), stat.Size)
+	if stat.OriginalSize != nil {
+		if *stat.OriginalSize < 0 {
+			fmt.Printf("  原始大小:   未知\n")
+		} else {
+			fmt.Printf("  原始大小:   %s (%d 字节)\n", formatSize(*stat.OriginalSize), *stat.OriginalSize)
+		}
+	}
+	if stat.Compression != "" {
+		fmt.Printf("  压缩:       %s\n", stat.Compression)
+	}
+	fmt.Printf("  修改时间:   %s\n", stat.LastModified.Local().Format("2006-01-02 15:04:05"))
+	fmt.Printf("  ETag:       %s\n", stat.ETag)
+	fmt.Printf("  类型:       %s\n", stat.ContentType)
+
+	if stat.StorageClass != "" {
+		fmt.Printf("  存储类型:   %s\n", stat.StorageClass)
+	}
+	if stat.VersionID != "" {
+		fmt.Printf("  版本 ID:    %s\n", stat.VersionID)
+	}
+	if stat.Encryption != "" {
+		fmt.Printf("  加密:       %s\n", stat.Encryption)
+	} else {
+		fmt.Printf("  加密:       无\n")
+	}
+	if stat.ClientEncryption != "" {
+		fmt.Printf("  客户端加密: %s\n", stat.ClientEncryption)
+	}
+	if stat.ReplicationStatus != "" {
+		fmt.Printf("  复制状态:   %s\n", stat.ReplicationStatus)
+	}
+	if stat.RetentionMode != "" {
+		until := ""
+		if stat.RetainUntil != nil {
+			until = "，保留至 " + stat.RetainUntil.Local().Format("2006-01-02 15:04:05")
+		}
+		fmt.Printf("  保留策略:   %s%s\n", stat.RetentionMode, until)
+	}
+	if stat.LegalHold != "" {
+		fmt.Printf("  法律保留:   %s\n", stat.LegalHold)
+	}
+	if stat.Expiration != nil {
+		fmt.Printf("  过期时间:   %s (规则 %s)\n", stat.Expiration.Local().Format("2006-01-02 15:04:05"), stat.ExpirationRuleID)
+	}
+	if stat.Expires != nil {
+		fmt.Printf("  缓存过期:   %s\n", stat.Expires.Local().Format("2006-01-02 15:04:05"))
+	}
+	if stat.WebsiteRedirection != "" {
+		fmt.Printf("  重定向:     %s\n", stat.WebsiteRedirection)
+	}
+
+	for _, name := range statHeaders {
+		if v, ok := stat.Headers[name]; ok {
+			fmt.Printf("  %s: %s\n", name, v)
+		}
+	}
+
+	printStatMap("校验和", stat.Checksums)
+	printStatMap("用户元数据", stat.UserMetadata)
+	printStatMap("标签", stat.Tags)
+}
+
+// 按键排序输出键值对
+func printStatMap(title string, m map[string]string) {
+	if len(m) == 0 {
+		return
+	}
+
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	fmt.Printf("  %s:\n", title)
+	for _, k := range keys {
+		fmt.Printf("    %s = %s\n", k, m[k])
+	}
+}
+
+// 显示对象元数据操作
+func statAction(c *cli.Context) error {
+	if c.NArg() < 1 {
+		return fmt.Errorf("需要指定远程文件或目录")
+	}
+
+	manager, err := initSessionManager()
+	if err != nil {
+		return err
+	}
+
+	client, err := manager.GetClient()
+	if err != nil {
+		return err
+	}
+
+	session, err := manager.CurrentSession()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	asJSON := c.Bool("json")
+	failures := newFailureReport("stat", session)
+
+	// JSON 模式下每行输出一个对象，便于流式处理
+	output := func(stat *ObjectStat) error {
+		if !asJSON {
+			printObjectStat(stat)
+			return nil
+		}
+		data, err := json.Marshal(stat)
+		if err != nil {
+			return fmt.Errorf("无法序列化元数据: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	for _, path := range c.Args().Slice() {
+		formattedPath, err := manager.FormatPath(path)
+		if err != nil {
+			return err
+		}
+
+		objectName := strings.TrimPrefix(formattedPath, "/")
+
+		if !c.Bool("r") {
+			sse, err := readEncryption(c.String("sse"), objectName)
+			if err != nil {
+				return err
+			}
+
+			stat, err := statObject(ctx, client, session.BucketName, objectName, sse)
+			if err != nil {
+				return fmt.Errorf("无法获取 '%s' 的元数据: %w", formattedPath, err)
+			}
+			if err := output(stat); err != nil {
+				return err
+			}
+			continue
+		}
+
+		prefix := objectName
+		if prefix != "" && !strings.HasSuffix(prefix, "/") {
+			prefix += "/"
+		}
+
+		for object := range client.ListObjects(ctx, session.BucketName, minio.ListObjectsOptions{
+			Prefix:    prefix,
+			Recursive: true,
+		}) {
+			if object.Err != nil {
+				return fmt.Errorf("列出对象失败: %w", object.Err)
+			}
+			if strings.HasSuffix(object.Key, "/") {
+				continue
+			}
+
+			sse, err := readEncryption(c.String("sse"), object.Key)
+			if err != nil {
+				return err
+			}
+
+			stat, err := statObject(ctx, client, session.BucketName, object.Key, sse)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "无法获取 '/%s' 的元数据: %v\n", object.Key, err)
+				failures.AddError(fmt.Errorf("无法获取 '/%s' 的元数据: %w", object.Key, err))
+				continue
+			}
+			if err := output(stat); err != nil {
+				return err
+			}
+		}
+	}
+
+	return failures.Finish(c)
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/encrypt"
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	defaultPartSize = 16 * 1024 * 1024 // 未知长度上传的默认分片大小
+	minPartSize     = 5 * 1024 * 1024  // S3 允许的最小分片大小
+)
+
+// byteRange 表示要读取的字节范围
+type byteRange struct {
+	Start  int64
+	End    int64 // 包含在内，-1 表示直到对象末尾
+	Suffix int64 // 大于 0 时表示读取最后 Suffix 个字节
+}
+
+// 根据 --range 或 --offset/--length 参数解析字节范围，未指定时返回 nil
+func parseRangeFlags(c *cli.Context) (*byteRange, error) {
+	if spec := c.String("range"); spec != "" {
+		if c.IsSet("offset") || c.IsSet("length") {
+			return nil, fmt.Errorf("--range 不能与 --offset/--length 同时使用")
+		}
+
+		// 支持 start-end、start- 和 -N 三种格式
+		startStr, endStr, ok := strings.Cut(spec, "-")
+		if !ok {
+			return nil, fmt.Errorf("无效的范围 '%s'，应为 start-end、start- 或 -N", spec)
+		}
+
+		if startStr == "" {
+			suffix, err := parseSize(endStr)
+			if err != nil || suffix <= 0 {
+				return nil, fmt.Errorf("无效的范围 '%s'", spec)
+			}
+			return &byteRange{Suffix: suffix}, nil
+		}
+
+		start, err := parseSize(startStr)
+		if err != nil {
+			return nil, fmt.Errorf("无效的范围 '%s': %w", spec, err)
+		}
+
+		r := &byteRange{Start: start, End: -1}
+		if endStr != "" {
+			end, err := parseSize(endStr)
+			if err != nil || end < start {
+				return nil, fmt.Errorf("无效的范围 '%s'", spec)
+			}
+			r.End = end
+		}
+		return r, nil
+	}
+
+	if !c.IsSet("offset") && !c.IsSet("length") {
+		return nil, nil
+	}
+
+	r := &byteRange{End: -1}
+	if s := c.String("offset"); s != "" {
+		offset, err := parseSize(s)
+		if err != nil {
+			return nil, fmt.Errorf("无效的偏移量: %w", err)
+		}
+		r.Start = offset
+	}
+	if s := c.String("length"); s != "" {
+		length, err := parseSize(s)
+		if err != nil || length <= 0 {
+			return nil, fmt.Errorf("无效的长度 '%s'", s)
+		}
+		r.End = r.Start + length - 1
+	}
+
+	return r, nil
+}
+
+// 根据对象大小计算实际的起始位置和长度，大小未知 (小于 0) 时长度为 -1 表示直到末尾
+func (r *byteRange) resolve(size int64) (int64, int64) {
+	if r == nil {
+		return 0, size
+	}
+
+	if size < 0 {
+		if r.End < 0 {
+			return r.Start, -1
+		}
+		return r.Start, r.End - r.Start + 1
+	}
+
+	if r.Suffix > 0 {
+		if r.Suffix > size {
+			return 0, size
+		}
+		return size - r.Suffix, r.Suffix
+	}
+
+	if r.Start >= size {
+		return size, 0
+	}
+
+	end := r.End
+	if end < 0 || end >= size {
+		end = size - 1
+	}
+	return r.Start, end - r.Start + 1
+}
+
+// 将对象的指定范围写入 w，重试时从已写入的位置继续
+// 加密或压缩的对象由 dec 还原，start 和 length 均按还原后的内容计算，length 小于 0 表示直到末尾
+func streamObject(ctx context.Context, client *minio.Client, bucket string, info minio.ObjectInfo, sse encrypt.ServerSide, dec *Decoder, start, length int64, w io.Writer, policy RetryPolicy) error {
+	var written int64
+
+	_, err := policy.Do(ctx, func() error {
+		if length >= 0 && written >= length {
+			return nil
+		}
+
+		remaining := int64(-1)
+		if length >= 0 {
+			remaining = length - written
+		}
+
+		obj, err := dec.Open(ctx, client, bucket, info, sse, start+written, remaining)
+		if err != nil {
+			return err
+		}
+		defer obj.Close()
+
+		n, err := io.Copy(w, newTransferReader(obj))
+		written += n
+		return err
+	})
+
+	return err
+}
+
+// 将对象内容输出到标准输出
+func catObjects(c *cli.Context, paths []string) error {
+	manager, err := initSessionManager()
+	if err != nil {
+		return err
+	}
+
+	client, err := manager.GetClient()
+	if err != nil {
+		return err
+	}
+
+	session, err := manager.CurrentSession()
+	if err != nil {
+		return err
+	}
+
+	r, err := parseRangeFlags(c)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	policy := retryPolicyFromContext(c)
+	dec := newDecoder(c)
+
+	out := bufio.NewWriterSize(os.Stdout, 256*1024)
+	defer out.Flush()
+
+	for _, path := range paths {
+		formattedPath, err := manager.FormatPath(path)
+		if err != nil {
+			return err
+		}
+
+		objectName := strings.TrimPrefix(formattedPath, "/")
+		sse, err := readEncryption(c.String("sse"), objectName)
+		if err != nil {
+			return err
+		}
+
+		objInfo, err := client.StatObject(ctx, session.BucketName, objectName, minio.StatObjectOptions{ServerSideEncryption: sse})
+		if err != nil {
+			return fmt.Errorf("文件 '%s' 不存在或无法访问: %w", formattedPath, err)
+		}
+
+		size := dec.PlainSize(objInfo)
+		if size < 0 && r != nil && r.Suffix > 0 {
+			return fmt.Errorf("文件 '%s' 的原始大小未知，无法读取末尾的 %d 字节", formattedPath, r.Suffix)
+		}
+
+		start, length := r.resolve(size)
+		if length == 0 {
+			continue
+		}
+
+		if err := streamObject(ctx, client, session.BucketName, objInfo, sse, dec, start, length, out, policy); err != nil {
+			return fmt.Errorf("读取文件 '%s' 失败: %w", formattedPath, err)
+		}
+	}
+
+	return out.Flush()
+}
+
+// 输出文件内容操作
+func catAction(c *cli.Context) error {
+	if c.NArg() < 1 {
+		return fmt.Errorf("需要指定远程文件")
+	}
+	return catObjects(c, c.Args().Slice())
+}
+
+// 获取 --part-size 指定的分片大小
+func partSizeFromContext(c *cli.Context) (uint64, error) {
+	s := c.String("part-size")
+	if s == "" {
+		return defaultPartSize, nil
+	}
+
+	size, err := parseSize(s)
+	if err != nil {
+		return 0, fmt.Errorf("无效的分片大小: %w", err)
+	}
+	if size < minPartSize {
+		return 0, fmt.Errorf("分片大小不能小于 %s", formatSize(minPartSize))
+	}
+
+	return uint64(size), nil
+}
+
+// 从标准输入流式上传，长度未知，使用分片上传
+func putStdin(c *cli.Context, client *minio.Client, session *Session, objectName, formattedPath string, plan *Plan, opts *TransferOptions) error {
+	ctx := context.Background()
+
+	partSize, err := partSizeFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	if plan != nil {
+		op, reason := planUploadOp(ctx, client, session.BucketName, objectName)
+		plan.Add(PlanAction{Op: op, Source: "-", Target: objectName, Size: -1, Reason: reason + "，数据来自标准输入", Options: opts})
+		return plan.Finish(c)
+	}
+
+	// 启用回收站时，被覆盖的对象先复制到回收站
+	if _, err := trashOverwritten(ctx, client, session.BucketName, objectName, opts.sseSpec()); err != nil {
+		return err
+	}
+
+	progress := newProgress(c)
+	bar := progress.Start("stdin", -1)
+
+	// 加密时进度按明文统计
+	reader, size, putOpts, err := opts.prepareUpload(objectName, getMimeType(objectName), NewProgressReader(newTransferReader(os.Stdin), bar), -1)
+	if err != nil {
+		bar.Done(err)
+		progress.Finish()
+		return err
+	}
+	putOpts.PartSize = partSize
+
+	info, err := client.PutObject(ctx, session.BucketName, objectName, reader, size, putOpts)
+	bar.Done(err)
+	progress.Finish()
+	if err != nil {
+		return fmt.Errorf("上传标准输入失败: %w", err)
+	}
+
+	fmt.Printf("已上传 %s 字节到 %s\n", formatSize(info.Size), formattedPath)
+	return nil
+}
@@ -0,0 +1,478 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/urfave/cli/v2"
+)
+
+// 回收站的默认前缀
+const defaultTrashPrefix = ".minx-trash/"
+
+// 回收站中对象记录的原路径和删除时间
+const (
+	trashMetaPath = "Minx-Trash-Path"
+	trashMetaTime = "Minx-Trash-Time"
+)
+
+// 回收站中每批删除的目录名，同一次操作删除的对象放在同一目录下
+const trashBatchLayout = "20060102-150405.000"
+
+// 当前会话的回收站前缀，未启用时返回空字符串
+func sessionTrashPrefix() string {
+	if manager == nil {
+		return ""
+	}
+	session, err := manager.CurrentSession()
+	if err != nil {
+		return ""
+	}
+	return session.Trash
+}
+
+// 列出 prefix 下的对象时是否跳过 key: 回收站中的对象不参与同步、比较、查找和统计，直接列出回收站时除外
+func inTrash(trash, prefix, key string) bool {
+	return trash != "" && strings.HasPrefix(key, trash) && !strings.HasPrefix(prefix, trash)
+}
+
+// 对象在回收站中的路径
+func trashKey(prefix string, deletedAt time.Time, objectName string) string {
+	return prefix + deletedAt.UTC().Format(trashBatchLayout) + "/" + objectName
+}
+
+// 将对象复制到回收站，记录原路径和删除时间
+func copyToTrash(ctx context.Context, client *minio.Client, bucket, objectName, target, sse string) error {
+	opts := &TransferOptions{SSE: sse, Metadata: map[string]string{
+		trashMetaPath: objectName,
+		trashMetaTime: time.Now().UTC().Format(time.RFC3339),
+	}}
+	return copyObjectWithMetadata(ctx, client, bucket, objectName, target, opts)
+}
+
+// 将对象移到回收站: 复制到回收站后删除原对象
+func moveToTrash(ctx context.Context, client *minio.Client, bucket, objectName, target string, action PlanAction) error {
+	if err := copyToTrash(ctx, client, bucket, objectName, target, action.Options.sseSpec()); err != nil {
+		return err
+	}
+	return client.RemoveObject(ctx, bucket, objectName, action.Lock.removeOptions())
+}
+
+// 从回收站还原对象，去掉回收站的元数据
+func restoreFromTrash(ctx context.Context, client *minio.Client, bucket, source, objectName string, action PlanAction) error {
+	opts := &TransferOptions{SSE: action.Options.sseSpec(), RemoveMetadata: []string{trashMetaPath, trashMetaTime}}
+	if err := copyObjectWithMetadata(ctx, client, bucket, source, objectName, opts); err != nil {
+		return err
+	}
+	return client.RemoveObject(ctx, bucket, source, minio.RemoveObjectOptions{})
+}
+
+// 覆盖对象之前将其复制到回收站，返回回收站中的路径
+// 原对象保留，由之后的上传或复制覆盖，覆盖失败时原对象不受影响；每次覆盖只应调用一次，不要放在重试中
+// 未启用回收站、对象不存在或对象本身在回收站中时不做任何操作，返回空字符串
+func trashOverwritten(ctx context.Context, client *minio.Client, bucket, objectName, sse string) (string, error) {
+	prefix := sessionTrashPrefix()
+	if prefix == "" || strings.HasPrefix(objectName, prefix) || strings.HasSuffix(objectName, "/") {
+		return "", nil
+	}
+
+	srcSSE, err := readEncryption(sse, objectName)
+	if err != nil {
+		return "", err
+	}
+	if _, err := client.StatObject(ctx, bucket, objectName, minio.StatObjectOptions{ServerSideEncryption: srcSSE}); err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return "", nil
+		}
+		return "", err
+	}
+
+	target := trashKey(prefix, time.Now(), objectName)
+	if err := copyToTrash(ctx, client, bucket, objectName, target, sse); err != nil {
+		return "", fmt.Errorf("无法将被覆盖的对象复制到回收站: %w", err)
+	}
+	return target, nil
+}
+
+// remover 执行删除: 启用回收站时移到回收站并记录撤销操作，回收站中的对象直接删除
+type remover struct {
+	client *minio.Client
+	bucket string
+	trash  string
+	batch  time.Time
+	lock   *ObjectLock
+
+	mu   sync.Mutex
+	undo []PlanAction
+}
+
+func newRemover(client *minio.Client, session *Session, lock *ObjectLock) *remover {
+	return &remover{client: client, bucket: session.BucketName, trash: session.Trash, batch: time.Now(), lock: lock}
+}
+
+// 删除对象对应的计划操作
+func (r *remover) action(objectName string, size int64, reason string) PlanAction {
+	if r.trash == "" || strings.HasPrefix(objectName, r.trash) {
+		return PlanAction{Op: opDelete, Target: objectName, Size: size, Reason: reason, Lock: r.lock}
+	}
+	return PlanAction{Op: opTrash, Source: objectName, Target: trashKey(r.trash, r.batch, objectName), Size: size, Reason: reason, Lock: r.lock}
+}
+
+// 删除一个对象，可在多个协程中调用
+func (r *remover) remove(ctx context.Context, objectName string) error {
+	action := r.action(objectName, 0, "")
+	if err := executePlanAction(ctx, r.client, r.bucket, action); err != nil {
+		return err
+	}
+	r.record(action)
+	return nil
+}
+
+// 记录已执行的删除，移到回收站的对象可以撤销
+func (r *remover) record(action PlanAction) {
+	if action.Op != opTrash {
+		return
+	}
+	r.mu.Lock()
+	r.undo = append(r.undo, PlanAction{Op: opRestore, Source: action.Target, Target: action.Source, Reason: "撤销删除"})
+	r.mu.Unlock()
+}
+
+// 记录可以撤销的删除，并提示回收站位置
+func (r *remover) finish(command, summary string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.undo) == 0 {
+		return
+	}
+	if err := recordJournal(command, r.bucket, summary, r.undo); err != nil {
+		fmt.Fprintf(os.Stderr, "无法记录操作日志: %v\n", err)
+	}
+	fmt.Printf("已移到回收站 /%s%s/，可以使用 minx undo 撤销\n", r.trash, r.batch.UTC().Format(trashBatchLayout))
+}
+
+// trashEntry 是回收站中的一个对象
+type trashEntry struct {
+	key       string // 回收站中的对象名
+	path      string // 原路径
+	deletedAt time.Time
+	size      int64
+}
+
+// 解析回收站中的对象，元数据缺失时从对象名中的批次目录推断
+func newTrashEntry(prefix string, info minio.ObjectInfo) (trashEntry, bool) {
+	batch, original, ok := strings.Cut(strings.TrimPrefix(info.Key, prefix), "/")
+	if !ok || original == "" {
+		return trashEntry{}, false
+	}
+
+	e := trashEntry{key: info.Key, path: original, size: info.Size}
+	if p := userMeta(info, trashMetaPath); p != "" {
+		e.path = p
+	}
+	if t, err := time.Parse(time.RFC3339, userMeta(info, trashMetaTime)); err == nil {
+		e.deletedAt = t
+	} else if t, err := time.Parse(trashBatchLayout, batch); err == nil {
+		e.deletedAt = t
+	} else {
+		e.deletedAt = info.LastModified
+	}
+	return e, true
+}
+
+// 列出回收站中删除时间早于 before 的对象，按删除时间排序，before 为零值时不过滤
+func listTrash(ctx context.Context, client *minio.Client, bucket, prefix string, before time.Time) ([]trashEntry, error) {
+	var entries []trashEntry
+	for object := range client.ListObjects(ctx, bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true, WithMetadata: true}) {
+		if object.Err != nil {
+			return nil, fmt.Errorf("列出回收站时出错: %w", object.Err)
+		}
+		e, ok := newTrashEntry(prefix, object)
+		if !ok {
+			continue
+		}
+		if !before.IsZero() && !e.deletedAt.Before(before) {
+			continue
+		}
+		entries = append(entries, e)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if !entries[i].deletedAt.Equal(entries[j].deletedAt) {
+			return entries[i].deletedAt.Before(entries[j].deletedAt)
+		}
+		return entries[i].path < entries[j].path
+	})
+	return entries, nil
+}
+
+// 获取当前会话和回收站前缀，未启用回收站时报错
+func currentTrash() (*SessionManager, *minio.Client, *Session, error) {
+	manager, err := initSessionManager()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	client, err := manager.GetClient()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	session, err := manager.CurrentSession()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if session.Trash == "" {
+		return nil, nil, nil, fmt.Errorf("当前会话未启用回收站，使用 minx trash enable 启用")
+	}
+	return manager, client, session, nil
+}
+
+// 启用回收站操作
+func trashEnableAction(c *cli.Context) error {
+	prefix := defaultTrashPrefix
+	if c.NArg() > 0 {
+		prefix = strings.Trim(path.Clean("/"+c.Args().First()), "/") + "/"
+		if prefix == "/" || strings.ContainsAny(prefix, "*?") {
+			return fmt.Errorf("无效的回收站前缀 '%s'", c.Args().First())
+		}
+	}
+
+	manager, err := initSessionManager()
+	if err != nil {
+		return err
+	}
+
+	session, err := manager.CurrentSession()
+	if err != nil {
+		return err
+	}
+
+	session.Trash = prefix
+	manager.Sessions[manager.CurrentName] = *session
+	if err := manager.Save(); err != nil {
+		return err
+	}
+
+	fmt.Printf("已启用回收站: /%s\n", prefix)
+	fmt.Println("rm 以及覆盖已有对象的 mv -f、cp -f 和上传会先把对象移到回收站")
+	return nil
+}
+
+// 停用回收站操作，回收站中已有的对象保留
+func trashDisableAction(c *cli.Context) error {
+	manager, err := initSessionManager()
+	if err != nil {
+		return err
+	}
+
+	session, err := manager.CurrentSession()
+	if err != nil {
+		return err
+	}
+
+	if session.Trash == "" {
+		fmt.Println("当前会话未启用回收站")
+		return nil
+	}
+
+	prefix := session.Trash
+	session.Trash = ""
+	manager.Sessions[manager.CurrentName] = *session
+	if err := manager.Save(); err != nil {
+		return err
+	}
+
+	fmt.Printf("已停用回收站，/%s 中已有的对象保留，可以使用 rm -a 删除\n", prefix)
+	return nil
+}
+
+// 列出回收站操作
+func trashLsAction(c *cli.Context) error {
+	_, client, session, err := currentTrash()
+	if err != nil {
+		return err
+	}
+
+	var before time.Time
+	if c.IsSet("older-than") {
+		if before, err = parseAge(c.String("older-than")); err != nil {
+			return err
+		}
+	}
+
+	entries, err := listTrash(context.Background(), client, session.BucketName, session.Trash, before)
+	if err != nil {
+		return err
+	}
+
+	if len(entries) == 0 {
+		fmt.Println("回收站为空")
+		return nil
+	}
+
+	var total int64
+	for _, e := range entries {
+		fmt.Printf("%s  %10s  /%s\n", e.deletedAt.Local().Format("2006-01-02 15:04:05"), formatSize(e.size), e.path)
+		total += e.size
+	}
+	if !c.Bool("quiet") {
+		fmt.Printf("共 %d 个对象，%s，位于 /%s\n", len(entries), formatSize(total), session.Trash)
+	}
+	return nil
+}
+
+// 从回收站还原操作: 参数为原路径，目录还原其下所有对象，同一路径删除过多次时还原最近的一次
+func trashRestoreAction(c *cli.Context) error {
+	if c.NArg() < 1 {
+		return fmt.Errorf("需要指定要还原的原路径")
+	}
+
+	manager, client, session, err := currentTrash()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	entries, err := listTrash(ctx, client, session.BucketName, session.Trash, time.Time{})
+	if err != nil {
+		return err
+	}
+
+	// 按删除时间排序，后面的覆盖前面的，每个原路径只保留最近删除的一次
+	latest := make(map[string]trashEntry)
+	for _, arg := range c.Args().Slice() {
+		formattedPath, err := manager.FormatPath(arg)
+		if err != nil {
+			return err
+		}
+		target := strings.Trim(formattedPath, "/")
+
+		matched := false
+		for _, e := range entries {
+			if e.path == target || target == "" || strings.HasPrefix(e.path, target+"/") {
+				latest[e.path] = e
+				matched = true
+			}
+		}
+		if !matched {
+			return fmt.Errorf("回收站中没有 '/%s'", target)
+		}
+	}
+
+	paths := make([]string, 0, len(latest))
+	for p := range latest {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	plan := newPlan(c, "trash restore", session)
+	policy := retryPolicyFromContext(c)
+	failures := newFailureReport("trash restore", session)
+	var done int
+
+	for _, p := range paths {
+		e := latest[p]
+		action := PlanAction{Op: opRestore, Source: e.key, Target: e.path, Size: e.size, Reason: "删除于 " + e.deletedAt.Local().Format("2006-01-02 15:04:05")}
+
+		if !c.Bool("f") {
+			if _, err := client.StatObject(ctx, session.BucketName, e.path, minio.StatObjectOptions{}); err == nil {
+				err := fmt.Errorf("目标已存在，使用 -f 选项覆盖")
+				fmt.Fprintf(os.Stderr, "还原失败 '/%s': %v\n", e.path, err)
+				failures.Add(action, 0, err)
+				continue
+			}
+		}
+
+		if plan != nil {
+			plan.Add(action)
+			continue
+		}
+
+		attempts, err := runPlanAction(ctx, policy, client, session.BucketName, action)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "还原失败 '/%s': %v\n", e.path, err)
+			failures.Add(action, attempts, err)
+			continue
+		}
+
+		if !c.Bool("quiet") {
+			fmt.Printf("已还原: /%s\n", e.path)
+		}
+		done++
+	}
+
+	if plan != nil {
+		return plan.Finish(c)
+	}
+
+	fmt.Printf("还原完成: 成功 %d 个, 失败 %d 个\n", done, failures.Count())
+	return failures.Finish(c)
+}
+
+// 清空回收站操作
+func trashEmptyAction(c *cli.Context) error {
+	_, client, session, err := currentTrash()
+	if err != nil {
+		return err
+	}
+
+	var before time.Time
+	reason := "清空回收站"
+	if c.IsSet("older-than") {
+		if before, err = parseAge(c.String("older-than")); err != nil {
+			return err
+		}
+		reason = "删除于 " + before.Format("2006-01-02 15:04:05") + " 之前"
+	}
+
+	ctx := context.Background()
+	entries, err := listTrash(ctx, client, session.BucketName, session.Trash, before)
+	if err != nil {
+		return err
+	}
+
+	if len(entries) == 0 {
+		fmt.Println("回收站中没有需要删除的对象")
+		return nil
+	}
+
+	plan := newPlan(c, "trash empty", session)
+	policy := retryPolicyFromContext(c)
+	failures := newFailureReport("trash empty", session)
+	var done int
+	var freed int64
+
+	for _, e := range entries {
+		action := PlanAction{Op: opDelete, Target: e.key, Size: e.size, Reason: reason}
+		if plan != nil {
+			plan.Add(action)
+			continue
+		}
+
+		attempts, err := runPlanAction(ctx, policy, client, session.BucketName, action)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "删除失败 '/%s': %v\n", e.key, err)
+			failures.Add(action, attempts, err)
+			continue
+		}
+		done++
+		freed += e.size
+	}
+
+	if plan != nil {
+		return plan.Finish(c)
+	}
+
+	fmt.Printf("已从回收站删除 %d 个对象，释放 %s，失败 %d 个\n", done, formatSize(freed), failures.Count())
+	return failures.Finish(c)
+}
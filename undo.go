@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/urfave/cli/v2"
+)
+
+// 操作日志最多保留的条数
+const maxJournalEntries = 50
+
+// JournalEntry 记录一次可以撤销的操作
+type JournalEntry struct {
+	Command string       `json:"command"`
+	Session string       `json:"session"`
+	Bucket  string       `json:"bucket"`
+	Time    time.Time    `json:"time"`
+	Summary string       `json:"summary,omitempty"`
+	Undo    []PlanAction `json:"undo"` // 按顺序执行即可撤销该操作
+}
+
+// 操作日志文件路径: ~/.minx/journal.json
+func journalPath() (string, error) {
+	manager, err := initSessionManager()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(manager.ConfigPath), "journal.json"), nil
+}
+
+// 读取操作日志，文件不存在时返回空列表
+func loadJournal() ([]JournalEntry, error) {
+	path, err := journalPath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("无法读取操作日志: %w", err)
+	}
+
+	var entries []JournalEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("无法解析操作日志: %w", err)
+	}
+	return entries, nil
+}
+
+// 保存操作日志，只保留最近的 maxJournalEntries 条
+// 先写入临时文件再重命名，中途退出不会留下不完整的日志
+func saveJournal(entries []JournalEntry) error {
+	path, err := journalPath()
+	if err != nil {
+		return err
+	}
+
+	if len(entries) > maxJournalEntries {
+		entries = entries[len(entries)-maxJournalEntries:]
+	}
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("无法序列化操作日志: %w", err)
+	}
+
+	if err := os.WriteFile(path+".tmp", data, 0600); err != nil {
+		return fmt.Errorf("无法写入操作日志: %w", err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return fmt.Errorf("无法写入操作日志: %w", err)
+	}
+	return nil
+}
+
+// 在锁内读取、修改并保存操作日志，避免多个 minx 进程同时写入时丢失记录
+func updateJournal(update func([]JournalEntry) []JournalEntry) error {
+	path, err := journalPath()
+	if err != nil {
+		return err
+	}
+
+	lock, err := lockFileWait(path + ".lock")
+	if err != nil {
+		return fmt.Errorf("无法锁定操作日志: %w", err)
+	}
+	if lock != nil {
+		defer lock.Close()
+	}
+
+	entries, err := loadJournal()
+	if err != nil {
+		return err
+	}
+	return saveJournal(update(entries))
+}
+
+// 记录一次可以撤销的操作
+func recordJournal(command, bucket, summary string, undo []PlanAction) error {
+	entry := JournalEntry{
+		Command: command,
+		Session: manager.CurrentName,
+		Bucket:  bucket,
+		Time:    time.Now(),
+		Summary: summary,
+		Undo:    undo,
+	}
+	return updateJournal(func(entries []JournalEntry) []JournalEntry {
+		return append(entries, entry)
+	})
+}
+
+// 当前会话和存储桶最近一次操作在日志中的位置，没有时返回 -1
+func lastJournalEntry(entries []JournalEntry, session *Session) int {
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].Session == manager.CurrentName && entries[i].Bucket == session.BucketName {
+			return i
+		}
+	}
+	return -1
+}
+
+// 撤销当前会话最近一次 mv 或 rm 操作
+func undoAction(c *cli.Context) error {
+	manager, err := initSessionManager()
+	if err != nil {
+		return err
+	}
+
+	client, err := manager.GetClient()
+	if err != nil {
+		return err
+	}
+
+	session, err := manager.CurrentSession()
+	if err != nil {
+		return err
+	}
+
+	entries, err := loadJournal()
+	if err != nil {
+		return err
+	}
+
+	if c.Bool("list") {
+		found := false
+		for i := len(entries) - 1; i >= 0; i-- {
+			e := entries[i]
+			if e.Session != manager.CurrentName || e.Bucket != session.BucketName {
+				continue
+			}
+			fmt.Printf("%s  %-4s  %d 个对象  %s\n", e.Time.Local().Format("2006-01-02 15:04:05"), e.Command, len(e.Undo), e.Summary)
+			found = true
+		}
+		if !found {
+			fmt.Println("没有可以撤销的操作")
+		}
+		return nil
+	}
+
+	index := lastJournalEntry(entries, session)
+	if index < 0 {
+		fmt.Println("没有可以撤销的操作")
+		return nil
+	}
+	entry := entries[index]
+
+	plan := newPlan(c, "undo", session)
+	if plan != nil {
+		for _, action := range entry.Undo {
+			plan.Add(action)
+		}
+		return plan.Finish(c)
+	}
+
+	fmt.Printf("撤销 %s: %s (%s)\n", entry.Command, entry.Summary, entry.Time.Local().Format("2006-01-02 15:04:05"))
+
+	ctx := context.Background()
+	policy := retryPolicyFromContext(c)
+	failures := newFailureReport("undo", session)
+	var remaining []PlanAction
+	var done int
+
+	for _, action := range entry.Undo {
+		attempts, err := runPlanAction(ctx, policy, client, session.BucketName, action)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "撤销失败 '/%s': %v\n", action.Target, err)
+			failures.Add(action, attempts, err)
+			remaining = append(remaining, action)
+			continue
+		}
+
+		if !c.Bool("quiet") {
+			fmt.Printf("已还原: /%s\n", action.Target)
+		}
+		done++
+	}
+
+	// 全部成功时移除该条记录，否则只保留失败的操作，以便再次撤销
+	// 撤销期间其他进程可能追加了记录，按时间和会话重新定位该条记录
+	err = updateJournal(func(entries []JournalEntry) []JournalEntry {
+		for i, e := range entries {
+			if !e.Time.Equal(entry.Time) || e.Session != entry.Session || e.Bucket != entry.Bucket || e.Command != entry.Command {
+				continue
+			}
+			if len(remaining) == 0 {
+				return append(entries[:i], entries[i+1:]...)
+			}
+			entries[i].Undo = remaining
+			break
+		}
+		return entries
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("撤销完成: 成功 %d 个, 失败 %d 个\n", done, failures.Count())
+	return failures.Finish(c)
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/urfave/cli/v2"
+)
+
+// incompleteUpload 是一个未完成的分片上传及其已上传的分片
+type incompleteUpload struct {
+	info  minio.ObjectMultipartInfo
+	parts int
+	size  int64
+}
+
+// 列出前缀下发起时间早于 before 的未完成上传，before 为零值时不按时间过滤
+func listIncompleteUploads(ctx context.Context, client *minio.Client, bucket, prefix string, before time.Time) ([]incompleteUpload, error) {
+	var uploads []incompleteUpload
+	for upload := range client.ListIncompleteUploads(ctx, bucket, prefix, true) {
+		if upload.Err != nil {
+			return nil, fmt.Errorf("列出未完成的上传时出错: %w", upload.Err)
+		}
+		if !before.IsZero() && !upload.Initiated.Before(before) {
+			continue
+		}
+
+		parts, size, err := uploadedParts(ctx, client, bucket, upload.Key, upload.UploadID)
+		if err != nil {
+			return nil, fmt.Errorf("列出分片时出错 '/%s': %w", upload.Key, err)
+		}
+		uploads = append(uploads, incompleteUpload{info: upload, parts: parts, size: size})
+	}
+	return uploads, nil
+}
+
+// 解析 uploads 命令的前缀参数: 未指定时为当前目录，以 / 结尾时按目录处理，否则按对象名前缀匹配
+func uploadsPrefix(manager *SessionManager, arg string) (string, error) {
+	formattedPath, err := manager.FormatPath(arg)
+	if err != nil {
+		return "", err
+	}
+
+	prefix := strings.TrimPrefix(formattedPath, "/")
+	if prefix != "" && (arg == "" || strings.HasSuffix(arg, "/")) && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	return prefix, nil
+}
+
+// 列出未完成的分片上传操作
+func uploadsLsAction(c *cli.Context) error {
+	manager, err := initSessionManager()
+	if err != nil {
+		return err
+	}
+
+	client, err := manager.GetClient()
+	if err != nil {
+		return err
+	}
+
+	session, err := manager.CurrentSession()
+	if err != nil {
+		return err
+	}
+
+	prefix, err := uploadsPrefix(manager, c.Args().First())
+	if err != nil {
+		return err
+	}
+
+	var before time.Time
+	if c.IsSet("older-than") {
+		if before, err = parseAge(c.String("older-than")); err != nil {
+			return err
+		}
+	}
+
+	uploads, err := listIncompleteUploads(context.Background(), client, session.BucketName, prefix, before)
+	if err != nil {
+		return err
+	}
+
+	if len(uploads) == 0 {
+		fmt.Printf("/%s 下没有未完成的上传\n", prefix)
+		return nil
+	}
+
+	var total int64
+	for _, u := range uploads {
+		line := fmt.Sprintf("%s  %6d  %10s  /%s", u.info.Initiated.Local().Format("2006-01-02 15:04:05"), u.parts, formatSize(u.size), u.info.Key)
+		if c.Bool("id") {
+			line += "  " + u.info.UploadID
+		}
+		fmt.Println(line)
+		total += u.size
+	}
+
+	if !c.Bool("quiet") {
+		fmt.Printf("共 %d 个未完成的上传，已上传 %s\n", len(uploads), formatSize(total))
+	}
+	return nil
+}
+
+// 中止未完成的分片上传操作，释放已上传分片占用的空间
+// RemoveIncompleteUpload 会中止同一对象名的所有上传，按发起时间过滤时需要按上传 ID 逐个中止
+func uploadsRmAction(c *cli.Context) error {
+	if c.NArg() < 1 && !c.IsSet("older-than") {
+		return fmt.Errorf("需要指定前缀或 --older-than，中止当前存储桶的全部上传请使用 uploads rm /")
+	}
+
+	manager, err := initSessionManager()
+	if err != nil {
+		return err
+	}
+
+	client, err := manager.GetClient()
+	if err != nil {
+		return err
+	}
+
+	session, err := manager.CurrentSession()
+	if err != nil {
+		return err
+	}
+
+	prefix, err := uploadsPrefix(manager, c.Args().First())
+	if err != nil {
+		return err
+	}
+
+	var before time.Time
+	reason := "未完成的上传"
+	if c.IsSet("older-than") {
+		if before, err = parseAge(c.String("older-than")); err != nil {
+			return err
+		}
+		reason = "发起于 " + before.Format("2006-01-02 15:04:05") + " 之前"
+	}
+
+	ctx := context.Background()
+	uploads, err := listIncompleteUploads(ctx, client, session.BucketName, prefix, before)
+	if err != nil {
+		return err
+	}
+
+	if len(uploads) == 0 {
+		fmt.Printf("/%s 下没有需要中止的上传\n", prefix)
+		return nil
+	}
+
+	plan := newPlan(c, "uploads rm", session)
+	policy := retryPolicyFromContext(c)
+	failures := newFailureReport("uploads rm", session)
+	var done int
+	var freed int64
+
+	for _, u := range uploads {
+		action := PlanAction{Op: opAbort, Source: u.info.UploadID, Target: u.info.Key, Size: u.size, Reason: reason}
+		if plan != nil {
+			plan.Add(action)
+			continue
+		}
+
+		attempts, err := runPlanAction(ctx, policy, client, session.BucketName, action)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "中止失败 '/%s': %v\n", u.info.Key, err)
+			failures.Add(action, attempts, err)
+			continue
+		}
+
+		if !c.Bool("quiet") {
+			fmt.Printf("已中止: /%s (%s)\n", u.info.Key, formatSize(u.size))
+		}
+		done++
+		freed += u.size
+	}
+
+	if plan != nil {
+		return plan.Finish(c)
+	}
+
+	fmt.Printf("中止完成: 成功 %d 个, 失败 %d 个, 释放 %s\n", done, failures.Count(), formatSize(freed))
+	return failures.Finish(c)
+}
@@ -0,0 +1,508 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/urfave/cli/v2"
+)
+
+// 文件系统事件类型
+const (
+	fsWrite  = "write"  // 新建或修改
+	fsRemove = "remove" // 删除或移出监视目录
+	fsRename = "rename" // 在监视目录内重命名
+	fsRescan = "rescan" // 事件丢失，需要完整同步
+)
+
+// fsEvent 是监视目录下的一个变化，路径相对于监视目录，使用 / 分隔
+type fsEvent struct {
+	op      string
+	path    string
+	oldPath string // 重命名前的路径
+	dir     bool
+	reason  string // fsRescan 的原因
+}
+
+// fsWatcher 监视目录树的变化，隐藏文件和目录被忽略
+type fsWatcher interface {
+	Events() <-chan fsEvent
+	Close() error
+}
+
+// 轮询时的扫描间隔
+const pollInterval = time.Second
+
+// 检查待处理变化是否已稳定的间隔
+const watchTickInterval = 200 * time.Millisecond
+
+// pollWatcher 定期遍历目录，比较大小和修改时间，不支持 inotify 的平台使用
+// 无法识别重命名，重命名表现为删除和新建
+type pollWatcher struct {
+	root   string
+	events chan fsEvent
+	done   chan struct{}
+	once   sync.Once
+}
+
+// 文件快照
+type pollState struct {
+	size    int64
+	modTime time.Time
+}
+
+func newPollWatcher(root string) *pollWatcher {
+	w := &pollWatcher{root: root, events: make(chan fsEvent, 256), done: make(chan struct{})}
+	go w.run()
+	return w
+}
+
+// Events 返回事件通道
+func (w *pollWatcher) Events() <-chan fsEvent {
+	return w.events
+}
+
+// Close 停止轮询
+func (w *pollWatcher) Close() error {
+	w.once.Do(func() { close(w.done) })
+	return nil
+}
+
+// 扫描目录，失败时返回 nil
+func (w *pollWatcher) scan() map[string]pollState {
+	files := make(map[string]pollState)
+	err := walkLocalFiles(w.root, func(relPath string, info os.FileInfo) error {
+		files[relPath] = pollState{size: info.Size(), modTime: info.ModTime()}
+		return nil
+	})
+	if err != nil {
+		return nil
+	}
+	return files
+}
+
+func (w *pollWatcher) run() {
+	defer close(w.events)
+
+	last := w.scan()
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+		}
+
+		current := w.scan()
+		if current == nil {
+			continue
+		}
+
+		var events []fsEvent
+		for relPath, state := range current {
+			if old, ok := last[relPath]; !ok || old != state {
+				events = append(events, fsEvent{op: fsWrite, path: relPath})
+			}
+		}
+		for relPath := range last {
+			if _, ok := current[relPath]; !ok {
+				events = append(events, fsEvent{op: fsRemove, path: relPath})
+			}
+		}
+		last = current
+
+		for _, event := range events {
+			select {
+			case w.events <- event:
+			case <-w.done:
+				return
+			}
+		}
+	}
+}
+
+// watchChange 是等待处理的变化，同一路径只保留最后一次
+type watchChange struct {
+	op     string // opUpload、opDelete、opCopy 或 opMove
+	source string // 复制或移动的原路径
+	dir    bool   // 目录的删除、复制或移动，作用于前缀下的所有对象
+	at     time.Time
+}
+
+// watchJob 是一次要执行的变化，目录操作会展开为多个操作
+type watchJob struct {
+	key     string
+	actions []PlanAction
+}
+
+// syncWatcher 将监视到的变化同步到远程
+type syncWatcher struct {
+	client    *minio.Client
+	session   *Session
+	localPath string
+	prefix    string
+	opts      *TransferOptions
+	delete    bool
+	settle    time.Duration
+
+	pending  map[string]*watchChange
+	inflight map[string]bool
+}
+
+// 监视本地目录并持续同步操作
+// 先执行一次完整同步，之后根据文件系统事件上传、删除和移动，并定期完整同步以修正遗漏
+func watchSync(c *cli.Context, client *minio.Client, session *Session, localPath, objectPrefix string) error {
+	opts, err := transferOptionsFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	settle := c.Duration("settle")
+	if settle <= 0 {
+		return fmt.Errorf("--settle 必须大于 0")
+	}
+	reconcile := c.Duration("reconcile")
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// 先开始监视，避免遗漏首次同步期间的变化
+	watcher, err := newFSWatcher(localPath)
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	if err := syncOnce(ctx, c, client, session, localPath, objectPrefix); err != nil && ctx.Err() == nil {
+		fmt.Fprintf(os.Stderr, "完整同步出错: %v\n", err)
+	}
+
+	w := &syncWatcher{
+		client:    client,
+		session:   session,
+		localPath: localPath,
+		prefix:    objectPrefix,
+		opts:      opts,
+		delete:    c.Bool("delete"),
+		settle:    settle,
+		pending:   make(map[string]*watchChange),
+		inflight:  make(map[string]bool),
+	}
+
+	concurrency, err := newConcurrency(c)
+	if err != nil {
+		return err
+	}
+	defer concurrency.Stop()
+
+	policy := retryPolicyFromContext(c)
+	failures := newFailureReport("sync", session)
+
+	// 工作线程执行变化，完成后通知主循环
+	// 使用独立的 context，停止监视时正在执行的操作可以完成
+	workCtx := context.Background()
+	jobCh := make(chan watchJob)
+	doneCh := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency.Workers(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobCh {
+				for _, action := range job.actions {
+					target := action.Target
+					if action.Source != "" && action.Op != opUpload {
+						target = action.Source + " -> " + action.Target
+					}
+					fmt.Printf("%s: %s\n", opLabels[action.Op], target)
+
+					concurrency.Acquire()
+					attempts, err := runPlanAction(workCtx, policy, client, session.BucketName, action)
+					concurrency.Release(err)
+					if err != nil {
+						fmt.Fprintf(os.Stderr, "%s失败 '%s': %v\n", opLabels[action.Op], target, err)
+						failures.Add(action, attempts, err)
+					}
+				}
+				doneCh <- job.key
+			}
+		}()
+	}
+
+	fmt.Printf("正在监视 %s (按 Ctrl+C 停止)\n", localPath)
+
+	var reconcileCh <-chan time.Time
+	if reconcile > 0 {
+		ticker := time.NewTicker(reconcile)
+		defer ticker.Stop()
+		reconcileCh = ticker.C
+	}
+
+	// 完整同步在后台执行，期间继续记录事件，但不分发新的任务
+	// 等已分发的任务全部完成后才开始，避免与工作线程同时修改同一个对象；停止监视时随 ctx 取消
+	reconciling := false
+	reconcileReason := ""
+	reconcileDone := make(chan struct{})
+	running := 0
+	startReconcile := func() {
+		if reconcileReason == "" || reconciling || running > 0 {
+			return
+		}
+		reconciling = true
+		fmt.Printf("完整同步: %s\n", reconcileReason)
+		reconcileReason = ""
+		go func() {
+			if err := syncOnce(ctx, c, client, session, localPath, objectPrefix); err != nil && ctx.Err() == nil {
+				fmt.Fprintf(os.Stderr, "完整同步出错: %v\n", err)
+			}
+			reconcileDone <- struct{}{}
+		}()
+	}
+
+	tick := time.NewTicker(watchTickInterval)
+	defer tick.Stop()
+
+	events := watcher.Events()
+	var queue []watchJob
+
+loop:
+	for {
+		// 有待分发的任务且没有等待或正在执行的完整同步时才尝试发送
+		var sendCh chan watchJob
+		var next watchJob
+		if len(queue) > 0 && !reconciling && reconcileReason == "" {
+			sendCh, next = jobCh, queue[0]
+		}
+
+		select {
+		case <-ctx.Done():
+			break loop
+
+		case event, ok := <-events:
+			if !ok {
+				break loop
+			}
+			if event.op == fsRescan {
+				reconcileReason = event.reason
+			} else {
+				w.record(event)
+			}
+
+		case sendCh <- next:
+			queue = queue[1:]
+			running++
+
+		case key := <-doneCh:
+			delete(w.inflight, key)
+			running--
+
+		case <-tick.C:
+			queue = append(queue, w.ready(ctx)...)
+
+		case <-reconcileCh:
+			if !reconciling {
+				reconcileReason = "定期检查"
+			}
+
+		case <-reconcileDone:
+			reconciling = false
+		}
+
+		startReconcile()
+	}
+
+	// 等待正在执行的任务完成，未开始的变化留给下次启动时的完整同步，再次按 Ctrl+C 立即退出
+	stop()
+	watcher.Close()
+	go func() {
+		for range doneCh {
+		}
+	}()
+	close(jobCh)
+	wg.Wait()
+	close(doneCh)
+	if reconciling {
+		<-reconcileDone
+	}
+
+	fmt.Println("已停止监视")
+	return failures.Finish(c)
+}
+
+// 记录一个事件，同一路径的变化合并为最后一次
+func (w *syncWatcher) record(event fsEvent) {
+	now := time.Now()
+
+	switch event.op {
+	case fsWrite:
+		w.pending[event.path] = &watchChange{op: opUpload, at: now}
+
+	case fsRemove:
+		if event.dir {
+			w.dropUnder(event.path)
+			if w.delete {
+				w.pending[event.path+"/"] = &watchChange{op: opDelete, dir: true, at: now}
+			}
+			return
+		}
+		if w.delete {
+			w.pending[event.path] = &watchChange{op: opDelete, at: now}
+		} else {
+			delete(w.pending, event.path)
+		}
+
+	case fsRename:
+		// 不删除远程文件时，重命名在服务端复制为新名称
+		op := opCopy
+		if w.delete {
+			op = opMove
+		}
+
+		if event.dir {
+			// 目录下尚未上传的文件随目录改名，其余对象在服务端复制或移动
+			oldDir, newDir := event.oldPath+"/", event.path+"/"
+			for key, change := range w.pending {
+				if strings.HasPrefix(key, oldDir) && change.op == opUpload {
+					delete(w.pending, key)
+					w.pending[newDir+strings.TrimPrefix(key, oldDir)] = change
+				}
+			}
+			w.pending[newDir] = &watchChange{op: op, source: oldDir, dir: true, at: now}
+			return
+		}
+
+		// 尚未上传的文件直接以新名称上传
+		if change, ok := w.pending[event.oldPath]; ok && change.op == opUpload {
+			delete(w.pending, event.oldPath)
+			w.pending[event.path] = &watchChange{op: opUpload, at: now}
+			return
+		}
+		delete(w.pending, event.oldPath)
+		w.pending[event.path] = &watchChange{op: op, source: event.oldPath, at: now}
+	}
+}
+
+// 移除目录下尚未处理的变化
+func (w *syncWatcher) dropUnder(dir string) {
+	for key := range w.pending {
+		if strings.HasPrefix(key, dir+"/") {
+			delete(w.pending, key)
+		}
+	}
+}
+
+// 取出已稳定的变化，转换为要执行的任务
+// 文件在 --settle 时间内没有新的事件且修改时间不再变化时才上传，避免上传写到一半的文件
+func (w *syncWatcher) ready(ctx context.Context) []watchJob {
+	now := time.Now()
+
+	var keys []string
+	for key, change := range w.pending {
+		if now.Sub(change.at) >= w.settle && !w.inflight[key] && !(change.source != "" && w.inflight[change.source]) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var jobs []watchJob
+	for _, key := range keys {
+		change := w.pending[key]
+
+		actions, err := w.actions(ctx, key, change, now)
+		if err == errNotSettled {
+			continue
+		}
+		delete(w.pending, key)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "处理 '%s' 失败: %v\n", key, err)
+			continue
+		}
+		if len(actions) == 0 {
+			continue
+		}
+
+		w.inflight[key] = true
+		jobs = append(jobs, watchJob{key: key, actions: actions})
+	}
+	return jobs
+}
+
+// 文件仍在写入
+var errNotSettled = errors.New("文件仍在写入")
+
+// 将一个变化转换为计划操作
+func (w *syncWatcher) actions(ctx context.Context, key string, change *watchChange, now time.Time) ([]PlanAction, error) {
+	bucket := w.session.BucketName
+
+	switch change.op {
+	case opUpload:
+		fullPath := filepath.Join(w.localPath, filepath.FromSlash(key))
+		info, err := os.Stat(fullPath)
+		if err != nil || !info.Mode().IsRegular() {
+			// 已被删除或不是普通文件，删除事件会单独处理
+			return nil, nil
+		}
+		if now.Sub(info.ModTime()) < w.settle {
+			change.at = info.ModTime()
+			return nil, errNotSettled
+		}
+		return []PlanAction{{Op: opUpload, Source: fullPath, Target: w.prefix + key, Size: info.Size(), Options: w.opts}}, nil
+
+	case opDelete:
+		if !change.dir {
+			return []PlanAction{{Op: opDelete, Target: w.prefix + key}}, nil
+		}
+		objects, err := listRemoteFiles(ctx, w.client, bucket, w.prefix+key, w.session.Trash)
+		if err != nil {
+			return nil, err
+		}
+		var actions []PlanAction
+		for _, relPath := range sortedObjectKeys(objects) {
+			actions = append(actions, PlanAction{Op: opDelete, Target: objects[relPath].Key, Size: objects[relPath].Size})
+		}
+		return actions, nil
+
+	case opCopy, opMove:
+		if !change.dir {
+			// 远程没有原文件 (例如尚未上传完成) 时改为上传
+			if _, err := w.client.StatObject(ctx, bucket, w.prefix+change.source, minio.StatObjectOptions{}); err != nil {
+				return w.actions(ctx, key, &watchChange{op: opUpload, at: change.at}, now)
+			}
+			return []PlanAction{{Op: change.op, Source: w.prefix + change.source, Target: w.prefix + key, Options: w.opts}}, nil
+		}
+
+		objects, err := listRemoteFiles(ctx, w.client, bucket, w.prefix+change.source, w.session.Trash)
+		if err != nil {
+			return nil, err
+		}
+		var actions []PlanAction
+		for _, relPath := range sortedObjectKeys(objects) {
+			actions = append(actions, PlanAction{Op: change.op, Source: objects[relPath].Key, Target: w.prefix + key + relPath, Size: objects[relPath].Size, Options: w.opts})
+		}
+		return actions, nil
+	}
+
+	return nil, fmt.Errorf("未知的操作类型: %s", change.op)
+}
+
+// 按名称排序的对象相对路径
+func sortedObjectKeys(objects map[string]minio.ObjectInfo) []string {
+	keys := make([]string, 0, len(objects))
+	for key := range objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,253 @@
+//go:build linux
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// 监视的事件，每个子目录单独添加
+const inotifyMask = unix.IN_CREATE | unix.IN_MODIFY | unix.IN_CLOSE_WRITE | unix.IN_DELETE |
+	unix.IN_MOVED_FROM | unix.IN_MOVED_TO | unix.IN_ONLYDIR
+
+// inotifyWatcher 使用 inotify 监视目录树
+// 重命名通过 IN_MOVED_FROM 和 IN_MOVED_TO 的 cookie 配对，未配对时视为移出 (删除) 或移入 (新建)
+type inotifyWatcher struct {
+	root   string
+	file   *os.File
+	fd     int
+	paths  map[int]string // 监视描述符对应的相对路径，根目录为空字符串，只在读取协程中访问
+	events chan fsEvent
+	done   chan struct{}
+	once   sync.Once
+}
+
+// 创建目录监视，无法使用 inotify 时改为轮询
+func newFSWatcher(root string) (fsWatcher, error) {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "无法使用 inotify (%v)，改为定期扫描目录\n", err)
+		return newPollWatcher(root), nil
+	}
+
+	// 非阻塞的描述符由运行时轮询，关闭时可以中断读取
+	w := &inotifyWatcher{
+		root:   root,
+		file:   os.NewFile(uintptr(fd), "inotify"),
+		fd:     fd,
+		paths:  make(map[int]string),
+		events: make(chan fsEvent, 256),
+		done:   make(chan struct{}),
+	}
+
+	if _, err := w.addTree(""); err != nil {
+		w.file.Close()
+		if errors.Is(err, unix.ENOSPC) {
+			fmt.Fprintf(os.Stderr, "目录数超过 inotify 监视数量上限 (fs.inotify.max_user_watches)，改为定期扫描目录\n")
+			return newPollWatcher(root), nil
+		}
+		return nil, fmt.Errorf("无法监视目录: %w", err)
+	}
+
+	go w.run()
+	return w, nil
+}
+
+// Events 返回事件通道
+func (w *inotifyWatcher) Events() <-chan fsEvent {
+	return w.events
+}
+
+// Close 停止监视
+func (w *inotifyWatcher) Close() error {
+	var err error
+	w.once.Do(func() {
+		close(w.done)
+		err = w.file.Close()
+	})
+	return err
+}
+
+// 监视目录及其所有子目录，返回其中已有的文件
+// 新建的目录在添加监视之前可能已经写入了文件，需要补发事件
+func (w *inotifyWatcher) addTree(rel string) ([]string, error) {
+	var files []string
+	dir := filepath.Join(w.root, filepath.FromSlash(rel))
+
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			// 遍历期间被删除
+			if errors.Is(err, os.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+
+		if strings.HasPrefix(info.Name(), ".") && p != dir {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		relPath, err := filepath.Rel(w.root, p)
+		if err != nil {
+			return err
+		}
+		relPath = filepath.ToSlash(relPath)
+		if relPath == "." {
+			relPath = ""
+		}
+
+		if !info.IsDir() {
+			files = append(files, relPath)
+			return nil
+		}
+
+		wd, err := unix.InotifyAddWatch(w.fd, p, inotifyMask)
+		if err != nil {
+			if errors.Is(err, unix.ENOENT) {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		w.paths[wd] = relPath
+		return nil
+	})
+
+	return files, err
+}
+
+// 目录重命名后更新其下所有监视的路径
+func (w *inotifyWatcher) renameTree(oldRel, newRel string) {
+	for wd, p := range w.paths {
+		if p == oldRel {
+			w.paths[wd] = newRel
+		} else if strings.HasPrefix(p, oldRel+"/") {
+			w.paths[wd] = newRel + strings.TrimPrefix(p, oldRel)
+		}
+	}
+}
+
+// 目录移出监视范围后移除其下所有监视
+func (w *inotifyWatcher) removeTree(rel string) {
+	for wd, p := range w.paths {
+		if p == rel || strings.HasPrefix(p, rel+"/") {
+			unix.InotifyRmWatch(w.fd, uint32(wd))
+			delete(w.paths, wd)
+		}
+	}
+}
+
+// 发送事件，停止监视后丢弃
+func (w *inotifyWatcher) send(event fsEvent) {
+	select {
+	case w.events <- event:
+	case <-w.done:
+	}
+}
+
+func (w *inotifyWatcher) run() {
+	defer close(w.events)
+
+	buf := make([]byte, 64*1024)
+	for {
+		n, err := w.file.Read(buf)
+		if err != nil {
+			select {
+			case <-w.done:
+			default:
+				fmt.Fprintf(os.Stderr, "读取 inotify 事件失败: %v\n", err)
+			}
+			return
+		}
+		w.handle(buf[:n])
+	}
+}
+
+// 处理一次读取到的所有事件
+func (w *inotifyWatcher) handle(buf []byte) {
+	// 等待配对的 IN_MOVED_FROM，同一次读取中没有配对时视为移出
+	var moved *fsEvent
+	var movedCookie uint32
+	flush := func() {
+		if moved == nil {
+			return
+		}
+		if moved.dir {
+			w.removeTree(moved.path)
+		}
+		w.send(*moved)
+		moved = nil
+	}
+
+	for offset := 0; offset+unix.SizeofInotifyEvent <= len(buf); {
+		raw := (*unix.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+		nameStart := offset + unix.SizeofInotifyEvent
+		name := strings.TrimRight(string(buf[nameStart:nameStart+int(raw.Len)]), "\x00")
+		offset = nameStart + int(raw.Len)
+
+		if raw.Mask&unix.IN_Q_OVERFLOW != 0 {
+			flush()
+			w.send(fsEvent{op: fsRescan, reason: "inotify 事件队列溢出"})
+			continue
+		}
+		if raw.Mask&unix.IN_IGNORED != 0 {
+			delete(w.paths, int(raw.Wd))
+			continue
+		}
+
+		dirRel, ok := w.paths[int(raw.Wd)]
+		if !ok || name == "" || strings.HasPrefix(name, ".") {
+			continue
+		}
+		rel := path.Join(dirRel, name)
+		isDir := raw.Mask&unix.IN_ISDIR != 0
+
+		if raw.Mask&unix.IN_MOVED_TO != 0 && moved != nil && raw.Cookie == movedCookie {
+			event := fsEvent{op: fsRename, path: rel, oldPath: moved.path, dir: isDir}
+			moved = nil
+			if isDir {
+				w.renameTree(event.oldPath, rel)
+			}
+			w.send(event)
+			continue
+		}
+		flush()
+
+		switch {
+		case raw.Mask&unix.IN_MOVED_FROM != 0:
+			moved = &fsEvent{op: fsRemove, path: rel, dir: isDir}
+			movedCookie = raw.Cookie
+
+		case isDir && raw.Mask&(unix.IN_CREATE|unix.IN_MOVED_TO) != 0:
+			files, err := w.addTree(rel)
+			if err != nil {
+				w.send(fsEvent{op: fsRescan, reason: fmt.Sprintf("无法监视新目录 '%s': %v", rel, err)})
+				continue
+			}
+			for _, file := range files {
+				w.send(fsEvent{op: fsWrite, path: file})
+			}
+
+		case isDir:
+			// 目录删除时其中的文件有各自的删除事件
+
+		case raw.Mask&(unix.IN_CREATE|unix.IN_MODIFY|unix.IN_CLOSE_WRITE|unix.IN_MOVED_TO) != 0:
+			w.send(fsEvent{op: fsWrite, path: rel})
+
+		case raw.Mask&unix.IN_DELETE != 0:
+			w.send(fsEvent{op: fsRemove, path: rel})
+		}
+	}
+	flush()
+}
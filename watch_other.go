@@ -0,0 +1,8 @@
+//go:build !linux
+
+package main
+
+// 创建目录监视，非 Linux 平台定期扫描目录
+func newFSWatcher(root string) (fsWatcher, error) {
+	return newPollWatcher(root), nil
+}